@@ -48,15 +48,64 @@ type Configuration struct {
 	// ListenAddress is the server listen address.
 	ListenAddress string `yaml:"listenAddress" validate:"nonzero"`
 
+	// BasePath is the path prefix that all HTTP routes are served under,
+	// for example when deployed behind a reverse proxy, empty serves routes
+	// from the root.
+	BasePath string `yaml:"basePath"`
+
 	// RPC is the RPC configuration.
 	RPC *RPCConfiguration `yaml:"rpc"`
 
+	// Selector is the set of tags every series in the local clusters has,
+	// queries that cannot match it are not read from the local clusters
+	// when fanning out (optional).
+	Selector map[string]string `yaml:"selector"`
+
 	// DecompressWorkerPoolCount is the number of decompression worker pools.
 	DecompressWorkerPoolCount int `yaml:"workerPoolCount"`
 
 	// DecompressWorkerPoolSize is the size of the worker pool given to each
 	// fetch request.
 	DecompressWorkerPoolSize int `yaml:"workerPoolSize"`
+
+	// MaxReadResponseSamples is the maximum number of datapoints a native
+	// read may return before the request is rejected, zero means no limit.
+	MaxReadResponseSamples int `yaml:"maxReadResponseSamples"`
+
+	// MaxSearchLimit is the maximum number of series a single search may
+	// return, larger requested limits are reduced to it and searches where
+	// the offset plus the limit exceeds it are rejected, zero means no limit.
+	MaxSearchLimit int `yaml:"maxSearchLimit"`
+
+	// WriteLimits is the per series write limits configuration (optional).
+	WriteLimits *WriteLimitsConfiguration `yaml:"writeLimits"`
+
+	// ReadPartitionSize is the size of the time ranges that native read
+	// queries are split into and executed in parallel when the query allows
+	// it, sizes below an hour are raised to an hour and zero disables
+	// partitioning.
+	ReadPartitionSize time.Duration `yaml:"readPartitionSize"`
+
+	// Read is the configuration for which namespaces are read from (optional).
+	Read *ReadConfiguration `yaml:"read"`
+
+	// ResponseCompression is the configuration for gzip compressing HTTP
+	// responses.
+	ResponseCompression ResponseCompressionConfiguration `yaml:"responseCompression"`
+
+	// DisableProfiling disables the pprof profiling endpoints.
+	DisableProfiling bool `yaml:"disableProfiling"`
+
+	// CORS is the configuration for cross-origin requests, disabled unless
+	// allowed origins are configured.
+	CORS CORSConfiguration `yaml:"cors"`
+
+	// RequestTimeout is the maximum time a request to any HTTP endpoint may
+	// take before it is cancelled and a 503 is returned, zero means no limit.
+	RequestTimeout time.Duration `yaml:"requestTimeout"`
+
+	// HTTPServer is the configuration for tuning the HTTP server.
+	HTTPServer HTTPServerConfiguration `yaml:"httpServer"`
 }
 
 // LocalConfiguration is the local embedded configuration if running
@@ -88,4 +137,105 @@ type RPCConfiguration struct {
 	// RemoteListenAddresses is the remote listen addresses to call for remote
 	// coordinator calls.
 	RemoteListenAddresses []string `yaml:"remoteListenAddresses"`
+
+	// RemoteSelector is the set of tags every series held by the remote
+	// coordinators has (optional).
+	RemoteSelector map[string]string `yaml:"remoteSelector"`
+}
+
+// WriteLimitsConfiguration is the configuration for limiting writes per
+// series to protect against cardinality explosions.
+type WriteLimitsConfiguration struct {
+	// Interval is the interval over which limits are enforced.
+	Interval time.Duration `yaml:"interval" validate:"nonzero"`
+
+	// MaxSamplesPerSeries is the maximum number of samples a single series
+	// may write per interval, zero means unlimited.
+	MaxSamplesPerSeries int `yaml:"maxSamplesPerSeries"`
+
+	// MaxNewSeries is the maximum number of previously unseen series that
+	// may be written per interval, zero means unlimited.
+	MaxNewSeries int `yaml:"maxNewSeries"`
+
+	// MaxTrackedSeries bounds the number of series tracked per interval,
+	// once reached series that are not already tracked are rejected.
+	MaxTrackedSeries int `yaml:"maxTrackedSeries" validate:"nonzero"`
+}
+
+// ReadConfiguration is the configuration for selecting which namespaces
+// are read from. Of the namespaces that retain the full range of a query,
+// each series is read from the finest resolution namespace that has it,
+// with ties broken by namespace ID.
+type ReadConfiguration struct {
+	// DefaultNamespace is the only namespace read from when it retains the
+	// full range of a query.
+	DefaultNamespace string `yaml:"defaultNamespace"`
+
+	// FallbackToLongestRetention reads from the namespace with the longest
+	// retention when no namespace retains the full range of a query, rather
+	// than failing the query.
+	FallbackToLongestRetention bool `yaml:"fallbackToLongestRetention"`
+}
+
+// ReadOptions returns the local storage read options for the configuration.
+func (c *ReadConfiguration) ReadOptions() local.ReadOptions {
+	if c == nil {
+		return local.ReadOptions{}
+	}
+	return local.ReadOptions{
+		DefaultNamespace:           c.DefaultNamespace,
+		FallbackToLongestRetention: c.FallbackToLongestRetention,
+	}
+}
+
+// ResponseCompressionConfiguration is the configuration for gzip compressing
+// HTTP responses for clients that accept gzip encoding.
+type ResponseCompressionConfiguration struct {
+	// Disabled disables compressing responses.
+	Disabled bool `yaml:"disabled"`
+
+	// MinSize is the minimum size in bytes of a response body before it is
+	// compressed, zero uses the default.
+	MinSize int `yaml:"minSize"`
+}
+
+// CORSConfiguration is the configuration for cross-origin requests.
+type CORSConfiguration struct {
+	// AllowedOrigins are the origins allowed to make cross-origin requests,
+	// "*" allows any origin.
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+
+	// AllowedMethods are the methods allowed in cross-origin requests,
+	// defaults to GET and POST.
+	AllowedMethods []string `yaml:"allowedMethods"`
+
+	// AllowedHeaders are the request headers allowed in cross-origin
+	// requests, defaults to Content-Type.
+	AllowedHeaders []string `yaml:"allowedHeaders"`
+}
+
+// HTTPServerConfiguration is the configuration for tuning the HTTP server,
+// zero values use the net/http defaults. The server only serves plaintext
+// HTTP/1.1, serving HTTP/2 requires a TLS terminating proxy in front of it.
+type HTTPServerConfiguration struct {
+	// ReadTimeout is the maximum time to read an entire request, including
+	// the body.
+	ReadTimeout time.Duration `yaml:"readTimeout"`
+
+	// ReadHeaderTimeout is the maximum time to read the request headers.
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout"`
+
+	// WriteTimeout is the maximum time to write a response.
+	WriteTimeout time.Duration `yaml:"writeTimeout"`
+
+	// IdleTimeout is the maximum time to wait for the next request on a
+	// keep-alive connection.
+	IdleTimeout time.Duration `yaml:"idleTimeout"`
+
+	// MaxHeaderBytes is the maximum size of the request headers.
+	MaxHeaderBytes int `yaml:"maxHeaderBytes"`
+
+	// DisableKeepAlives disables HTTP keep-alives, closing each connection
+	// after a single request.
+	DisableKeepAlives bool `yaml:"disableKeepAlives"`
 }
@@ -57,6 +57,11 @@ type BootstrapConfiguration struct {
 	// CacheSeriesMetadata determines whether individual bootstrappers cache
 	// series metadata across all calls (namespaces / shards / blocks).
 	CacheSeriesMetadata *bool `yaml:"cacheSeriesMetadata"`
+
+	// IndexSegmentInitialCapacity is the number of documents the mutable index
+	// segments built during bootstrap are initially sized for, a larger value
+	// avoids regrowing segments for high cardinality namespaces.
+	IndexSegmentInitialCapacity *int `yaml:"indexSegmentInitialCapacity"`
 }
 
 func (bsc BootstrapConfiguration) fsNumProcessors() int {
@@ -104,6 +109,10 @@ func (bsc BootstrapConfiguration) New(
 		bs  bootstrap.BootstrapperProvider
 		err error
 	)
+	if capacity := bsc.IndexSegmentInitialCapacity; capacity != nil {
+		mutableSegmentAllocator = result.NewMutableSegmentAllocator(*capacity,
+			opts.IndexOptions().MemSegmentOptions())
+	}
 	rsOpts := result.NewOptions().
 		SetInstrumentOptions(opts.InstrumentOptions()).
 		SetDatabaseBlockOptions(opts.DatabaseBlockOptions()).
@@ -372,6 +372,7 @@ db:
       numProcessorsPerCPU: 0.125
     peers: null
     cacheSeriesMetadata: null
+    indexSegmentInitialCapacity: null
   blockRetrieve: null
   cache:
     series: null
@@ -0,0 +1,185 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handler
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultCompressionMinSize is the default minimum size in bytes of a
+	// response body before it is gzip compressed
+	DefaultCompressionMinSize = 1024
+
+	gzipEncoding = "gzip"
+)
+
+// WithGzipCompression wraps around the given handler, gzip compressing
+// response bodies of at least minSize bytes when the client accepts gzip
+// encoding, smaller responses are written uncompressed
+func WithGzipCompression(next http.Handler, minSize int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, minSize: minSize}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(encoding, ";")
+		if strings.TrimSpace(parts[0]) != gzipEncoding {
+			continue
+		}
+		// A quality value of zero means the client refuses the encoding
+		for _, param := range parts[1:] {
+			if q := strings.TrimSpace(param); q == "q=0" || q == "q=0.0" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the response body until it reaches the minimum
+// size to compress, the status code is held back until then as whether the
+// response is compressed determines the headers sent with it
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minSize     int
+	status      int
+	buf         []byte
+	gz          *gzip.Writer
+	passthrough bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) < w.minSize {
+		return len(p), nil
+	}
+	if err := w.begin(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// begin sends the headers and writes the buffered body, compressing the rest
+// of the response unless the handler has already encoded it
+func (w *gzipResponseWriter) begin() error {
+	buf := w.buf
+	w.buf = nil
+	if w.Header().Get("Content-Encoding") != "" {
+		// Already encoded by the handler, write it through as is
+		w.passthrough = true
+		w.writeHeader()
+		_, err := w.ResponseWriter.Write(buf)
+		return err
+	}
+
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		// Sniff the content type from the uncompressed body since once
+		// compressed it can no longer be detected by the server
+		header.Set("Content-Type", http.DetectContentType(buf))
+	}
+	header.Set("Content-Encoding", gzipEncoding)
+	header.Del("Content-Length")
+	w.writeHeader()
+
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+	_, err := w.gz.Write(buf)
+	return err
+}
+
+// Flush sends what has been written so far to the client, a response that is
+// flushed before reaching the minimum size is still compressed since a
+// streamed response is expected to keep growing
+func (w *gzipResponseWriter) Flush() {
+	if w.gz == nil && !w.passthrough {
+		if err := w.begin(); err != nil {
+			return
+		}
+	}
+	if w.gz != nil {
+		if err := w.gz.Flush(); err != nil {
+			return
+		}
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// CloseNotify returns the close notification channel of the underlying
+// response writer, or a channel that never fires if it has none
+func (w *gzipResponseWriter) CloseNotify() <-chan bool {
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+func (w *gzipResponseWriter) writeHeader() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+func (w *gzipResponseWriter) close() error {
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	if w.passthrough {
+		return nil
+	}
+
+	// The response was too small to compress, write it uncompressed
+	w.writeHeader()
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf)
+	return err
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveCompressed(
+	t *testing.T,
+	acceptEncoding string,
+	body []byte,
+) *http.Response {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		// Write in two parts to exercise buffering across writes
+		half := len(body) / 2
+		_, err := w.Write(body[:half])
+		require.NoError(t, err)
+		_, err = w.Write(body[half:])
+		require.NoError(t, err)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	WithGzipCompression(next, 64).ServeHTTP(w, req)
+	return w.Result()
+}
+
+func TestWithGzipCompression(t *testing.T) {
+	body := bytes.Repeat([]byte(`{"a":1}`), 100)
+	resp := serveCompressed(t, "deflate, gzip", body)
+
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	r, err := gzip.NewReader(resp.Body)
+	require.NoError(t, err)
+	decompressed, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, body, decompressed)
+}
+
+func TestWithGzipCompressionSkipsSmallResponses(t *testing.T) {
+	body := []byte(`{"a":1}`)
+	resp := serveCompressed(t, "gzip", body)
+
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+
+	read, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, body, read)
+}
+
+func TestWithGzipCompressionNotAccepted(t *testing.T) {
+	body := bytes.Repeat([]byte(`{"a":1}`), 100)
+	for _, acceptEncoding := range []string{"", "deflate", "gzip;q=0"} {
+		resp := serveCompressed(t, acceptEncoding, body)
+
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+
+		read, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, body, read)
+	}
+}
+
+func TestWithGzipCompressionFlush(t *testing.T) {
+	var (
+		chunk   = []byte(`{"a":1}`)
+		w       = httptest.NewRecorder()
+		flushed []byte
+	)
+	next := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, ok := rw.(http.CloseNotifier)
+		require.True(t, ok)
+		flusher, ok := rw.(http.Flusher)
+		require.True(t, ok)
+
+		// A flushed response is sent even though it is below the minimum size
+		_, err := rw.Write(chunk)
+		require.NoError(t, err)
+		flusher.Flush()
+		flushed = append(flushed, w.Body.Bytes()...)
+
+		_, err = rw.Write(chunk)
+		require.NoError(t, err)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	WithGzipCompression(next, 64).ServeHTTP(w, req)
+
+	assert.True(t, w.Flushed)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+
+	// The flushed bytes decompress to the first chunk
+	r, err := gzip.NewReader(bytes.NewReader(flushed))
+	require.NoError(t, err)
+	read := make([]byte, len(chunk))
+	_, err = io.ReadFull(r, read)
+	require.NoError(t, err)
+	assert.Equal(t, chunk, read)
+
+	r, err = gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	decompressed, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, bytes.Repeat(chunk, 2), decompressed)
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handler
+
+import (
+	"net/http"
+	"strings"
+)
+
+const anyOrigin = "*"
+
+var (
+	defaultCORSAllowedMethods = []string{http.MethodGet, http.MethodPost}
+	defaultCORSAllowedHeaders = []string{"Content-Type"}
+)
+
+// CORSOptions describes which cross-origin requests are allowed
+type CORSOptions struct {
+	// AllowedOrigins are the origins allowed to make cross-origin requests,
+	// an origin of "*" allows any origin
+	AllowedOrigins []string
+
+	// AllowedMethods are the methods allowed in cross-origin requests,
+	// defaults to GET and POST when empty
+	AllowedMethods []string
+
+	// AllowedHeaders are the request headers allowed in cross-origin
+	// requests, defaults to Content-Type when empty
+	AllowedHeaders []string
+}
+
+func (o CORSOptions) allowedMethods() []string {
+	if len(o.AllowedMethods) == 0 {
+		return defaultCORSAllowedMethods
+	}
+	return o.AllowedMethods
+}
+
+func (o CORSOptions) allowedHeaders() []string {
+	if len(o.AllowedHeaders) == 0 {
+		return defaultCORSAllowedHeaders
+	}
+	return o.AllowedHeaders
+}
+
+// allowOrigin returns the value of the Access-Control-Allow-Origin header
+// for the origin and whether the origin is allowed at all
+func (o CORSOptions) allowOrigin(origin string) (string, bool) {
+	for _, allowed := range o.AllowedOrigins {
+		if allowed == anyOrigin {
+			return anyOrigin, true
+		}
+		if allowed == origin {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// WithCORS wraps around the given handler, adding the CORS headers to
+// responses for requests from allowed origins
+func WithCORS(next http.Handler, opts CORSOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if allowOrigin, ok := opts.allowOrigin(origin); ok {
+				w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// NewCORSPreflightHandler returns a handler responding to CORS preflight
+// OPTIONS requests, preflight requests from origins or for methods that are
+// not allowed are rejected
+func NewCORSPreflightHandler(opts CORSOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		allowOrigin, ok := opts.allowOrigin(r.Header.Get("Origin"))
+		if !ok {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		methods := opts.allowedMethods()
+		if !containsString(methods, r.Header.Get("Access-Control-Request-Method")) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		header := w.Header()
+		header.Set("Access-Control-Allow-Origin", allowOrigin)
+		header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		header.Set("Access-Control-Allow-Headers", strings.Join(opts.allowedHeaders(), ", "))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
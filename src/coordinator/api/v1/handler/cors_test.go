@@ -0,0 +1,88 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithCORS(t *testing.T) {
+	opts := CORSOptions{AllowedOrigins: []string{"http://grafana.example.com"}}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		origin        string
+		expectedAllow string
+	}{
+		{origin: "http://grafana.example.com", expectedAllow: "http://grafana.example.com"},
+		{origin: "http://other.example.com", expectedAllow: ""},
+		{origin: "", expectedAllow: ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.origin != "" {
+			req.Header.Set("Origin", test.origin)
+		}
+		w := httptest.NewRecorder()
+		WithCORS(next, opts).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, test.expectedAllow, w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "Origin", w.Header().Get("Vary"))
+	}
+}
+
+func TestCORSPreflightHandler(t *testing.T) {
+	opts := CORSOptions{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{http.MethodGet},
+	}
+
+	tests := []struct {
+		method       string
+		expectedCode int
+	}{
+		{method: http.MethodGet, expectedCode: http.StatusNoContent},
+		{method: http.MethodDelete, expectedCode: http.StatusForbidden},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodOptions, "/", nil)
+		req.Header.Set("Origin", "http://grafana.example.com")
+		req.Header.Set("Access-Control-Request-Method", test.method)
+		w := httptest.NewRecorder()
+		NewCORSPreflightHandler(opts).ServeHTTP(w, req)
+
+		assert.Equal(t, test.expectedCode, w.Code)
+		if test.expectedCode != http.StatusNoContent {
+			assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+			continue
+		}
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "GET", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "Content-Type", w.Header().Get("Access-Control-Allow-Headers"))
+	}
+}
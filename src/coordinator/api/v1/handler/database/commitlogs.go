@@ -0,0 +1,129 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package database
+
+import (
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	dbconfig "github.com/m3db/m3db/src/cmd/services/m3dbnode/config"
+	"github.com/m3db/m3db/src/coordinator/api/v1/handler"
+	"github.com/m3db/m3db/src/coordinator/util/logging"
+	"github.com/m3db/m3db/src/dbnode/persist/fs"
+	"github.com/m3db/m3db/src/dbnode/persist/fs/commitlog"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// CommitLogsURL is the url for the commit log files handler.
+	CommitLogsURL = handler.RoutePrefixV1 + "/database/commitlogs"
+
+	// CommitLogsHTTPMethod is the HTTP method used with this resource.
+	CommitLogsHTTPMethod = http.MethodGet
+)
+
+// CommitLogsResponse is the response returned by the commit log files handler.
+type CommitLogsResponse struct {
+	Files []CommitLogFile `json:"files"`
+}
+
+// CommitLogFile describes a commit log file of the embedded database, files
+// that could not be read are listed with only their path and the error.
+type CommitLogFile struct {
+	Path      string    `json:"path"`
+	Start     time.Time `json:"start"`
+	Duration  string    `json:"duration,omitempty"`
+	Index     int64     `json:"index"`
+	SizeBytes int64     `json:"sizeBytes"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type commitLogsHandler struct {
+	opts commitlog.Options
+}
+
+// NewCommitLogsHandler returns a new instance of a handler that lists the
+// commit log files of the local embedded database. Deleting commit log files
+// is left to the database cleanup process which only removes files once
+// their data has been flushed.
+func NewCommitLogsHandler(embeddedDbCfg *dbconfig.DBConfiguration) http.Handler {
+	fsOpts := fs.NewOptions().
+		SetFilePathPrefix(embeddedDbCfg.Filesystem.FilePathPrefix)
+	return &commitLogsHandler{
+		opts: commitlog.NewOptions().SetFilesystemOptions(fsOpts),
+	}
+}
+
+func (h *commitLogsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := logging.WithContext(r.Context())
+
+	dir := fs.CommitLogsDirPath(h.opts.FilesystemOptions().FilePathPrefix())
+	filePaths, err := fs.SortedCommitLogFiles(dir)
+	if err != nil {
+		logger.Error("unable to list commit log files", zap.Any("error", err))
+		handler.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := CommitLogsResponse{Files: make([]CommitLogFile, 0, len(filePaths))}
+	for _, filePath := range filePaths {
+		file, err := h.readFile(filePath)
+		if os.IsNotExist(err) {
+			// Removed by cleanup since the directory was listed
+			continue
+		}
+		if err != nil {
+			logger.Warn("unable to read commit log file",
+				zap.String("path", filePath), zap.Any("error", err))
+			file = CommitLogFile{Path: filePath, Error: err.Error()}
+		}
+		resp.Files = append(resp.Files, file)
+	}
+
+	// Files that could not be read have a zero start and are listed first
+	sort.SliceStable(resp.Files, func(i, j int) bool {
+		return resp.Files[i].Start.Before(resp.Files[j].Start)
+	})
+
+	handler.WriteJSONResponse(w, resp, logger)
+}
+
+func (h *commitLogsHandler) readFile(filePath string) (CommitLogFile, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return CommitLogFile{}, err
+	}
+
+	start, duration, index, err := commitlog.ReadLogInfo(filePath, h.opts)
+	if err != nil {
+		return CommitLogFile{}, err
+	}
+
+	return CommitLogFile{
+		Path:      filePath,
+		Start:     start,
+		Duration:  duration.String(),
+		Index:     index,
+		SizeBytes: info.Size(),
+	}, nil
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package database
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	dbconfig "github.com/m3db/m3db/src/cmd/services/m3dbnode/config"
+	"github.com/m3db/m3db/src/coordinator/util/logging"
+	"github.com/m3db/m3db/src/dbnode/persist/fs"
+	"github.com/m3db/m3db/src/dbnode/persist/fs/commitlog"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitLogsHandler(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	dir, err := ioutil.TempDir("", "commitlogs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := &dbconfig.DBConfiguration{
+		Filesystem: dbconfig.FilesystemConfiguration{FilePathPrefix: dir},
+	}
+	h := NewCommitLogsHandler(cfg)
+
+	// No commit logs yet
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(CommitLogsHTTPMethod, CommitLogsURL, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp CommitLogsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Files, 0)
+
+	opts := commitlog.NewOptions().
+		SetFilesystemOptions(fs.NewOptions().SetFilePathPrefix(dir)).
+		SetBlockSize(time.Hour)
+	commitLog, err := commitlog.NewCommitLog(opts)
+	require.NoError(t, err)
+	require.NoError(t, commitLog.Open())
+	require.NoError(t, commitLog.Close())
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(CommitLogsHTTPMethod, CommitLogsURL, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Files, 1)
+	file := resp.Files[0]
+	assert.Equal(t, time.Hour.String(), file.Duration)
+	assert.Equal(t, int64(0), file.Index)
+	assert.True(t, file.SizeBytes > 0)
+	assert.Contains(t, file.Path, dir)
+}
+
+func TestCommitLogsHandlerUnreadableFile(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	dir, err := ioutil.TempDir("", "commitlogs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	opts := commitlog.NewOptions().
+		SetFilesystemOptions(fs.NewOptions().SetFilePathPrefix(dir)).
+		SetBlockSize(time.Hour)
+	commitLog, err := commitlog.NewCommitLog(opts)
+	require.NoError(t, err)
+	require.NoError(t, commitLog.Open())
+	require.NoError(t, commitLog.Close())
+
+	// A truncated file that does not have a readable log info header
+	corruptPath, _ := fs.NextCommitLogsFile(dir, time.Now().Add(time.Hour))
+	require.NoError(t, ioutil.WriteFile(corruptPath, []byte{0xff}, 0666))
+
+	cfg := &dbconfig.DBConfiguration{
+		Filesystem: dbconfig.FilesystemConfiguration{FilePathPrefix: dir},
+	}
+	h := NewCommitLogsHandler(cfg)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(CommitLogsHTTPMethod, CommitLogsURL, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp CommitLogsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Files, 2)
+
+	corrupt, valid := resp.Files[0], resp.Files[1]
+	assert.Equal(t, corruptPath, corrupt.Path)
+	assert.NotEmpty(t, corrupt.Error)
+	assert.Empty(t, valid.Error)
+	assert.Equal(t, time.Hour.String(), valid.Duration)
+	assert.True(t, valid.SizeBytes > 0)
+}
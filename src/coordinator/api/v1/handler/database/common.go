@@ -46,4 +46,8 @@ func RegisterRoutes(
 	logged := logging.WithResponseTimeLogging
 
 	r.HandleFunc(CreateURL, logged(NewCreateHandler(client, cfg, embeddedDbCfg)).ServeHTTP).Methods(CreateHTTPMethod)
+
+	if embeddedDbCfg != nil {
+		r.HandleFunc(CommitLogsURL, logged(NewCommitLogsHandler(embeddedDbCfg)).ServeHTTP).Methods(CommitLogsHTTPMethod)
+	}
 }
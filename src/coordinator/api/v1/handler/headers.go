@@ -32,4 +32,8 @@ const (
 
 	// DeprecatedHeader is the M3 deprecated header
 	DeprecatedHeader = "M3-Deprecated"
+
+	// TruncatedHeader is the M3 header indicating whether results were
+	// truncated to the requested or maximum limit
+	TruncatedHeader = "M3-Results-Truncated"
 )
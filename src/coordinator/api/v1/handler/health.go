@@ -0,0 +1,133 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/m3db/m3db/src/coordinator/models"
+	"github.com/m3db/m3db/src/coordinator/storage"
+	"github.com/m3db/m3db/src/coordinator/storage/health"
+	"github.com/m3db/m3db/src/coordinator/util/logging"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// HealthURL is the url for the health handler
+	HealthURL = "/health"
+
+	// HealthHTTPMethod is the HTTP method used with this resource.
+	HealthHTTPMethod = http.MethodGet
+
+	defaultHealthProbeTimeout = 5 * time.Second
+	healthProbeWindow         = time.Minute
+
+	// healthProbeMetricName is the metric name the health probe searches
+	// for, an exact match is a single term lookup in the index regardless
+	// of whether any series has the name
+	healthProbeMetricName = "__health_probe__"
+)
+
+// HealthHandler represents a handler for the health endpoint
+type HealthHandler struct {
+	store        storage.Storage
+	tracker      *health.Tracker
+	start        time.Time
+	nowFn        func() time.Time
+	probeTimeout time.Duration
+}
+
+// HealthResponse is the response returned by the health endpoint
+type HealthResponse struct {
+	Uptime           string          `json:"uptime"`
+	StorageReachable bool            `json:"storageReachable"`
+	Error            string          `json:"error,omitempty"`
+	Stores           []health.Status `json:"stores,omitempty"`
+}
+
+// NewHealthHandler returns a new instance of handler, if tracker is not nil
+// the response includes the health of each store it tracks
+func NewHealthHandler(storage storage.Storage, tracker *health.Tracker) http.Handler {
+	return newHealthHandler(storage, tracker, time.Now)
+}
+
+func newHealthHandler(
+	storage storage.Storage,
+	tracker *health.Tracker,
+	nowFn func() time.Time,
+) *HealthHandler {
+	return &HealthHandler{
+		store:        storage,
+		tracker:      tracker,
+		start:        nowFn(),
+		nowFn:        nowFn,
+		probeTimeout: defaultHealthProbeTimeout,
+	}
+}
+
+func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := logging.WithContext(r.Context())
+
+	status := http.StatusOK
+	resp := HealthResponse{
+		Uptime:           h.nowFn().Sub(h.start).String(),
+		StorageReachable: true,
+	}
+	if err := h.probe(r.Context()); err != nil {
+		logger.Error("storage health probe failed", zap.Any("error", err))
+		status = http.StatusServiceUnavailable
+		resp.StorageReachable = false
+		resp.Error = err.Error()
+	}
+	if h.tracker != nil {
+		resp.Stores = h.tracker.Snapshot()
+	}
+
+	if status != http.StatusOK {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+	}
+	WriteJSONResponse(w, resp, logger)
+}
+
+// probe checks the storage backend is reachable by issuing an exact match
+// tag search over a short recent window that returns at most a single result
+func (h *HealthHandler) probe(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, h.probeTimeout)
+	defer cancel()
+
+	matcher, err := models.NewMatcher(models.MatchEqual, models.MetricName, healthProbeMetricName)
+	if err != nil {
+		return err
+	}
+
+	now := h.nowFn()
+	query := &storage.FetchQuery{
+		TagMatchers: models.Matchers{matcher},
+		Start:       now.Add(-healthProbeWindow),
+		End:         now,
+	}
+	_, err = h.store.FetchTags(ctx, query, &storage.FetchOptions{Limit: 1})
+	return err
+}
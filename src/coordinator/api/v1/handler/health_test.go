@@ -0,0 +1,123 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/m3db/m3db/src/coordinator/models"
+	"github.com/m3db/m3db/src/coordinator/storage"
+	"github.com/m3db/m3db/src/coordinator/storage/health"
+	"github.com/m3db/m3db/src/coordinator/storage/mock"
+	"github.com/m3db/m3db/src/coordinator/util/logging"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type probeStorage struct {
+	storage.Storage
+	query   *storage.FetchQuery
+	options *storage.FetchOptions
+	err     error
+}
+
+func (s *probeStorage) FetchTags(
+	ctx context.Context,
+	query *storage.FetchQuery,
+	options *storage.FetchOptions,
+) (*storage.SearchResults, error) {
+	s.query, s.options = query, options
+	return nil, s.err
+}
+
+func serveHealth(
+	t *testing.T,
+	store storage.Storage,
+	tracker *health.Tracker,
+) (int, HealthResponse) {
+	now := time.Unix(0, 0)
+	h := newHealthHandler(store, tracker, func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	})
+
+	req := httptest.NewRequest(HealthHTTPMethod, HealthURL, nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	resp := w.Result()
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var health HealthResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+	return resp.StatusCode, health
+}
+
+func TestHealthHandler(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	store := &probeStorage{Storage: mock.NewMockStorage()}
+	status, health := serveHealth(t, store, nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.True(t, health.StorageReachable)
+	assert.Equal(t, "1s", health.Uptime)
+	assert.Empty(t, health.Error)
+
+	require.NotNil(t, store.options)
+	assert.Equal(t, 1, store.options.Limit)
+	require.NotNil(t, store.query)
+	require.Len(t, store.query.TagMatchers, 1)
+	matcher := store.query.TagMatchers[0]
+	assert.Equal(t, models.MatchEqual, matcher.Type)
+	assert.Equal(t, healthProbeMetricName, matcher.Value)
+}
+
+func TestHealthHandlerStorageUnavailable(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	store := &probeStorage{
+		Storage: mock.NewMockStorage(),
+		err:     errors.New("connection refused"),
+	}
+	status, health := serveHealth(t, store, nil)
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	assert.False(t, health.StorageReachable)
+	assert.Equal(t, "connection refused", health.Error)
+}
+
+func TestHealthHandlerStoreStatus(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	tracker := health.NewTracker()
+	store := health.NewStorage(&probeStorage{Storage: mock.NewMockStorage()}, "local", tracker)
+	status, resp := serveHealth(t, store, tracker)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, resp.Stores, 1)
+	assert.Equal(t, "local", resp.Stores[0].Name)
+	assert.Equal(t, int64(1), resp.Stores[0].Successes)
+}
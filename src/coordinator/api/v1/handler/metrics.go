@@ -0,0 +1,101 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/uber-go/tally"
+)
+
+const (
+	routeTag  = "route"
+	statusTag = "status"
+)
+
+var (
+	// DefaultLatencyBuckets are the default buckets of the request latency
+	// histogram, from 1ms doubling up to 32s
+	DefaultLatencyBuckets = tally.MustMakeExponentialDurationBuckets(time.Millisecond, 2, 16)
+)
+
+// WithRequestMetrics wraps around the given handler, counting requests by
+// status code and recording their latency, tagged with the route, responses
+// with a 5xx status code are also counted as errors. The route should be the
+// route template rather than the request path to keep the number of distinct
+// tag values bounded.
+func WithRequestMetrics(next http.Handler, scope tally.Scope, route string) http.Handler {
+	scope = scope.Tagged(map[string]string{routeTag: route})
+	errCounter := scope.Counter("request-errors")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		elapsed := time.Since(start)
+
+		status := sw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		tagged := scope.Tagged(map[string]string{statusTag: strconv.Itoa(status)})
+		tagged.Counter("request").Inc(1)
+		tagged.Histogram("latency", DefaultLatencyBuckets).RecordDuration(elapsed)
+		if status >= http.StatusInternalServerError {
+			errCounter.Inc(1)
+		}
+	})
+}
+
+// statusResponseWriter records the status code written to the response,
+// forwarding flushes and close notifications when the response supports them
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *statusResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *statusResponseWriter) CloseNotify() <-chan bool {
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	// Never notify if the response cannot detect the client disconnecting
+	return make(chan bool)
+}
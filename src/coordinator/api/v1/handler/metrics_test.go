@@ -0,0 +1,88 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+)
+
+func TestWithRequestMetrics(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	status := http.StatusOK
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+		}
+		w.Write([]byte("ok"))
+	})
+	h := WithRequestMetrics(next, scope, "/test/{id}")
+
+	for _, s := range []int{http.StatusOK, http.StatusOK, http.StatusInternalServerError} {
+		status = s
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test/1", nil))
+		require.Equal(t, s, w.Code)
+	}
+
+	snapshot := scope.Snapshot()
+	counters := snapshot.Counters()
+	requests := func(status string) int64 {
+		c, ok := counters[tally.KeyForPrefixedStringMap("request",
+			map[string]string{routeTag: "/test/{id}", statusTag: status})]
+		require.True(t, ok, "no request counter for status %s", status)
+		return c.Value()
+	}
+	assert.Equal(t, int64(2), requests("200"))
+	assert.Equal(t, int64(1), requests("500"))
+
+	errs, ok := counters[tally.KeyForPrefixedStringMap("request-errors",
+		map[string]string{routeTag: "/test/{id}"})]
+	require.True(t, ok)
+	assert.Equal(t, int64(1), errs.Value())
+
+	latency, ok := snapshot.Histograms()[tally.KeyForPrefixedStringMap("latency",
+		map[string]string{routeTag: "/test/{id}", statusTag: "200"})]
+	require.True(t, ok)
+	var recorded int64
+	for _, count := range latency.Durations() {
+		recorded += count
+	}
+	assert.Equal(t, int64(2), recorded)
+}
+
+func TestWithRequestMetricsForwardsFlush(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		require.True(t, ok)
+		w.Write([]byte("ok"))
+		flusher.Flush()
+	})
+
+	w := httptest.NewRecorder()
+	WithRequestMetrics(next, tally.NewTestScope("", nil), "/").
+		ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.True(t, w.Flushed)
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package namespace
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	clusterclient "github.com/m3db/m3cluster/client"
+	"github.com/m3db/m3db/src/coordinator/api/v1/handler"
+	"github.com/m3db/m3db/src/coordinator/generated/proto/admin"
+	"github.com/m3db/m3db/src/coordinator/util/logging"
+	nsproto "github.com/m3db/m3db/src/dbnode/generated/proto/namespace"
+	"github.com/m3db/m3db/src/dbnode/storage/namespace"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"go.uber.org/zap"
+)
+
+const (
+	// BulkAddURL is the url for the namespace bulk add handler.
+	BulkAddURL = handler.RoutePrefixV1 + "/namespace/bulk"
+
+	// BulkAddHTTPMethod is the HTTP method used with this resource.
+	BulkAddHTTPMethod = http.MethodPost
+)
+
+var (
+	errNoNamespaces = errors.New("must specify at least one namespace to add")
+)
+
+// BulkAddHandler is the handler for adding several namespaces at once.
+type BulkAddHandler Handler
+
+// NewBulkAddHandler returns a new instance of BulkAddHandler.
+func NewBulkAddHandler(client clusterclient.Client) *BulkAddHandler {
+	return &BulkAddHandler{client: client}
+}
+
+func (h *BulkAddHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	logger := logging.WithContext(ctx)
+
+	addReqs, rErr := h.parseRequest(r)
+	if rErr != nil {
+		logger.Error("unable to parse request", zap.Any("error", rErr))
+		handler.Error(w, rErr.Inner(), rErr.Code())
+		return
+	}
+
+	nsRegistry, err := h.BulkAdd(addReqs)
+	if err != nil {
+		logger.Error("unable to add namespaces", zap.Any("error", err))
+		handler.Error(w, err, http.StatusBadRequest)
+		return
+	}
+
+	resp := &admin.NamespaceGetResponse{
+		Registry: &nsRegistry,
+	}
+
+	handler.WriteProtoMsgJSONResponse(w, resp, logger)
+}
+
+func (h *BulkAddHandler) parseRequest(r *http.Request) ([]*admin.NamespaceAddRequest, *handler.ParseError) {
+	defer r.Body.Close()
+
+	var dicts []map[string]interface{}
+	d := json.NewDecoder(r.Body)
+	d.UseNumber()
+	if err := d.Decode(&dicts); err != nil {
+		return nil, handler.NewParseError(err, http.StatusBadRequest)
+	}
+	if len(dicts) == 0 {
+		return nil, handler.NewParseError(errNoNamespaces, http.StatusBadRequest)
+	}
+
+	addReqs := make([]*admin.NamespaceAddRequest, 0, len(dicts))
+	for i, dict := range dicts {
+		dict, err := handler.DurationToNanosMap(dict)
+		if err != nil {
+			return nil, handler.NewParseError(fmt.Errorf("namespace %d: %v", i, err), http.StatusBadRequest)
+		}
+
+		rBody, err := json.Marshal(dict)
+		if err != nil {
+			return nil, handler.NewParseError(err, http.StatusBadRequest)
+		}
+
+		addReq := new(admin.NamespaceAddRequest)
+		if err := jsonpb.Unmarshal(bytes.NewReader(rBody), addReq); err != nil {
+			return nil, handler.NewParseError(fmt.Errorf("namespace %d: %v", i, err), http.StatusBadRequest)
+		}
+		addReqs = append(addReqs, addReq)
+	}
+
+	return addReqs, nil
+}
+
+// BulkAdd adds all the namespaces or none of them. The namespaces are added
+// to the registry with a single check and set so a failure to add any of them,
+// or a concurrent change to the registry, leaves the registry unchanged.
+func (h *BulkAddHandler) BulkAdd(addReqs []*admin.NamespaceAddRequest) (nsproto.Registry, error) {
+	var emptyReg = nsproto.Registry{}
+
+	mds := make([]namespace.Metadata, 0, len(addReqs))
+	for _, addReq := range addReqs {
+		md, err := namespace.ToMetadata(addReq.Name, addReq.Options)
+		if err != nil {
+			return emptyReg, fmt.Errorf("unable to get metadata for namespace %s: %v", addReq.Name, err)
+		}
+		mds = append(mds, md)
+	}
+
+	store, err := h.client.KV()
+	if err != nil {
+		return emptyReg, err
+	}
+
+	currentMetadata, version, err := Metadata(store)
+	if err != nil {
+		return emptyReg, err
+	}
+
+	nsMap, err := namespace.NewMap(append(currentMetadata, mds...))
+	if err != nil {
+		return emptyReg, err
+	}
+
+	protoRegistry := namespace.ToProto(nsMap)
+	_, err = store.CheckAndSet(M3DBNodeNamespacesKey, version, protoRegistry)
+	if err != nil {
+		return emptyReg, fmt.Errorf("failed to add namespaces: %v", err)
+	}
+
+	return *protoRegistry, nil
+}
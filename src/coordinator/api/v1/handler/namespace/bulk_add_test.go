@@ -0,0 +1,123 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package namespace
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/m3db/m3cluster/kv"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bulkAddNamespaceJSON(name string) string {
+	return fmt.Sprintf(`
+        {
+            "name": "%s",
+            "options": {
+              "bootstrapEnabled": true,
+              "flushEnabled": true,
+              "writesToCommitLog": true,
+              "retentionOptions": {
+                "retentionPeriodDuration": "48h",
+                "blockSizeDuration": "2h",
+                "bufferFutureDuration": "10m",
+                "bufferPastDuration": "10m"
+              },
+              "indexOptions": {
+                "enabled": true,
+                "blockSizeDuration": "2h"
+              }
+            }
+        }
+    `, name)
+}
+
+func TestNamespaceBulkAddHandler(t *testing.T) {
+	mockClient, mockKV, _ := SetupNamespaceTest(t)
+	bulkAddHandler := NewBulkAddHandler(mockClient)
+
+	jsonInput := "[" + bulkAddNamespaceJSON("first") + "," + bulkAddNamespaceJSON("second") + "]"
+	req := httptest.NewRequest(BulkAddHTTPMethod, BulkAddURL, strings.NewReader(jsonInput))
+
+	mockKV.EXPECT().Get(M3DBNodeNamespacesKey).Return(nil, kv.ErrNotFound)
+	mockKV.EXPECT().CheckAndSet(M3DBNodeNamespacesKey, 0, gomock.Not(nil)).Return(1, nil)
+
+	w := httptest.NewRecorder()
+	bulkAddHandler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Registry struct {
+			Namespaces map[string]interface{} `json:"namespaces"`
+		} `json:"registry"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, len(resp.Registry.Namespaces))
+	assert.Contains(t, resp.Registry.Namespaces, "first")
+	assert.Contains(t, resp.Registry.Namespaces, "second")
+}
+
+func TestNamespaceBulkAddHandlerAllOrNothing(t *testing.T) {
+	mockClient, mockKV, _ := SetupNamespaceTest(t)
+	bulkAddHandler := NewBulkAddHandler(mockClient)
+
+	// No write is made to the KV store in any of the cases below
+	for _, tc := range []struct {
+		name      string
+		jsonInput string
+		get       bool
+	}{
+		{
+			name:      "invalid namespace",
+			jsonInput: "[" + bulkAddNamespaceJSON("first") + `,{"name": "second", "options": {}}]`,
+		},
+		{
+			name:      "duplicate namespace",
+			jsonInput: "[" + bulkAddNamespaceJSON("first") + "," + bulkAddNamespaceJSON("first") + "]",
+			get:       true,
+		},
+		{
+			name:      "no namespaces",
+			jsonInput: "[]",
+		},
+		{
+			name:      "not an array",
+			jsonInput: bulkAddNamespaceJSON("first"),
+		},
+	} {
+		if tc.get {
+			mockKV.EXPECT().Get(M3DBNodeNamespacesKey).Return(nil, kv.ErrNotFound)
+		}
+
+		req := httptest.NewRequest(BulkAddHTTPMethod, BulkAddURL, strings.NewReader(tc.jsonInput))
+		w := httptest.NewRecorder()
+		bulkAddHandler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, tc.name)
+	}
+}
@@ -21,6 +21,7 @@
 package openapi
 
 import (
+	"bytes"
 	"net/http"
 
 	"github.com/m3db/m3db/src/coordinator/api/v1/handler"
@@ -37,7 +38,8 @@ const (
 	// HTTPMethod is the HTTP method used with this resource.
 	HTTPMethod = http.MethodGet
 
-	docPath = "/index.html"
+	docPath  = "/index.html"
+	specPath = "/spec.yml"
 )
 
 var (
@@ -66,5 +68,36 @@ func (h *DocHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 // StaticHandler is the handler for serving static assets (including OpenAPI specs).
 func StaticHandler() http.Handler {
-	return http.StripPrefix(StaticURLPrefix, http.FileServer(assets.FS(false)))
+	return NewStaticHandler("")
+}
+
+// NewStaticHandler returns the handler for serving static assets (including
+// OpenAPI specs) when routes are served under the base path, the served spec's
+// base path is prefixed with it.
+func NewStaticHandler(basePath string) http.Handler {
+	fileServer := http.FileServer(assets.FS(false))
+	if basePath == "" {
+		return http.StripPrefix(StaticURLPrefix, fileServer)
+	}
+
+	return http.StripPrefix(basePath+StaticURLPrefix, http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != specPath[1:] {
+				fileServer.ServeHTTP(w, r)
+				return
+			}
+
+			spec, err := assets.FSByte(false, specPath)
+			if err != nil {
+				logging.WithContext(r.Context()).Error("unable to load spec", zap.Any("error", err))
+				handler.Error(w, err, http.StatusInternalServerError)
+				return
+			}
+
+			spec = bytes.Replace(spec,
+				[]byte(`basePath: "`+handler.RoutePrefixV1+`"`),
+				[]byte(`basePath: "`+basePath+handler.RoutePrefixV1+`"`), 1)
+			w.Header().Set("Content-Type", "text/yaml; charset=utf-8")
+			w.Write(spec)
+		}))
 }
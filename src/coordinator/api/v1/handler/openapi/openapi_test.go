@@ -50,3 +50,28 @@ func TestDocHandler(t *testing.T) {
 func TestStaticHandler(t *testing.T) {
 	assert.NotNil(t, StaticHandler())
 }
+
+func TestStaticHandlerBasePath(t *testing.T) {
+	for _, test := range []struct {
+		basePath string
+		specBase string
+	}{
+		{basePath: "", specBase: `basePath: "/api/v1"`},
+		{basePath: "/prefix", specBase: `basePath: "/prefix/api/v1"`},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", test.basePath+StaticURLPrefix+"spec.yml", nil)
+		NewStaticHandler(test.basePath).ServeHTTP(w, req)
+
+		resp := w.Result()
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, string(body), test.specBase)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", StaticURLPrefix+"spec.yml", nil)
+	NewStaticHandler("/prefix").ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
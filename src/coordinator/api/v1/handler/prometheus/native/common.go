@@ -133,38 +133,42 @@ func renderResultsJSON(w io.Writer, series []*ts.Series) {
 	jw := json.NewWriter(w)
 	jw.BeginArray()
 	for _, s := range series {
-		jw.BeginObject()
-		jw.BeginObjectField("target")
-		jw.WriteString(s.Name())
-
-		jw.BeginObjectField("tags")
-		jw.BeginObject()
-		for k, v := range s.Tags {
-			jw.BeginObjectField(k)
-			jw.WriteString(v)
-		}
-		jw.EndObject()
+		renderSeriesJSON(jw, s)
+	}
 
-		jw.BeginObjectField("datapoints")
-		jw.BeginArray()
-		vals := s.Values()
-		for i := 0; i < s.Len(); i++ {
-			dp := vals.DatapointAt(i)
-			jw.BeginArray()
-			jw.WriteFloat64(dp.Value)
-			jw.WriteInt(int(dp.Timestamp.Unix()))
-			jw.EndArray()
-		}
-		jw.EndArray()
+	jw.EndArray()
+	jw.Close()
+}
 
-		fixedStep, ok := s.Values().(ts.FixedResolutionMutableValues)
-		if ok {
-			jw.BeginObjectField("step_size_ms")
-			jw.WriteInt(int(util.DurationToMS(fixedStep.MillisPerStep())))
-			jw.EndObject()
-		}
+func renderSeriesJSON(jw *json.Writer, s *ts.Series) {
+	jw.BeginObject()
+	jw.BeginObjectField("target")
+	jw.WriteString(s.Name())
+
+	jw.BeginObjectField("tags")
+	jw.BeginObject()
+	for k, v := range s.Tags {
+		jw.BeginObjectField(k)
+		jw.WriteString(v)
 	}
+	jw.EndObject()
 
+	jw.BeginObjectField("datapoints")
+	jw.BeginArray()
+	vals := s.Values()
+	for i := 0; i < s.Len(); i++ {
+		dp := vals.DatapointAt(i)
+		jw.BeginArray()
+		jw.WriteFloat64(dp.Value)
+		jw.WriteInt(int(dp.Timestamp.Unix()))
+		jw.EndArray()
+	}
 	jw.EndArray()
-	jw.Close()
+
+	fixedStep, ok := s.Values().(ts.FixedResolutionMutableValues)
+	if ok {
+		jw.BeginObjectField("step_size_ms")
+		jw.WriteInt(int(util.DurationToMS(fixedStep.MillisPerStep())))
+	}
+	jw.EndObject()
 }
@@ -21,11 +21,16 @@
 package native
 
 import (
+	"bytes"
+	"math"
 	"net/http"
 	"net/url"
 	"testing"
 	"time"
 
+	"github.com/m3db/m3db/src/coordinator/models"
+	"github.com/m3db/m3db/src/coordinator/ts"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -74,3 +79,28 @@ func TestInvalidTarget(t *testing.T) {
 	assert.NotNil(t, p.Start)
 	require.Equal(t, err.Code(), http.StatusBadRequest)
 }
+
+func TestRenderResultsJSON(t *testing.T) {
+	start := time.Unix(1530220860, 0)
+	fixed := ts.NewFixedStepValues(10*time.Second, 2, math.NaN(), start)
+	fixed.SetValueAt(0, 1)
+
+	series := []*ts.Series{
+		ts.NewSeries("foo", fixed, models.Tags{"a": "b"}),
+		ts.NewSeries("bar", ts.Datapoints{
+			{Timestamp: start, Value: math.NaN()},
+			{Timestamp: start.Add(15 * time.Second), Value: 2},
+		}, models.Tags{}),
+	}
+
+	var buf bytes.Buffer
+	renderResultsJSON(&buf, series)
+
+	expected := `[` +
+		`{"target":"foo","tags":{"a":"b"},` +
+		`"datapoints":[[1.000000,1530220860],[null,1530220870]],"step_size_ms":10000},` +
+		`{"target":"bar","tags":{},` +
+		`"datapoints":[[null,1530220860],[2.000000,1530220875]]}` +
+		`]`
+	assert.Equal(t, expected, buf.String())
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package native
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/m3db/m3db/src/coordinator/block"
+	"github.com/m3db/m3db/src/coordinator/executor"
+	"github.com/m3db/m3db/src/coordinator/functions"
+	"github.com/m3db/m3db/src/coordinator/functions/linear"
+	"github.com/m3db/m3db/src/coordinator/models"
+	"github.com/m3db/m3db/src/coordinator/parser"
+	"github.com/m3db/m3db/src/coordinator/ts"
+)
+
+const (
+	// minPartitionSize is the smallest time range a query is partitioned
+	// into, smaller configured partition sizes are raised to it
+	minPartitionSize = time.Hour
+
+	// maxPartitionConcurrency is the most partitions of a single query that
+	// are executed at once
+	maxPartitionConcurrency = 8
+)
+
+// partitionableOps are the operations whose output at each step depends only
+// on their input at the same step, so evaluating them over adjacent time
+// ranges and concatenating the results is equivalent to a single evaluation.
+var partitionableOps = map[string]struct{}{
+	functions.FetchType: struct{}{},
+	functions.CountType: struct{}{},
+	linear.AbsType:      struct{}{},
+	linear.ClampMinType: struct{}{},
+	linear.ClampMaxType: struct{}{},
+}
+
+// isPartitionable returns whether every node in the query can be evaluated
+// independently over time. Range selectors look back across steps and
+// operations such as absent or binary matching may produce a different set
+// of series per partition, so those queries are executed serially.
+func isPartitionable(p parser.Parser) bool {
+	nodes, _, err := p.DAG()
+	if err != nil {
+		return false
+	}
+
+	for _, node := range nodes {
+		if _, ok := partitionableOps[node.Op.OpType()]; !ok {
+			return false
+		}
+		if fetch, ok := node.Op.(functions.FetchOp); ok && fetch.Range != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// partitionParams splits the request into consecutive step aligned time
+// ranges of at most partitionSize, every step of the original request is
+// covered by exactly one partition.
+func partitionParams(params models.RequestParams, partitionSize time.Duration) []models.RequestParams {
+	if params.Step <= 0 || params.Start.After(params.End) {
+		return []models.RequestParams{params}
+	}
+
+	stepsPerPartition := int(partitionSize / params.Step)
+	if stepsPerPartition < 1 {
+		stepsPerPartition = 1
+	}
+
+	var (
+		partitions []models.RequestParams
+		span       = time.Duration(stepsPerPartition-1) * params.Step
+	)
+	for start := params.Start; !start.After(params.End); start = start.Add(span + params.Step) {
+		end := start.Add(span)
+		if end.After(params.End) {
+			end = params.End
+		}
+
+		partition := params
+		partition.Start = start
+		partition.End = end
+		partitions = append(partitions, partition)
+	}
+
+	return partitions
+}
+
+// executePartitioned executes the query over each partition, at most
+// maxPartitionConcurrency at a time, and merges the results. The first error
+// cancels the partitions still executing and is returned.
+func (h *PromReadHandler) executePartitioned(
+	ctx context.Context,
+	parser parser.Parser,
+	opts *executor.EngineOptions,
+	params models.RequestParams,
+	partitions []models.RequestParams,
+) ([]*ts.Series, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		sem      = make(chan struct{}, maxPartitionConcurrency)
+		results  = make([]ts.TimePartition, len(partitions))
+	)
+	for i, partition := range partitions {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		i, partition := i, partition
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			series, err := h.execute(ctx, parser, opts, partition)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[i] = ts.TimePartition{Start: partition.Start, Series: series}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	bounds := block.Bounds{
+		Start:    params.Start,
+		End:      params.End,
+		StepSize: params.Step,
+	}
+	merged := ts.MergeTimePartitions(params.Start, params.Step, bounds.Steps(), results)
+	if len(merged) == 0 {
+		return emptySeriesList, nil
+	}
+
+	return merged, nil
+}
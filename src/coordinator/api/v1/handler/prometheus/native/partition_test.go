@@ -0,0 +1,148 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package native
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/m3db/m3db/src/coordinator/block"
+	"github.com/m3db/m3db/src/coordinator/executor"
+	"github.com/m3db/m3db/src/coordinator/models"
+	"github.com/m3db/m3db/src/coordinator/parser/promql"
+	"github.com/m3db/m3db/src/coordinator/storage"
+	"github.com/m3db/m3db/src/coordinator/storage/mock"
+	"github.com/m3db/m3db/src/coordinator/util/logging"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPartitionable(t *testing.T) {
+	tests := []struct {
+		query         string
+		partitionable bool
+	}{
+		{"up", true},
+		{"abs(up)", true},
+		{"clamp_max(up, 3)", true},
+		{"count(up)", true},
+		{"absent(up)", false},
+		{"up[5m]", false},
+		{"up and down", false},
+	}
+
+	for _, tt := range tests {
+		p, err := promql.Parse(tt.query)
+		require.NoError(t, err, tt.query)
+		assert.Equal(t, tt.partitionable, isPartitionable(p), tt.query)
+	}
+}
+
+func TestPartitionParams(t *testing.T) {
+	start := time.Unix(0, 0)
+	params := models.RequestParams{
+		Start:  start,
+		End:    start.Add(9 * time.Minute),
+		Step:   time.Minute,
+		Target: "up",
+	}
+
+	partitions := partitionParams(params, 4*time.Minute)
+	require.Len(t, partitions, 3)
+
+	expected := []struct{ start, end time.Duration }{
+		{0, 3 * time.Minute},
+		{4 * time.Minute, 7 * time.Minute},
+		{8 * time.Minute, 9 * time.Minute},
+	}
+	for i, partition := range partitions {
+		assert.Equal(t, start.Add(expected[i].start), partition.Start)
+		assert.Equal(t, start.Add(expected[i].end), partition.End)
+		assert.Equal(t, params.Step, partition.Step)
+		assert.Equal(t, params.Target, partition.Target)
+	}
+
+	// Partition sizes smaller than the step still make progress
+	assert.Len(t, partitionParams(params, time.Second), 10)
+}
+
+func TestPartitionSizeMinimum(t *testing.T) {
+	engine := executor.NewEngine(mock.NewMockStorage())
+	h := NewPromReadHandler(engine, 0, time.Second).(*PromReadHandler)
+	assert.Equal(t, minPartitionSize, h.partitionSize)
+
+	h = NewPromReadHandler(engine, 0, 0).(*PromReadHandler)
+	assert.Equal(t, time.Duration(0), h.partitionSize)
+}
+
+type failFirstStorage struct {
+	storage.Storage
+	calls   int32
+	ctxErrs chan error
+}
+
+func (s *failFirstStorage) FetchBlocks(
+	ctx context.Context,
+	query *storage.FetchQuery,
+	options *storage.FetchOptions,
+) (block.Result, error) {
+	if atomic.AddInt32(&s.calls, 1) == 1 {
+		return block.Result{}, errors.New("fetch failed")
+	}
+	<-ctx.Done()
+	s.ctxErrs <- ctx.Err()
+	return block.Result{}, ctx.Err()
+}
+
+func TestPartitionedReadCancelsOnError(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	start := time.Now().Truncate(time.Hour)
+	params := models.RequestParams{
+		Now:     start,
+		Start:   start,
+		End:     start.Add(3 * time.Hour),
+		Step:    time.Minute,
+		Target:  "up",
+		Timeout: time.Minute,
+	}
+	numPartitions := len(partitionParams(params, time.Hour))
+	require.True(t, numPartitions > 1)
+
+	store := &failFirstStorage{
+		Storage: mock.NewMockStorage(),
+		ctxErrs: make(chan error, numPartitions),
+	}
+	h := NewPromReadHandler(executor.NewEngine(store), 0, time.Hour).(*PromReadHandler)
+	_, err := h.read(context.Background(), httptest.NewRecorder(), params)
+	require.Error(t, err)
+
+	// Every other partition that started observes the cancellation
+	calls := int(atomic.LoadInt32(&store.calls))
+	require.Equal(t, calls-1, len(store.ctxErrs))
+	for i := 1; i < calls; i++ {
+		assert.Equal(t, context.Canceled, <-store.ctxErrs)
+	}
+}
@@ -26,13 +26,17 @@ import (
 	"math"
 	"net/http"
 	"sort"
+	"strconv"
+	"time"
 
 	"github.com/m3db/m3db/src/coordinator/api/v1/handler"
 	"github.com/m3db/m3db/src/coordinator/block"
 	"github.com/m3db/m3db/src/coordinator/executor"
 	"github.com/m3db/m3db/src/coordinator/models"
+	"github.com/m3db/m3db/src/coordinator/parser"
 	"github.com/m3db/m3db/src/coordinator/parser/promql"
 	"github.com/m3db/m3db/src/coordinator/ts"
+	"github.com/m3db/m3db/src/coordinator/util/json"
 	"github.com/m3db/m3db/src/coordinator/util/logging"
 
 	"go.uber.org/zap"
@@ -47,6 +51,10 @@ const (
 
 	// TODO: Move to config
 	initialBlockAlloc = 10
+
+	// streamParam selects streaming the response, series are encoded and
+	// flushed to the client one at a time instead of all at once
+	streamParam = "stream"
 )
 
 var (
@@ -55,7 +63,9 @@ var (
 
 // PromReadHandler represents a handler for prometheus read endpoint.
 type PromReadHandler struct {
-	engine *executor.Engine
+	engine        *executor.Engine
+	maxSamples    int
+	partitionSize time.Duration
 }
 
 // ReadResponse is the response that gets returned to the user
@@ -68,9 +78,24 @@ type blockWithMeta struct {
 	meta  block.Metadata
 }
 
-// NewPromReadHandler returns a new instance of handler.
-func NewPromReadHandler(engine *executor.Engine) http.Handler {
-	return &PromReadHandler{engine: engine}
+// NewPromReadHandler returns a new instance of handler. Responses with more
+// than maxSamples datapoints are rejected unless maxSamples is zero. If
+// partitionSize is positive then queries that can be evaluated independently
+// over time are split into time ranges of that size, or of an hour if it is
+// smaller, which are executed in parallel.
+func NewPromReadHandler(
+	engine *executor.Engine,
+	maxSamples int,
+	partitionSize time.Duration,
+) http.Handler {
+	if partitionSize > 0 && partitionSize < minPartitionSize {
+		partitionSize = minPartitionSize
+	}
+	return &PromReadHandler{
+		engine:        engine,
+		maxSamples:    maxSamples,
+		partitionSize: partitionSize,
+	}
 }
 
 func (h *PromReadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -87,6 +112,11 @@ func (h *PromReadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		logger.Info("Request params", zap.Any("params", params))
 	}
 
+	if stream, _ := strconv.ParseBool(r.FormValue(streamParam)); stream {
+		h.serveStream(ctx, w, params)
+		return
+	}
+
 	result, err := h.read(ctx, w, params)
 	if err != nil {
 		logger.Error("unable to fetch data", zap.Any("error", err))
@@ -94,15 +124,99 @@ func (h *PromReadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.withinSampleLimit(w, logger, func() int { return numSamples(result) }) {
+		return
+	}
+
 	// TODO: Support multiple result types
 	w.Header().Set("Content-Type", "application/json")
 	renderResultsJSON(w, result)
 }
 
+// serveStream executes the query and writes the resulting series to the
+// client as they are assembled from the result blocks, flushing after each
+// series, rather than first building the full list of series. Streamed
+// queries are never partitioned since partitions must be merged before any
+// series is complete.
+func (h *PromReadHandler) serveStream(ctx context.Context, w http.ResponseWriter, params models.RequestParams) {
+	logger := logging.WithContext(ctx)
+
+	blockList, err := h.readBlocks(ctx, w, params)
+	if err != nil {
+		logger.Error("unable to fetch data", zap.Any("error", err))
+		handler.Error(w, err, http.StatusInternalServerError)
+		return
+	}
+	defer closeBlocks(blockList)
+
+	if !h.withinSampleLimit(w, logger, func() int { return numBlockSamples(blockList) }) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	flusher, _ := w.(http.Flusher)
+	jw := json.NewWriter(w)
+	jw.BeginArray()
+	err = forEachSortedBlockSeries(blockList, func(s *ts.Series) error {
+		renderSeriesJSON(jw, s)
+		if err := jw.Flush(); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// The status has already been sent so the error cannot be returned,
+		// the array is left open so that clients fail to parse the response
+		// rather than mistaking it for a complete one
+		logger.Error("unable to stream results", zap.Any("error", err))
+		return
+	}
+	jw.EndArray()
+	if err := jw.Close(); err != nil {
+		logger.Error("unable to stream results", zap.Any("error", err))
+	}
+}
+
 func (h *PromReadHandler) read(reqCtx context.Context, w http.ResponseWriter, params models.RequestParams) ([]*ts.Series, error) {
 	ctx, cancel := context.WithTimeout(reqCtx, params.Timeout)
 	defer cancel()
 
+	parser, opts, err := parseQuery(ctx, w, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.partitionSize > 0 && isPartitionable(parser) {
+		if partitions := partitionParams(params, h.partitionSize); len(partitions) > 1 {
+			return h.executePartitioned(ctx, parser, opts, params, partitions)
+		}
+	}
+
+	return h.execute(ctx, parser, opts, params)
+}
+
+// readBlocks executes the query and returns the result blocks sorted by start
+// time, the caller is responsible for closing the blocks.
+func (h *PromReadHandler) readBlocks(reqCtx context.Context, w http.ResponseWriter, params models.RequestParams) ([]blockWithMeta, error) {
+	ctx, cancel := context.WithTimeout(reqCtx, params.Timeout)
+	defer cancel()
+
+	parser, opts, err := parseQuery(ctx, w, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.executeBlocks(ctx, parser, opts, params)
+}
+
+func parseQuery(
+	ctx context.Context,
+	w http.ResponseWriter,
+	params models.RequestParams,
+) (parser.Parser, *executor.EngineOptions, error) {
 	opts := &executor.EngineOptions{}
 	// Detect clients closing connections
 	abortCh, _ := handler.CloseWatcher(ctx, w)
@@ -110,10 +224,36 @@ func (h *PromReadHandler) read(reqCtx context.Context, w http.ResponseWriter, pa
 
 	// TODO: Capture timing
 	parser, err := promql.Parse(params.Target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return parser, opts, nil
+}
+
+func (h *PromReadHandler) execute(
+	ctx context.Context,
+	parser parser.Parser,
+	opts *executor.EngineOptions,
+	params models.RequestParams,
+) ([]*ts.Series, error) {
+	sortedBlockList, err := h.executeBlocks(ctx, parser, opts, params)
 	if err != nil {
 		return nil, err
 	}
+	defer closeBlocks(sortedBlockList)
+
+	return sortedBlocksToSeriesList(sortedBlockList)
+}
 
+// executeBlocks executes the query and returns the result blocks sorted by
+// start time, the caller is responsible for closing the blocks.
+func (h *PromReadHandler) executeBlocks(
+	ctx context.Context,
+	parser parser.Parser,
+	opts *executor.EngineOptions,
+	params models.RequestParams,
+) ([]blockWithMeta, error) {
 	// Results is closed by execute
 	results := make(chan executor.Query)
 	go h.engine.ExecuteExpr(ctx, parser, opts, params, results)
@@ -158,6 +298,7 @@ func (h *PromReadHandler) read(reqCtx context.Context, w http.ResponseWriter, pa
 			}
 
 			// Insert blocks sorted by start time
+			var err error
 			sortedBlockList, err = insertSortedBlock(b, sortedBlockList, numSteps, numSeries)
 			if err != nil {
 				processErr = err
@@ -166,20 +307,72 @@ func (h *PromReadHandler) read(reqCtx context.Context, w http.ResponseWriter, pa
 		}
 	}
 
-	// Ensure that the blocks are closed. Can't do this above since sortedBlockList might change
-	defer func() {
-		for _, b := range sortedBlockList {
-			b.block.Close()
-		}
-	}()
-
 	if processErr != nil {
+		// Ensure that the blocks are closed. Can't do this above since sortedBlockList might change
+		closeBlocks(sortedBlockList)
 		// Drain anything remaining
 		drainResultChan(results)
 		return nil, processErr
 	}
 
-	return sortedBlocksToSeriesList(sortedBlockList)
+	return sortedBlockList, nil
+}
+
+// withinSampleLimit returns whether a response with the number of samples
+// returned by numSamples is within the sample limit, writing an error
+// response if it is not. Samples are only counted if there is a limit.
+func (h *PromReadHandler) withinSampleLimit(
+	w http.ResponseWriter,
+	logger *zap.Logger,
+	numSamples func() int,
+) bool {
+	if h.maxSamples <= 0 {
+		return true
+	}
+	n := numSamples()
+	if n <= h.maxSamples {
+		return true
+	}
+
+	err := fmt.Errorf("response has %d samples, exceeds limit of %d", n, h.maxSamples)
+	logger.Error("response too large", zap.Any("error", err))
+	handler.Error(w, err, http.StatusRequestEntityTooLarge)
+	return false
+}
+
+func closeBlocks(blockList []blockWithMeta) {
+	for _, b := range blockList {
+		b.block.Close()
+	}
+}
+
+func numSamples(seriesList []*ts.Series) int {
+	n := 0
+	for _, s := range seriesList {
+		n += s.Values().Len()
+	}
+	return n
+}
+
+// numBlockSamples returns the number of samples in the series built from the
+// sorted blocks, every block has the same number of series and steps.
+func numBlockSamples(blockList []blockWithMeta) int {
+	if len(blockList) == 0 {
+		return 0
+	}
+
+	firstBlock := blockList[0].block
+	firstStepIter, err := firstBlock.StepIter()
+	if err != nil {
+		return 0
+	}
+
+	firstSeriesIter, err := firstBlock.SeriesIter()
+	if err != nil {
+		return 0
+	}
+
+	return firstSeriesIter.SeriesCount() * firstStepIter.StepCount() * len(blockList)
 }
 
 func drainResultChan(resultsChan chan executor.Query) {
@@ -200,29 +393,48 @@ func sortedBlocksToSeriesList(blockList []blockWithMeta) ([]*ts.Series, error) {
 		return emptySeriesList, nil
 	}
 
+	var seriesList []*ts.Series
+	err := forEachSortedBlockSeries(blockList, func(s *ts.Series) error {
+		seriesList = append(seriesList, s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return seriesList, nil
+}
+
+// forEachSortedBlockSeries combines the series of the blocks, which must be
+// sorted by start time, and calls fn with each series in turn so that only
+// one combined series needs to be held at a time.
+func forEachSortedBlockSeries(blockList []blockWithMeta, fn func(*ts.Series) error) error {
+	if len(blockList) == 0 {
+		return nil
+	}
+
 	firstBlock := blockList[0].block
 	firstStepIter, err := firstBlock.StepIter()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	firstSeriesIter, err := firstBlock.SeriesIter()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	numSeries := firstSeriesIter.SeriesCount()
 	seriesMeta := firstSeriesIter.SeriesMeta()
 	bounds := firstSeriesIter.Meta().Bounds
 
-	seriesList := make([]*ts.Series, numSeries)
 	seriesIters := make([]block.SeriesIter, len(blockList))
 	// To create individual series, we iterate over seriesIterators for each block in the block list.
 	// For each iterator, the nth current() will be combined to give the nth series
 	for i, b := range blockList {
 		seriesIter, err := b.block.SeriesIter()
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		seriesIters[i] = seriesIter
@@ -234,12 +446,12 @@ func sortedBlocksToSeriesList(blockList []blockWithMeta) ([]*ts.Series, error) {
 		valIdx := 0
 		for idx, iter := range seriesIters {
 			if !iter.Next() {
-				return nil, fmt.Errorf("invalid number of datapoints for series: %d, block: %d", i, idx)
+				return fmt.Errorf("invalid number of datapoints for series: %d, block: %d", i, idx)
 			}
 
 			blockSeries, err := iter.Current()
 			if err != nil {
-				return nil, err
+				return err
 			}
 
 			for i := 0; i < blockSeries.Len(); i++ {
@@ -248,10 +460,12 @@ func sortedBlocksToSeriesList(blockList []blockWithMeta) ([]*ts.Series, error) {
 			}
 		}
 
-		seriesList[i] = ts.NewSeries(seriesMeta[i].Name, values, seriesMeta[i].Tags)
+		if err := fn(ts.NewSeries(seriesMeta[i].Name, values, seriesMeta[i].Tags)); err != nil {
+			return err
+		}
 	}
 
-	return seriesList, nil
+	return nil
 }
 
 func insertSortedBlock(b block.Block, blockList []blockWithMeta, stepCount, seriesCount int) ([]blockWithMeta, error) {
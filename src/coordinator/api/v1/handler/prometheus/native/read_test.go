@@ -25,9 +25,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/m3db/m3db/src/coordinator/api/v1/handler"
 	"github.com/m3db/m3db/src/coordinator/block"
 	"github.com/m3db/m3db/src/coordinator/executor"
+	"github.com/m3db/m3db/src/coordinator/storage"
 	"github.com/m3db/m3db/src/coordinator/storage/mock"
 	"github.com/m3db/m3db/src/coordinator/test"
 	"github.com/m3db/m3db/src/coordinator/util/logging"
@@ -59,3 +62,109 @@ func TestPromRead(t *testing.T) {
 		assert.Equal(t, float64(i), s.Values().ValueAt(i))
 	}
 }
+
+func TestPromReadResponseSampleLimit(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	values, bounds := test.GenerateValuesAndBounds(nil, nil)
+	b := test.NewBlockFromValues(bounds, values)
+	mockStorage := mock.NewMockStorageWithBlocks([]block.Block{b})
+
+	for _, tc := range []struct {
+		maxSamples int
+		code       int
+	}{
+		{maxSamples: 0, code: http.StatusOK},
+		{maxSamples: 10, code: http.StatusOK},
+		{maxSamples: 9, code: http.StatusRequestEntityTooLarge},
+	} {
+		promRead := NewPromReadHandler(executor.NewEngine(mockStorage), tc.maxSamples, 0)
+		req, _ := http.NewRequest("GET", PromReadURL, nil)
+		req.URL.RawQuery = defaultParams().Encode()
+
+		recorder := httptest.NewRecorder()
+		promRead.ServeHTTP(recorder, req)
+		assert.Equal(t, tc.code, recorder.Code, "max samples %d", tc.maxSamples)
+	}
+}
+
+func TestPromReadStream(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	values, bounds := test.GenerateValuesAndBounds(nil, nil)
+	b := test.NewBlockFromValues(bounds, values)
+	mockStorage := mock.NewMockStorageWithBlocks([]block.Block{b})
+	promRead := NewPromReadHandler(executor.NewEngine(mockStorage), 0, 0)
+
+	req, _ := http.NewRequest("GET", PromReadURL, nil)
+	req.URL.RawQuery = defaultParams().Encode()
+	buffered := httptest.NewRecorder()
+	promRead.ServeHTTP(buffered, req)
+	require.Equal(t, http.StatusOK, buffered.Code)
+
+	params := defaultParams()
+	params.Set(streamParam, "true")
+	req, _ = http.NewRequest("GET", PromReadURL, nil)
+	req.URL.RawQuery = params.Encode()
+	streamed := httptest.NewRecorder()
+	promRead.ServeHTTP(streamed, req)
+	require.Equal(t, http.StatusOK, streamed.Code)
+
+	assert.True(t, streamed.Flushed)
+	assert.Equal(t, "application/json", streamed.Header().Get("Content-Type"))
+	assert.Equal(t, buffered.Body.String(), streamed.Body.String())
+}
+
+func TestPromReadStreamResponseSampleLimit(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	values, bounds := test.GenerateValuesAndBounds(nil, nil)
+	b := test.NewBlockFromValues(bounds, values)
+	mockStorage := mock.NewMockStorageWithBlocks([]block.Block{b})
+	promRead := NewPromReadHandler(executor.NewEngine(mockStorage), 9, 0)
+
+	params := defaultParams()
+	params.Set(streamParam, "true")
+	req, _ := http.NewRequest("GET", PromReadURL, nil)
+	req.URL.RawQuery = params.Encode()
+
+	recorder := httptest.NewRecorder()
+	promRead.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	assert.False(t, recorder.Flushed)
+}
+
+type blockingStorage struct {
+	storage.Storage
+	ctxErr chan error
+}
+
+func (s *blockingStorage) FetchBlocks(
+	ctx context.Context,
+	query *storage.FetchQuery,
+	options *storage.FetchOptions,
+) (block.Result, error) {
+	<-ctx.Done()
+	s.ctxErr <- ctx.Err()
+	return block.Result{}, ctx.Err()
+}
+
+func TestPromReadTimeoutCancelsStorage(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	store := &blockingStorage{Storage: mock.NewMockStorage(), ctxErr: make(chan error, 1)}
+	promRead := handler.WithTimeout(NewPromReadHandler(executor.NewEngine(store), 0, 0), 50*time.Millisecond)
+	req, _ := http.NewRequest("GET", PromReadURL, nil)
+	req.URL.RawQuery = defaultParams().Encode()
+
+	recorder := httptest.NewRecorder()
+	promRead.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	select {
+	case err := <-store.ctxErr:
+		assert.Equal(t, context.DeadlineExceeded, err)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "storage did not observe the cancelled context")
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package remote
+
+import (
+	"sync"
+	"time"
+)
+
+// SeriesLimitOptions are the options for limiting writes per series.
+type SeriesLimitOptions struct {
+	// Interval is the interval over which limits are enforced.
+	Interval time.Duration
+
+	// MaxSamplesPerSeries is the maximum number of samples a single series
+	// may write per interval, zero means unlimited.
+	MaxSamplesPerSeries int
+
+	// MaxNewSeries is the maximum number of previously unseen series that
+	// may be written per interval, zero means unlimited.
+	MaxNewSeries int
+
+	// MaxTrackedSeries bounds the number of series tracked per interval.
+	// Once reached, series that are not already tracked are rejected if any
+	// limit is set since their writes cannot be accounted for.
+	MaxTrackedSeries int
+}
+
+type seriesLimitResult int
+
+const (
+	seriesAllowed seriesLimitResult = iota
+	seriesSamplesLimited
+	seriesNewSeriesLimited
+)
+
+// SeriesLimiter limits the rate of samples written by individual series and
+// the rate at which new series are written to protect against cardinality
+// explosions.
+type SeriesLimiter struct {
+	sync.Mutex
+
+	opts        SeriesLimitOptions
+	nowFn       func() time.Time
+	windowStart time.Time
+	newSeries   int
+
+	// Series seen in the current and previous interval, a series is new if it
+	// was seen in neither. Bounded by MaxTrackedSeries each.
+	curr map[string]int
+	prev map[string]int
+}
+
+// NewSeriesLimiter returns a new series limiter.
+func NewSeriesLimiter(opts SeriesLimitOptions) *SeriesLimiter {
+	return newSeriesLimiter(opts, time.Now)
+}
+
+func newSeriesLimiter(opts SeriesLimitOptions, nowFn func() time.Time) *SeriesLimiter {
+	return &SeriesLimiter{
+		opts:        opts,
+		nowFn:       nowFn,
+		windowStart: nowFn(),
+		curr:        make(map[string]int),
+		prev:        make(map[string]int),
+	}
+}
+
+func (l *SeriesLimiter) allow(id string, samples int) seriesLimitResult {
+	l.Lock()
+	defer l.Unlock()
+
+	if now := l.nowFn(); now.Sub(l.windowStart) >= l.opts.Interval {
+		l.windowStart = now
+		l.newSeries = 0
+		l.prev = l.curr
+		l.curr = make(map[string]int, len(l.prev))
+	}
+
+	written, tracked := l.curr[id]
+	isNew := false
+	if !tracked {
+		_, seen := l.prev[id]
+		isNew = !seen
+	}
+	if isNew && l.opts.MaxNewSeries > 0 && l.newSeries >= l.opts.MaxNewSeries {
+		return seriesNewSeriesLimited
+	}
+	if l.opts.MaxSamplesPerSeries > 0 && written+samples > l.opts.MaxSamplesPerSeries {
+		return seriesSamplesLimited
+	}
+
+	if !tracked && len(l.curr) >= l.opts.MaxTrackedSeries {
+		// The series cannot be tracked, so neither its samples nor whether
+		// it is new would be remembered by the next call
+		if l.opts.MaxSamplesPerSeries > 0 {
+			return seriesSamplesLimited
+		}
+		if l.opts.MaxNewSeries > 0 {
+			return seriesNewSeriesLimited
+		}
+		return seriesAllowed
+	}
+
+	// Only count the series once it is admitted and tracked so that it is
+	// not counted as new again
+	if isNew {
+		l.newSeries++
+	}
+	l.curr[id] = written + samples
+	return seriesAllowed
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package remote
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesLimiterSamplesPerSeries(t *testing.T) {
+	now := time.Now()
+	limiter := newSeriesLimiter(SeriesLimitOptions{
+		Interval:            time.Minute,
+		MaxSamplesPerSeries: 3,
+		MaxTrackedSeries:    10,
+	}, func() time.Time { return now })
+
+	require.Equal(t, seriesAllowed, limiter.allow("foo", 2))
+	require.Equal(t, seriesAllowed, limiter.allow("bar", 3))
+	require.Equal(t, seriesSamplesLimited, limiter.allow("foo", 2))
+	require.Equal(t, seriesAllowed, limiter.allow("foo", 1))
+	require.Equal(t, seriesSamplesLimited, limiter.allow("foo", 1))
+
+	// Limits reset after the interval
+	now = now.Add(time.Minute)
+	require.Equal(t, seriesAllowed, limiter.allow("foo", 3))
+}
+
+func TestSeriesLimiterNewSeries(t *testing.T) {
+	now := time.Now()
+	limiter := newSeriesLimiter(SeriesLimitOptions{
+		Interval:         time.Minute,
+		MaxNewSeries:     2,
+		MaxTrackedSeries: 10,
+	}, func() time.Time { return now })
+
+	require.Equal(t, seriesAllowed, limiter.allow("foo", 1))
+	require.Equal(t, seriesAllowed, limiter.allow("bar", 1))
+	require.Equal(t, seriesNewSeriesLimited, limiter.allow("baz", 1))
+
+	// Existing series are not new
+	require.Equal(t, seriesAllowed, limiter.allow("foo", 1))
+
+	// Series seen in the previous interval are not new
+	now = now.Add(time.Minute)
+	require.Equal(t, seriesAllowed, limiter.allow("foo", 1))
+	require.Equal(t, seriesAllowed, limiter.allow("bar", 1))
+	require.Equal(t, seriesAllowed, limiter.allow("baz", 1))
+	require.Equal(t, seriesAllowed, limiter.allow("qux", 1))
+	require.Equal(t, seriesNewSeriesLimited, limiter.allow("quux", 1))
+}
+
+func TestSeriesLimiterMaxTrackedSeries(t *testing.T) {
+	now := time.Now()
+	limiter := newSeriesLimiter(SeriesLimitOptions{
+		Interval:         time.Minute,
+		MaxTrackedSeries: 5,
+	}, func() time.Time { return now })
+
+	for i := 0; i < 10; i++ {
+		require.Equal(t, seriesAllowed, limiter.allow(strconv.Itoa(i), 1))
+	}
+	require.Equal(t, 5, len(limiter.curr))
+}
+
+func TestSeriesLimiterUntrackedSeries(t *testing.T) {
+	now := time.Now()
+	limiter := newSeriesLimiter(SeriesLimitOptions{
+		Interval:            time.Minute,
+		MaxSamplesPerSeries: 2,
+		MaxNewSeries:        3,
+		MaxTrackedSeries:    1,
+	}, func() time.Time { return now })
+
+	require.Equal(t, seriesAllowed, limiter.allow("foo", 1))
+
+	// Series that cannot be tracked are rejected rather than escaping the
+	// sample limit, and are not counted as new each time they are seen
+	for i := 0; i < 5; i++ {
+		require.Equal(t, seriesSamplesLimited, limiter.allow("bar", 1))
+	}
+	require.Equal(t, 1, limiter.newSeries)
+
+	// A series rejected by the sample limit is not counted as new
+	require.Equal(t, seriesSamplesLimited, limiter.allow("baz", 3))
+	require.Equal(t, 1, limiter.newSeries)
+}
+
+func TestSeriesLimiterNewSeriesCountedOnce(t *testing.T) {
+	now := time.Now()
+	limiter := newSeriesLimiter(SeriesLimitOptions{
+		Interval:         time.Minute,
+		MaxNewSeries:     2,
+		MaxTrackedSeries: 1,
+	}, func() time.Time { return now })
+
+	require.Equal(t, seriesAllowed, limiter.allow("foo", 1))
+	require.Equal(t, seriesNewSeriesLimited, limiter.allow("bar", 1))
+	require.Equal(t, seriesNewSeriesLimited, limiter.allow("bar", 1))
+	require.Equal(t, 1, limiter.newSeries)
+	require.Equal(t, seriesAllowed, limiter.allow("foo", 1))
+}
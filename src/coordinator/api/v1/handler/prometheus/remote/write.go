@@ -22,11 +22,13 @@ package remote
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/m3db/m3db/src/coordinator/api/v1/handler"
 	"github.com/m3db/m3db/src/coordinator/api/v1/handler/prometheus"
 	"github.com/m3db/m3db/src/coordinator/generated/proto/prompb"
+	"github.com/m3db/m3db/src/coordinator/policy/filter"
 	"github.com/m3db/m3db/src/coordinator/storage"
 	"github.com/m3db/m3db/src/coordinator/util/execution"
 	"github.com/m3db/m3db/src/coordinator/util/logging"
@@ -47,28 +49,47 @@ const (
 // PromWriteHandler represents a handler for prometheus write endpoint.
 type PromWriteHandler struct {
 	store            storage.Storage
+	limiter          *SeriesLimiter
+	writeFilter      filter.StorageWrite
 	promWriteMetrics promWriteMetrics
 }
 
-// NewPromWriteHandler returns a new instance of handler.
-func NewPromWriteHandler(store storage.Storage, scope tally.Scope) http.Handler {
+// NewPromWriteHandler returns a new instance of handler, if limiter is
+// non-nil it is used to limit writes per series and if writeFilter is
+// non-nil series it rejects are dropped rather than written to storage.
+func NewPromWriteHandler(
+	store storage.Storage,
+	limiter *SeriesLimiter,
+	writeFilter filter.StorageWrite,
+	scope tally.Scope,
+) http.Handler {
 	return &PromWriteHandler{
 		store:            store,
+		limiter:          limiter,
+		writeFilter:      writeFilter,
 		promWriteMetrics: newPromWriteMetrics(scope),
 	}
 }
 
 type promWriteMetrics struct {
-	writeSuccess      tally.Counter
-	writeErrorsServer tally.Counter
-	writeErrorsClient tally.Counter
+	writeSuccess       tally.Counter
+	writeErrorsServer  tally.Counter
+	writeErrorsClient  tally.Counter
+	writeErrorsLimited tally.Counter
+	limitedSamples     tally.Counter
+	limitedNewSeries   tally.Counter
+	filteredSeries     tally.Counter
 }
 
 func newPromWriteMetrics(scope tally.Scope) promWriteMetrics {
 	return promWriteMetrics{
-		writeSuccess:      scope.Counter("write.success"),
-		writeErrorsServer: scope.Tagged(map[string]string{"code": "5XX"}).Counter("write.errors"),
-		writeErrorsClient: scope.Tagged(map[string]string{"code": "4XX"}).Counter("write.errors"),
+		writeSuccess:       scope.Counter("write.success"),
+		writeErrorsServer:  scope.Tagged(map[string]string{"code": "5XX"}).Counter("write.errors"),
+		writeErrorsClient:  scope.Tagged(map[string]string{"code": "4XX"}).Counter("write.errors"),
+		writeErrorsLimited: scope.Tagged(map[string]string{"code": "429"}).Counter("write.errors"),
+		limitedSamples:     scope.Tagged(map[string]string{"reason": "samples"}).Counter("write.limited-series"),
+		limitedNewSeries:   scope.Tagged(map[string]string{"reason": "new-series"}).Counter("write.limited-series"),
+		filteredSeries:     scope.Counter("write.filtered-series"),
 	}
 }
 
@@ -79,6 +100,7 @@ func (h *PromWriteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		handler.Error(w, rErr.Inner(), rErr.Code())
 		return
 	}
+	limited := h.limit(req)
 	if err := h.write(r.Context(), req); err != nil {
 		h.promWriteMetrics.writeErrorsServer.Inc(1)
 		logging.WithContext(r.Context()).Error("Write error", zap.Any("err", err))
@@ -86,9 +108,42 @@ func (h *PromWriteHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if limited > 0 {
+		h.promWriteMetrics.writeErrorsLimited.Inc(1)
+		err := fmt.Errorf("rejected writes for %d series exceeding series limits", limited)
+		handler.Error(w, err, http.StatusTooManyRequests)
+		return
+	}
+
 	h.promWriteMetrics.writeSuccess.Inc(1)
 }
 
+// limit removes series that exceed the series limits from the request and
+// returns the number of series removed.
+func (h *PromWriteHandler) limit(r *prompb.WriteRequest) int {
+	if h.limiter == nil {
+		return 0
+	}
+
+	allowed := r.Timeseries[:0]
+	limited := 0
+	for _, t := range r.Timeseries {
+		id := storage.PromLabelsToM3Tags(t.Labels).ID()
+		switch h.limiter.allow(id, len(t.Samples)) {
+		case seriesSamplesLimited:
+			h.promWriteMetrics.limitedSamples.Inc(1)
+			limited++
+		case seriesNewSeriesLimited:
+			h.promWriteMetrics.limitedNewSeries.Inc(1)
+			limited++
+		default:
+			allowed = append(allowed, t)
+		}
+	}
+	r.Timeseries = allowed
+	return limited
+}
+
 func (h *PromWriteHandler) parseRequest(r *http.Request) (*prompb.WriteRequest, *handler.ParseError) {
 	reqBuf, err := prometheus.ParsePromCompressedRequest(r)
 	if err != nil {
@@ -104,9 +159,14 @@ func (h *PromWriteHandler) parseRequest(r *http.Request) (*prompb.WriteRequest,
 }
 
 func (h *PromWriteHandler) write(ctx context.Context, r *prompb.WriteRequest) error {
-	requests := make([]execution.Request, len(r.Timeseries))
-	for idx, t := range r.Timeseries {
-		requests[idx] = newLocalWriteRequest(storage.PromWriteTSToM3(t), h.store)
+	requests := make([]execution.Request, 0, len(r.Timeseries))
+	for _, t := range r.Timeseries {
+		writeQuery := storage.PromWriteTSToM3(t)
+		if h.writeFilter != nil && !h.writeFilter(writeQuery, h.store) {
+			h.promWriteMetrics.filteredSeries.Inc(1)
+			continue
+		}
+		requests = append(requests, newLocalWriteRequest(writeQuery, h.store))
 	}
 	return execution.ExecuteParallel(ctx, requests)
 }
@@ -30,6 +30,7 @@ import (
 	"time"
 
 	"github.com/m3db/m3db/src/coordinator/generated/proto/prompb"
+	"github.com/m3db/m3db/src/coordinator/storage"
 	"github.com/m3db/m3db/src/coordinator/test/local"
 	"github.com/m3db/m3db/src/coordinator/util/logging"
 	"github.com/m3db/m3db/src/dbnode/x/metrics"
@@ -134,3 +135,53 @@ func TestWriteErrorMetricCount(t *testing.T) {
 	}, 5*time.Second)
 	require.True(t, foundMetric)
 }
+
+func TestPromWriteLimitsSeries(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	ctrl := gomock.NewController(t)
+	storage, session := local.NewStorageAndSession(t, ctrl)
+	// Only the series within limits is written
+	session.EXPECT().WriteTagged(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+
+	scope := tally.NewTestScope("", nil)
+	limiter := NewSeriesLimiter(SeriesLimitOptions{
+		Interval:         time.Minute,
+		MaxNewSeries:     1,
+		MaxTrackedSeries: 10,
+	})
+	promWrite := NewPromWriteHandler(storage, limiter, nil, scope)
+
+	req, _ := http.NewRequest("POST", PromWriteURL, generatePromWriteBody(t))
+	recorder := httptest.NewRecorder()
+	promWrite.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+
+	limited, ok := scope.Snapshot().Counters()["write.limited-series+reason=new-series"]
+	require.True(t, ok)
+	require.Equal(t, int64(1), limited.Value())
+}
+
+func TestPromWriteFilter(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	ctrl := gomock.NewController(t)
+	store, session := local.NewStorageAndSession(t, ctrl)
+	// Only the series not dropped by the filter is written
+	session.EXPECT().WriteTagged(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+
+	scope := tally.NewTestScope("", nil)
+	dropQux := func(write *storage.WriteQuery, _ storage.Storage) bool {
+		return write.Tags["foo"] != "qux"
+	}
+	promWrite := NewPromWriteHandler(store, nil, dropQux, scope)
+
+	req, _ := http.NewRequest("POST", PromWriteURL, generatePromWriteBody(t))
+	recorder := httptest.NewRecorder()
+	promWrite.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	filtered, ok := scope.Snapshot().Counters()["write.filtered-series+"]
+	require.True(t, ok)
+	require.Equal(t, int64(1), filtered.Value())
+}
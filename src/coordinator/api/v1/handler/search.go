@@ -23,12 +23,15 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
 
+	"github.com/m3db/m3db/src/coordinator/models"
 	"github.com/m3db/m3db/src/coordinator/storage"
 	"github.com/m3db/m3db/src/coordinator/util/logging"
+	"github.com/m3db/m3db/src/m3ninx/index"
 
 	"go.uber.org/zap"
 )
@@ -41,16 +44,29 @@ const (
 	SearchHTTPMethod = http.MethodPost
 
 	defaultLimit = 1000
+
+	limitParam  = "limit"
+	offsetParam = "offset"
 )
 
 // SearchHandler represents a handler for the search endpoint
 type SearchHandler struct {
-	store storage.Storage
+	store    storage.Storage
+	maxLimit int
+}
+
+type searchParams struct {
+	limit  int
+	offset int
 }
 
-// NewSearchHandler returns a new instance of handler
-func NewSearchHandler(storage storage.Storage) http.Handler {
-	return &SearchHandler{store: storage}
+// NewSearchHandler returns a new instance of handler. Searches return at most
+// limit series after skipping the first offset series, limits requested above
+// maxLimit are reduced to it and requests where offset plus limit exceeds it
+// are rejected, unless maxLimit is zero. Pagination depends on the order
+// storage returns series in, which is not stable across requests.
+func NewSearchHandler(storage storage.Storage, maxLimit int) http.Handler {
+	return &SearchHandler{store: storage, maxLimit: maxLimit}
 }
 
 func (h *SearchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -62,15 +78,24 @@ func (h *SearchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		Error(w, rErr.Inner(), rErr.Code())
 		return
 	}
-	opts := h.parseURLParams(r)
+	params, rErr := h.parseURLParams(r)
+	if rErr != nil {
+		logger.Error("unable to parse request", zap.Any("error", rErr))
+		Error(w, rErr.Inner(), rErr.Code())
+		return
+	}
 
-	results, err := h.search(r.Context(), query, opts)
+	// Fetch one more than the page to detect whether there are more results
+	opts := newFetchOptions(params.offset + params.limit + 1)
+	results, err := h.search(r.Context(), query, &opts)
 	if err != nil {
 		logger.Error("unable to fetch data", zap.Any("error", err))
 		Error(w, err, http.StatusBadRequest)
 		return
 	}
 
+	results, truncated := paginate(results, params)
+	w.Header().Set(TruncatedHeader, strconv.FormatBool(truncated))
 	WriteJSONResponse(w, results, logger)
 }
 
@@ -86,27 +111,89 @@ func (h *SearchHandler) parseBody(r *http.Request) (*storage.FetchQuery, *ParseE
 		return nil, NewParseError(err, http.StatusBadRequest)
 	}
 
+	if err := optimizeNameMatchers(fetchQuery.TagMatchers); err != nil {
+		return nil, NewParseError(err, http.StatusBadRequest)
+	}
+
 	return &fetchQuery, nil
 }
 
-func (h *SearchHandler) parseURLParams(r *http.Request) *storage.FetchOptions {
-	var (
-		limit int
-		err   error
-	)
+// optimizeNameMatchers rewrites regexp matchers on the metric name that can
+// only match a single literal name into exact matchers, so they are resolved
+// with a term lookup rather than a regexp. Regexps with a literal prefix are
+// left as is, storage uses the prefix to bound the range of names scanned
+// and only evaluates the regexp against names within that range.
+func optimizeNameMatchers(matchers models.Matchers) error {
+	for i, matcher := range matchers {
+		if matcher.Type != models.MatchRegexp || matcher.Name != models.MetricName {
+			continue
+		}
+
+		prefix, complete, err := index.RegexpLiteralPrefix([]byte(matcher.Value))
+		if err != nil {
+			return err
+		}
+		if !complete {
+			continue
+		}
 
-	limitRaw := r.URL.Query().Get("limit")
-	if limitRaw != "" {
-		limit, err = strconv.Atoi(limitRaw)
+		optimized, err := models.NewMatcher(models.MatchEqual, matcher.Name, string(prefix))
 		if err != nil {
-			limit = defaultLimit
+			return err
 		}
-	} else {
-		limit = defaultLimit
+		matchers[i] = optimized
+	}
+	return nil
+}
+
+func (h *SearchHandler) parseURLParams(r *http.Request) (searchParams, *ParseError) {
+	params := searchParams{limit: defaultLimit}
+
+	query := r.URL.Query()
+	if limitRaw := query.Get(limitParam); limitRaw != "" {
+		limit, err := strconv.ParseInt(limitRaw, 10, 32)
+		if err != nil || limit <= 0 {
+			return params, NewParseError(fmt.Errorf("invalid limit: %s", limitRaw), http.StatusBadRequest)
+		}
+		params.limit = int(limit)
+	}
+	if h.maxLimit > 0 && params.limit > h.maxLimit {
+		params.limit = h.maxLimit
+	}
+
+	if offsetRaw := query.Get(offsetParam); offsetRaw != "" {
+		offset, err := strconv.ParseInt(offsetRaw, 10, 32)
+		if err != nil || offset < 0 {
+			return params, NewParseError(fmt.Errorf("invalid offset: %s", offsetRaw), http.StatusBadRequest)
+		}
+		params.offset = int(offset)
+	}
+
+	// The series skipped by the offset are fetched too, bound the total
+	if h.maxLimit > 0 && params.offset+params.limit > h.maxLimit {
+		return params, NewParseError(fmt.Errorf("offset %d plus limit %d exceeds max limit %d",
+			params.offset, params.limit, h.maxLimit), http.StatusBadRequest)
+	}
+
+	return params, nil
+}
+
+// paginate returns the page of the results selected by the params, along with
+// whether there were results after the page that were left out. Pages follow
+// the order the storage returns results in, which is not stable across
+// requests, so paging through results may skip or repeat series.
+func paginate(results *storage.SearchResults, params searchParams) (*storage.SearchResults, bool) {
+	metrics := results.Metrics
+	if params.offset >= len(metrics) {
+		return &storage.SearchResults{Metrics: models.Metrics{}}, false
 	}
 
-	fetchOptions := newFetchOptions(limit)
-	return &fetchOptions
+	metrics = metrics[params.offset:]
+	truncated := len(metrics) > params.limit
+	if truncated {
+		metrics = metrics[:params.limit]
+	}
+	return &storage.SearchResults{Metrics: metrics}, truncated
 }
 
 func (h *SearchHandler) search(ctx context.Context, query *storage.FetchQuery, opts *storage.FetchOptions) (*storage.SearchResults, error) {
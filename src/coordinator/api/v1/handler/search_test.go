@@ -127,4 +127,107 @@ func TestSearchEndpoint(t *testing.T) {
 	require.NoError(t, err)
 	defer resp.Body.Close()
 	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "false", resp.Header.Get(TruncatedHeader))
+}
+
+func TestSearchParseURLParams(t *testing.T) {
+	tests := []struct {
+		query    string
+		maxLimit int
+		expected searchParams
+		invalid  bool
+	}{
+		{query: "", expected: searchParams{limit: defaultLimit}},
+		{query: "limit=10&offset=20", expected: searchParams{limit: 10, offset: 20}},
+		{query: "limit=10", maxLimit: 5, expected: searchParams{limit: 5}},
+		{query: "", maxLimit: 5, expected: searchParams{limit: 5}},
+		{query: "limit=0", invalid: true},
+		{query: "limit=foo", invalid: true},
+		{query: "offset=-1", invalid: true},
+		{query: "offset=99999999999", invalid: true},
+		{query: "limit=2&offset=3", maxLimit: 5, expected: searchParams{limit: 2, offset: 3}},
+		{query: "limit=3&offset=3", maxLimit: 5, invalid: true},
+		{query: "offset=1", maxLimit: 5, invalid: true},
+	}
+
+	for _, test := range tests {
+		h := &SearchHandler{maxLimit: test.maxLimit}
+		req := httptest.NewRequest(SearchHTTPMethod, SearchURL+"?"+test.query, nil)
+		params, err := h.parseURLParams(req)
+		if test.invalid {
+			require.NotNil(t, err, test.query)
+			assert.Equal(t, http.StatusBadRequest, err.Code())
+			continue
+		}
+		require.Nil(t, err, test.query)
+		assert.Equal(t, test.expected, params, test.query)
+	}
+}
+
+func TestSearchPaginate(t *testing.T) {
+	results := &storage.SearchResults{}
+	for i := 0; i < 5; i++ {
+		results.Metrics = append(results.Metrics, &models.Metric{ID: fmt.Sprintf("%d", i)})
+	}
+
+	ids := func(results *storage.SearchResults) []string {
+		ids := []string{}
+		for _, m := range results.Metrics {
+			ids = append(ids, m.ID)
+		}
+		return ids
+	}
+
+	page, truncated := paginate(results, searchParams{limit: 2, offset: 1})
+	assert.Equal(t, []string{"1", "2"}, ids(page))
+	assert.True(t, truncated)
+
+	page, truncated = paginate(results, searchParams{limit: 2, offset: 3})
+	assert.Equal(t, []string{"3", "4"}, ids(page))
+	assert.False(t, truncated)
+
+	page, truncated = paginate(results, searchParams{limit: 10})
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, ids(page))
+	assert.False(t, truncated)
+
+	page, truncated = paginate(results, searchParams{limit: 2, offset: 5})
+	assert.Equal(t, []string{}, ids(page))
+	assert.False(t, truncated)
+}
+
+func TestOptimizeNameMatchers(t *testing.T) {
+	tests := []struct {
+		value         string
+		expectedType  models.MatchType
+		expectedValue string
+	}{
+		// Literal regexps are rewritten to exact matches
+		{value: "http_requests", expectedType: models.MatchEqual, expectedValue: "http_requests"},
+		{value: "^http_requests$", expectedType: models.MatchEqual, expectedValue: "http_requests"},
+		// Regexps with only a literal prefix are left for storage
+		{value: "http_.*", expectedType: models.MatchRegexp, expectedValue: "http_.*"},
+		{value: "http_(requests|errors)", expectedType: models.MatchRegexp, expectedValue: "http_(requests|errors)"},
+		// Regexps without a literal prefix are left as is
+		{value: ".*_total", expectedType: models.MatchRegexp, expectedValue: ".*_total"},
+		{value: "(?i)http_requests", expectedType: models.MatchRegexp, expectedValue: "(?i)http_requests"},
+	}
+
+	for _, test := range tests {
+		matchers := models.Matchers{
+			{Type: models.MatchRegexp, Name: models.MetricName, Value: test.value},
+			{Type: models.MatchRegexp, Name: "foo", Value: "bar"},
+		}
+		require.NoError(t, optimizeNameMatchers(matchers))
+		assert.Equal(t, test.expectedType, matchers[0].Type, test.value)
+		assert.Equal(t, test.expectedValue, matchers[0].Value, test.value)
+
+		// Only the metric name is optimized
+		assert.Equal(t, models.MatchRegexp, matchers[1].Type)
+	}
+
+	err := optimizeNameMatchers(models.Matchers{
+		{Type: models.MatchRegexp, Name: models.MetricName, Value: "(unclosed"},
+	})
+	assert.Error(t, err)
 }
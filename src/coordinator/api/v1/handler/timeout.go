@@ -0,0 +1,95 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WithTimeout wraps around the given handler, cancelling the request context
+// once the timeout elapses so that storage calls made with it are abandoned.
+// If the handler then fails the request, or returns without responding, a
+// 503 is returned in place of its error response. The response is not
+// buffered, so a handler that has started a successful response before the
+// timeout finishes it as it sees fit.
+func WithTimeout(next http.Handler, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		tw := &timeoutResponseWriter{ResponseWriter: w, ctx: ctx, timeout: timeout}
+		next.ServeHTTP(tw, r.WithContext(ctx))
+		if !tw.wroteHeader && ctx.Err() == context.DeadlineExceeded {
+			tw.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+}
+
+// timeoutResponseWriter replaces error responses written after the request
+// context has timed out with a timeout error
+type timeoutResponseWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	timeout     time.Duration
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (w *timeoutResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if status >= http.StatusBadRequest && w.ctx.Err() == context.DeadlineExceeded {
+		w.timedOut = true
+		Error(w.ResponseWriter, fmt.Errorf("request timed out after %v", w.timeout),
+			http.StatusServiceUnavailable)
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *timeoutResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.timedOut {
+		// Drop the handler's error response, the timeout error was sent
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *timeoutResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *timeoutResponseWriter) CloseNotify() <-chan bool {
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return make(chan bool)
+}
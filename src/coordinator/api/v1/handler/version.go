@@ -0,0 +1,92 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handler
+
+import (
+	"net/http"
+
+	"github.com/m3db/m3db/src/coordinator/util/logging"
+	"github.com/m3db/m3db/src/dbnode/persist/fs/msgpack"
+	"github.com/m3db/m3db/src/dbnode/persist/schema"
+	m3ninxfs "github.com/m3db/m3db/src/m3ninx/index/segment/fs"
+
+	"github.com/m3db/m3x/instrument"
+)
+
+const (
+	// VersionURL is the url for the version handler
+	VersionURL = "/version"
+
+	// VersionHTTPMethod is the HTTP method used with this resource.
+	VersionHTTPMethod = http.MethodGet
+)
+
+// VersionHandler represents a handler for the version endpoint
+type VersionHandler struct{}
+
+// VersionResponse is the response returned by the version endpoint
+type VersionResponse struct {
+	Version        string         `json:"version"`
+	Revision       string         `json:"revision"`
+	Branch         string         `json:"branch"`
+	BuildDate      string         `json:"buildDate"`
+	FormatVersions FormatVersions `json:"formatVersions"`
+}
+
+// FormatVersions describes the on-disk format versions supported by the build
+type FormatVersions struct {
+	CommitLogInfo     int `json:"commitLogInfo"`
+	CommitLogEntry    int `json:"commitLogEntry"`
+	CommitLogMetadata int `json:"commitLogMetadata"`
+	FileSet           int `json:"fileSet"`
+	IndexSegmentMajor int `json:"indexSegmentMajor"`
+	IndexSegmentMinor int `json:"indexSegmentMinor"`
+}
+
+// NewVersionHandler returns a new instance of handler
+func NewVersionHandler() http.Handler {
+	return &VersionHandler{}
+}
+
+func (h *VersionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := logging.WithContext(r.Context())
+	WriteJSONResponse(w, CurrentVersion(), logger)
+}
+
+// CurrentVersion returns the build and format version information of the
+// running binary, build information is read from the m3x instrument package
+// which is populated at build time using ldflags
+func CurrentVersion() VersionResponse {
+	return VersionResponse{
+		Version:   instrument.Version,
+		Revision:  instrument.Revision,
+		Branch:    instrument.Branch,
+		BuildDate: instrument.BuildDate,
+		FormatVersions: FormatVersions{
+			CommitLogInfo:     msgpack.CommitLogInfoVersion,
+			CommitLogEntry:    msgpack.CommitLogEntryVersion,
+			CommitLogMetadata: msgpack.CommitLogMetadataVersion,
+			FileSet:           schema.MajorVersion,
+			IndexSegmentMajor: m3ninxfs.MajorVersion,
+			IndexSegmentMinor: m3ninxfs.MinorVersion,
+		},
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/m3db/m3db/src/coordinator/util/logging"
+
+	"github.com/m3db/m3x/instrument"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionHandler(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	prevVersion, prevRevision, prevBranch, prevBuildDate :=
+		instrument.Version, instrument.Revision, instrument.Branch, instrument.BuildDate
+	instrument.Version, instrument.Revision, instrument.Branch, instrument.BuildDate =
+		"v0.1.0", "abcdef", "master", "2018-06-01-12:00:00"
+	defer func() {
+		instrument.Version, instrument.Revision, instrument.Branch, instrument.BuildDate =
+			prevVersion, prevRevision, prevBranch, prevBuildDate
+	}()
+
+	req := httptest.NewRequest(VersionHTTPMethod, VersionURL, nil)
+	w := httptest.NewRecorder()
+	NewVersionHandler().ServeHTTP(w, req)
+
+	resp := w.Result()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var version VersionResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&version))
+	assert.Equal(t, "v0.1.0", version.Version)
+	assert.Equal(t, "abcdef", version.Revision)
+	assert.Equal(t, "master", version.Branch)
+	assert.Equal(t, "2018-06-01-12:00:00", version.BuildDate)
+	assert.NotZero(t, version.FormatVersions.CommitLogEntry)
+	assert.NotZero(t, version.FormatVersions.FileSet)
+}
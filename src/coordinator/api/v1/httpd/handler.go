@@ -21,11 +21,17 @@
 package httpd
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"net/http/pprof"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 
 	clusterclient "github.com/m3db/m3cluster/client"
 	"github.com/m3db/m3db/src/cmd/services/m3coordinator/config"
@@ -39,6 +45,7 @@ import (
 	"github.com/m3db/m3db/src/coordinator/api/v1/handler/prometheus/remote"
 	"github.com/m3db/m3db/src/coordinator/executor"
 	"github.com/m3db/m3db/src/coordinator/storage"
+	"github.com/m3db/m3db/src/coordinator/storage/health"
 	"github.com/m3db/m3db/src/coordinator/util/logging"
 
 	"github.com/gorilla/mux"
@@ -47,8 +54,8 @@ import (
 )
 
 const (
-	pprofURL  = "/debug/pprof/profile"
-	routesURL = "/routes"
+	pprofURLPrefix = "/debug/pprof"
+	routesURL      = "/routes"
 )
 
 var (
@@ -57,7 +64,12 @@ var (
 
 // Handler represents an HTTP handler.
 type Handler struct {
+	// Router is the router routes are registered on, when a base path is
+	// configured it only matches requests under the base path and the
+	// handler itself must be served to answer requests outside it
 	Router        *mux.Router
+	root          *mux.Router
+	basePath      string
 	CLFLogger     *log.Logger
 	storage       storage.Storage
 	engine        *executor.Engine
@@ -65,6 +77,12 @@ type Handler struct {
 	config        config.Configuration
 	embeddedDbCfg *dbconfig.DBConfiguration
 	scope         tally.Scope
+	healthTracker *health.Tracker
+	middleware    []func(http.Handler) http.Handler
+
+	serverLock sync.Mutex
+	server     *http.Server
+	shutdown   bool
 }
 
 // NewHandler returns a new instance of handler with routes.
@@ -76,7 +94,18 @@ func NewHandler(
 	embeddedDbCfg *dbconfig.DBConfiguration,
 	scope tally.Scope,
 ) (*Handler, error) {
-	r := mux.NewRouter()
+	var (
+		root     = mux.NewRouter()
+		r        = root
+		basePath = strings.TrimSuffix(cfg.BasePath, "/")
+	)
+	if basePath != "" {
+		if !strings.HasPrefix(basePath, "/") {
+			return nil, fmt.Errorf("base path must begin with /: %s", cfg.BasePath)
+		}
+		r = root.PathPrefix(basePath).Subrouter()
+	}
+
 	logger, err := zap.NewProduction()
 	if err != nil {
 		return nil, err
@@ -86,6 +115,8 @@ func NewHandler(
 	h := &Handler{
 		CLFLogger:     log.New(os.Stderr, "[httpd] ", 0),
 		Router:        r,
+		root:          root,
+		basePath:      basePath,
 		storage:       storage,
 		engine:        engine,
 		clusterClient: clusterClient,
@@ -96,17 +127,33 @@ func NewHandler(
 	return h, nil
 }
 
+// SetStorageHealthTracker sets the tracker for the health of the stores
+// backing the handler's storage, which is reported by the health endpoint.
+// It must be set before the routes are registered.
+func (h *Handler) SetStorageHealthTracker(tracker *health.Tracker) {
+	h.healthTracker = tracker
+}
+
 // RegisterRoutes registers all http routes.
 func (h *Handler) RegisterRoutes() error {
-	logged := logging.WithResponseTimeLogging
+	logged := h.builtInMiddleware()
+
+	if cors := h.config.CORS; len(cors.AllowedOrigins) > 0 {
+		// Preflight requests are answered for every path, registered first
+		// so they are not matched by routes without a method matcher
+		preflight := handler.NewCORSPreflightHandler(corsOptions(cors))
+		h.Router.PathPrefix("/").Methods(http.MethodOptions).Handler(logging.WithResponseTimeLogging(preflight))
+	}
 
 	h.Router.HandleFunc(openapi.URL, logged(&openapi.DocHandler{}).ServeHTTP).Methods(openapi.HTTPMethod)
-	h.Router.PathPrefix(openapi.StaticURLPrefix).Handler(logged(openapi.StaticHandler()))
+	h.Router.PathPrefix(openapi.StaticURLPrefix).Handler(logged(openapi.NewStaticHandler(h.basePath)))
 
 	h.Router.HandleFunc(remote.PromReadURL, logged(remote.NewPromReadHandler(h.engine, h.scope.Tagged(remoteSource))).ServeHTTP).Methods(remote.PromReadHTTPMethod)
-	h.Router.HandleFunc(remote.PromWriteURL, logged(remote.NewPromWriteHandler(h.storage, h.scope.Tagged(remoteSource))).ServeHTTP).Methods(remote.PromWriteHTTPMethod)
-	h.Router.HandleFunc(native.PromReadURL, logged(native.NewPromReadHandler(h.engine)).ServeHTTP).Methods(native.PromReadHTTPMethod)
-	h.Router.HandleFunc(handler.SearchURL, logged(handler.NewSearchHandler(h.storage)).ServeHTTP).Methods(handler.SearchHTTPMethod)
+	h.Router.HandleFunc(remote.PromWriteURL, logged(remote.NewPromWriteHandler(h.storage, h.seriesLimiter(), nil, h.scope.Tagged(remoteSource))).ServeHTTP).Methods(remote.PromWriteHTTPMethod)
+	h.Router.HandleFunc(native.PromReadURL, logged(native.NewPromReadHandler(h.engine, h.config.MaxReadResponseSamples, h.config.ReadPartitionSize)).ServeHTTP).Methods(native.PromReadHTTPMethod)
+	h.Router.HandleFunc(handler.SearchURL, logged(handler.NewSearchHandler(h.storage, h.config.MaxSearchLimit)).ServeHTTP).Methods(handler.SearchHTTPMethod)
+	h.Router.HandleFunc(handler.VersionURL, logged(handler.NewVersionHandler()).ServeHTTP).Methods(handler.VersionHTTPMethod)
+	h.Router.HandleFunc(handler.HealthURL, logged(handler.NewHealthHandler(h.storage, h.healthTracker)).ServeHTTP).Methods(handler.HealthHTTPMethod)
 
 	if h.clusterClient != nil {
 		placement.RegisterRoutes(h.Router, h.clusterClient, h.config)
@@ -114,36 +161,231 @@ func (h *Handler) RegisterRoutes() error {
 		database.RegisterRoutes(h.Router, h.clusterClient, h.config, h.embeddedDbCfg)
 	}
 
-	h.registerProfileEndpoints()
+	if !h.config.DisableProfiling {
+		h.registerProfileEndpoints()
+	}
 	h.registerRoutesEndpoint()
 
-	return nil
+	if err := h.instrumentRoutes(); err != nil {
+		return err
+	}
+	return h.applyMiddleware()
+}
+
+// builtInMiddleware returns the middleware wrapping each route handler with
+// response time logging and, when configured, CORS headers, a request timeout
+// and compression
+func (h *Handler) builtInMiddleware() func(http.Handler) http.Handler {
+	var wrappers []func(http.Handler) http.Handler
+	if cors := h.config.CORS; len(cors.AllowedOrigins) > 0 {
+		opts := corsOptions(cors)
+		wrappers = append(wrappers, func(next http.Handler) http.Handler {
+			return handler.WithCORS(next, opts)
+		})
+	}
+	if timeout := h.config.RequestTimeout; timeout > 0 {
+		wrappers = append(wrappers, func(next http.Handler) http.Handler {
+			return handler.WithTimeout(next, timeout)
+		})
+	}
+	if cfg := h.config.ResponseCompression; !cfg.Disabled {
+		minSize := cfg.MinSize
+		if minSize <= 0 {
+			minSize = handler.DefaultCompressionMinSize
+		}
+		wrappers = append(wrappers, func(next http.Handler) http.Handler {
+			return handler.WithGzipCompression(next, minSize)
+		})
+	}
+
+	return func(next http.Handler) http.Handler {
+		for i := len(wrappers) - 1; i >= 0; i-- {
+			next = wrappers[i](next)
+		}
+		return logging.WithResponseTimeLogging(next)
+	}
+}
+
+func corsOptions(cfg config.CORSConfiguration) handler.CORSOptions {
+	return handler.CORSOptions{
+		AllowedOrigins: cfg.AllowedOrigins,
+		AllowedMethods: cfg.AllowedMethods,
+		AllowedHeaders: cfg.AllowedHeaders,
+	}
+}
+
+// ListenAndServe listens on the given address and serves the registered
+// routes until Shutdown is called, after which it returns http.ErrServerClosed.
+func (h *Handler) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return h.Serve(listener)
+}
+
+// Serve serves the registered routes on the given listener until Shutdown is
+// called, after which it returns http.ErrServerClosed.
+func (h *Handler) Serve(listener net.Listener) error {
+	h.serverLock.Lock()
+	if h.shutdown {
+		h.serverLock.Unlock()
+		listener.Close()
+		return http.ErrServerClosed
+	}
+	server := NewServer(h, h.config)
+	h.server = server
+	h.serverLock.Unlock()
+
+	return server.Serve(listener)
+}
+
+// NewServer returns an HTTP server for the handler tuned with the server
+// configuration. The server only serves plaintext HTTP/1.1, serving HTTP/2
+// requires a TLS terminating proxy in front of it.
+func NewServer(h *Handler, cfg config.Configuration) *http.Server {
+	serverCfg := cfg.HTTPServer
+	server := &http.Server{
+		Handler:           h,
+		ErrorLog:          h.CLFLogger,
+		ReadTimeout:       serverCfg.ReadTimeout,
+		ReadHeaderTimeout: serverCfg.ReadHeaderTimeout,
+		WriteTimeout:      serverCfg.WriteTimeout,
+		IdleTimeout:       serverCfg.IdleTimeout,
+		MaxHeaderBytes:    serverCfg.MaxHeaderBytes,
+	}
+	server.SetKeepAlivesEnabled(!serverCfg.DisableKeepAlives)
+	return server
+}
+
+// ServeHTTP serves a request with the registered routes, requests outside the
+// configured base path are not found.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.root.ServeHTTP(w, r)
+}
+
+// Shutdown stops accepting new requests and waits for in-flight requests to
+// complete, returning the context error if it expires first.
+func (h *Handler) Shutdown(ctx context.Context) error {
+	h.serverLock.Lock()
+	h.shutdown = true
+	server := h.server
+	h.serverLock.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
+
+// Use appends middleware that RegisterRoutes applies to every registered
+// route. Middleware is applied in the order it was added, the first added
+// being the outermost, and wraps the built-in response time logging and
+// compression. It therefore runs before them, its time is not included in
+// the logged response time and the request context does not yet carry the
+// generated request ID.
+func (h *Handler) Use(middleware func(http.Handler) http.Handler) {
+	h.middleware = append(h.middleware, middleware)
+}
+
+// instrumentRoutes wraps every registered route with request metrics tagged
+// by the route's path template.
+func (h *Handler) instrumentRoutes() error {
+	scope := h.scope.SubScope("http")
+	return h.Router.Walk(
+		func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+			next := route.GetHandler()
+			if next == nil {
+				return nil
+			}
+			path, err := route.GetPathTemplate()
+			if err != nil {
+				return err
+			}
+			route.Handler(handler.WithRequestMetrics(next, scope, path))
+			return nil
+		})
+}
+
+func (h *Handler) applyMiddleware() error {
+	if len(h.middleware) == 0 {
+		return nil
+	}
+	return h.Router.Walk(
+		func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+			next := route.GetHandler()
+			if next == nil {
+				return nil
+			}
+			for i := len(h.middleware) - 1; i >= 0; i-- {
+				next = h.middleware[i](next)
+			}
+			route.Handler(next)
+			return nil
+		})
+}
+
+func (h *Handler) seriesLimiter() *remote.SeriesLimiter {
+	cfg := h.config.WriteLimits
+	if cfg == nil {
+		return nil
+	}
+	return remote.NewSeriesLimiter(remote.SeriesLimitOptions{
+		Interval:            cfg.Interval,
+		MaxSamplesPerSeries: cfg.MaxSamplesPerSeries,
+		MaxNewSeries:        cfg.MaxNewSeries,
+		MaxTrackedSeries:    cfg.MaxTrackedSeries,
+	})
 }
 
 // Endpoints useful for profiling the service
 func (h *Handler) registerProfileEndpoints() {
-	h.Router.HandleFunc(pprofURL, pprof.Profile)
+	h.Router.HandleFunc(pprofURLPrefix+"/", pprof.Index)
+	h.Router.HandleFunc(pprofURLPrefix+"/cmdline", pprof.Cmdline)
+	h.Router.HandleFunc(pprofURLPrefix+"/profile", pprof.Profile)
+	h.Router.HandleFunc(pprofURLPrefix+"/symbol", pprof.Symbol)
+	h.Router.HandleFunc(pprofURLPrefix+"/trace", pprof.Trace)
+	for _, profile := range []string{"heap", "goroutine", "block", "mutex", "threadcreate"} {
+		h.Router.Handle(pprofURLPrefix+"/"+profile, pprof.Handler(profile))
+	}
+}
+
+// routeInfo describes a single registered route
+type routeInfo struct {
+	Path    string   `json:"path"`
+	Methods []string `json:"methods"`
 }
 
 // Endpoints useful for viewing routes directory
 func (h *Handler) registerRoutesEndpoint() {
 	h.Router.HandleFunc(routesURL, func(w http.ResponseWriter, r *http.Request) {
-		var routes []string
+		var routes []routeInfo
 		err := h.Router.Walk(
 			func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
-				str, err := route.GetPathTemplate()
+				path, err := route.GetPathTemplate()
 				if err != nil {
 					return err
 				}
-				routes = append(routes, str)
+				// Routes registered without a method matcher return an
+				// error here, report them with an empty list of methods
+				routeMethods, _ := route.GetMethods()
+				methods := append([]string{}, routeMethods...)
+				sort.Strings(methods)
+				routes = append(routes, routeInfo{Path: path, Methods: methods})
 				return nil
 			})
 		if err != nil {
 			handler.Error(w, err, http.StatusInternalServerError)
 			return
 		}
+		sort.Slice(routes, func(i, j int) bool {
+			if routes[i].Path != routes[j].Path {
+				return routes[i].Path < routes[j].Path
+			}
+			return strings.Join(routes[i].Methods, ",") < strings.Join(routes[j].Methods, ",")
+		})
 		json.NewEncoder(w).Encode(struct {
-			Routes []string `json:"routes"`
+			Routes []routeInfo `json:"routes"`
 		}{
 			Routes: routes,
 		})
@@ -21,12 +21,18 @@
 package httpd
 
 import (
+	"context"
 	"encoding/json"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/m3db/m3db/src/cmd/services/m3coordinator/config"
+	"github.com/m3db/m3db/src/coordinator/api/v1/handler"
 	"github.com/m3db/m3db/src/coordinator/api/v1/handler/prometheus/native"
 	"github.com/m3db/m3db/src/coordinator/api/v1/handler/prometheus/remote"
 	"github.com/m3db/m3db/src/coordinator/executor"
@@ -117,7 +123,7 @@ func TestRoutesGet(t *testing.T) {
 	require.Equal(t, res.Code, http.StatusOK)
 
 	response := &struct {
-		Routes []string `json:"routes"`
+		Routes []routeInfo `json:"routes"`
 	}{}
 
 	err = json.NewDecoder(res.Body).Decode(response)
@@ -125,10 +131,256 @@ func TestRoutesGet(t *testing.T) {
 
 	foundRoutesURL := false
 	for _, route := range response.Routes {
-		if route == routesURL {
+		if route.Path == routesURL {
 			foundRoutesURL = true
 			break
 		}
 	}
 	assert.True(t, foundRoutesURL, "routes URL not served by routes endpoint")
+
+	foundVersionURL := false
+	for _, route := range response.Routes {
+		if route.Path == handler.VersionURL {
+			foundVersionURL = true
+			assert.Equal(t, []string{handler.VersionHTTPMethod}, route.Methods)
+			break
+		}
+	}
+	assert.True(t, foundVersionURL, "version URL not served by routes endpoint")
+
+	assert.True(t, sort.SliceIsSorted(response.Routes, func(i, j int) bool {
+		return response.Routes[i].Path < response.Routes[j].Path
+	}), "routes are not sorted by path")
+}
+
+func TestHandlerBasePath(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	ctrl := gomock.NewController(t)
+	storage, _ := local.NewStorageAndSession(t, ctrl)
+
+	cfg := config.Configuration{BasePath: "/prefix/"}
+	h, err := NewHandler(storage, executor.NewEngine(storage), nil, cfg, nil, tally.NewTestScope("", nil))
+	require.NoError(t, err, "unable to setup handler")
+	require.NoError(t, h.RegisterRoutes())
+
+	req, _ := http.NewRequest("GET", "/prefix"+routesURL, nil)
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	require.Equal(t, http.StatusOK, res.Code)
+
+	response := &struct {
+		Routes []routeInfo `json:"routes"`
+	}{}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(response))
+	require.NotEmpty(t, response.Routes)
+	for _, route := range response.Routes {
+		assert.True(t, strings.HasPrefix(route.Path, "/prefix/"), "route %s not under base path", route.Path)
+	}
+
+	req, _ = http.NewRequest("GET", handler.VersionURL, nil)
+	res = httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	require.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestNewHandlerRelativeBasePath(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	storage, _ := local.NewStorageAndSession(t, ctrl)
+
+	cfg := config.Configuration{BasePath: "prefix"}
+	_, err := NewHandler(storage, executor.NewEngine(storage), nil, cfg, nil, tally.NewTestScope("", nil))
+	require.Error(t, err)
+}
+
+func TestHandlerUseMiddleware(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	ctrl := gomock.NewController(t)
+	storage, _ := local.NewStorageAndSession(t, ctrl)
+
+	h, err := NewHandler(storage, executor.NewEngine(storage), nil, config.Configuration{}, nil, tally.NewTestScope("", nil))
+	require.NoError(t, err, "unable to setup handler")
+
+	var calls []string
+	for _, name := range []string{"first", "second"} {
+		name := name
+		h.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+	require.NoError(t, h.RegisterRoutes())
+
+	for _, url := range []string{handler.VersionURL, routesURL} {
+		calls = nil
+		req, _ := http.NewRequest("GET", url, nil)
+		res := httptest.NewRecorder()
+		h.Router.ServeHTTP(res, req)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, []string{"first", "second"}, calls)
+	}
+}
+
+func TestHandlerRequestMetrics(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	ctrl := gomock.NewController(t)
+	storage, _ := local.NewStorageAndSession(t, ctrl)
+
+	scope := tally.NewTestScope("", nil)
+	h, err := NewHandler(storage, executor.NewEngine(storage), nil, config.Configuration{}, nil, scope)
+	require.NoError(t, err, "unable to setup handler")
+	require.NoError(t, h.RegisterRoutes())
+
+	req, _ := http.NewRequest("GET", handler.VersionURL, nil)
+	res := httptest.NewRecorder()
+	h.Router.ServeHTTP(res, req)
+	require.Equal(t, http.StatusOK, res.Code)
+
+	counters := scope.Snapshot().Counters()
+	requests, ok := counters[tally.KeyForPrefixedStringMap("http.request",
+		map[string]string{"route": handler.VersionURL, "status": "200"})]
+	require.True(t, ok)
+	assert.Equal(t, int64(1), requests.Value())
+}
+
+func TestNewServer(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	ctrl := gomock.NewController(t)
+	storage, _ := local.NewStorageAndSession(t, ctrl)
+
+	cfg := config.Configuration{
+		HTTPServer: config.HTTPServerConfiguration{
+			ReadTimeout:    time.Second,
+			WriteTimeout:   2 * time.Second,
+			IdleTimeout:    3 * time.Second,
+			MaxHeaderBytes: 4096,
+		},
+	}
+	h, err := NewHandler(storage, executor.NewEngine(storage), nil, cfg, nil, tally.NewTestScope("", nil))
+	require.NoError(t, err, "unable to setup handler")
+
+	server := NewServer(h, cfg)
+	assert.Equal(t, time.Second, server.ReadTimeout)
+	assert.Equal(t, 2*time.Second, server.WriteTimeout)
+	assert.Equal(t, 3*time.Second, server.IdleTimeout)
+	assert.Equal(t, 4096, server.MaxHeaderBytes)
+}
+
+func TestProfileEndpoints(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	for _, disabled := range []bool{false, true} {
+		ctrl := gomock.NewController(t)
+		storage, _ := local.NewStorageAndSession(t, ctrl)
+
+		cfg := config.Configuration{DisableProfiling: disabled}
+		h, err := NewHandler(storage, executor.NewEngine(storage), nil, cfg, nil, tally.NewTestScope("", nil))
+		require.NoError(t, err, "unable to setup handler")
+		require.NoError(t, h.RegisterRoutes())
+
+		expectedCode := http.StatusOK
+		if disabled {
+			expectedCode = http.StatusNotFound
+		}
+		for _, url := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/goroutine", "/debug/pprof/cmdline"} {
+			req, _ := http.NewRequest("GET", url, nil)
+			res := httptest.NewRecorder()
+			h.Router.ServeHTTP(res, req)
+			assert.Equal(t, expectedCode, res.Code, url)
+		}
+		ctrl.Finish()
+	}
+}
+
+func TestHandlerShutdownDrainsInFlightRequests(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	ctrl := gomock.NewController(t)
+	storage, _ := local.NewStorageAndSession(t, ctrl)
+
+	h, err := NewHandler(storage, executor.NewEngine(storage), nil, config.Configuration{}, nil, tally.NewTestScope("", nil))
+	require.NoError(t, err, "unable to setup handler")
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h.Router.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- h.Serve(listener)
+	}()
+
+	respCode := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			respCode <- 0
+			return
+		}
+		resp.Body.Close()
+		respCode <- resp.StatusCode
+	}()
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- h.Shutdown(context.Background())
+	}()
+
+	select {
+	case <-shutdownErr:
+		require.Fail(t, "shutdown returned before in-flight request completed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-shutdownErr)
+	assert.Equal(t, http.StatusOK, <-respCode)
+	assert.Equal(t, http.ErrServerClosed, <-serveErr)
+
+	// Serving after shutdown is rejected
+	listener, err = net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	assert.Equal(t, http.ErrServerClosed, h.Serve(listener))
+}
+
+func TestCORSPreflight(t *testing.T) {
+	logging.InitWithCores(nil)
+
+	ctrl := gomock.NewController(t)
+	storage, _ := local.NewStorageAndSession(t, ctrl)
+
+	cfg := config.Configuration{
+		CORS: config.CORSConfiguration{AllowedOrigins: []string{"http://grafana.example.com"}},
+	}
+	h, err := NewHandler(storage, executor.NewEngine(storage), nil, cfg, nil, tally.NewTestScope("", nil))
+	require.NoError(t, err, "unable to setup handler")
+	require.NoError(t, h.RegisterRoutes())
+
+	req, _ := http.NewRequest(http.MethodOptions, native.PromReadURL, nil)
+	req.Header.Set("Origin", "http://grafana.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	res := httptest.NewRecorder()
+	h.Router.ServeHTTP(res, req)
+	require.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "http://grafana.example.com", res.Header().Get("Access-Control-Allow-Origin"))
+
+	req, _ = http.NewRequest(http.MethodGet, handler.VersionURL, nil)
+	req.Header.Set("Origin", "http://grafana.example.com")
+	res = httptest.NewRecorder()
+	h.Router.ServeHTTP(res, req)
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "http://grafana.example.com", res.Header().Get("Access-Control-Allow-Origin"))
 }
@@ -20,11 +20,18 @@
 
 package filter
 
-import "github.com/m3db/m3db/src/coordinator/storage"
+import (
+	"time"
+
+	"github.com/m3db/m3db/src/coordinator/storage"
+)
 
 // Storage determines whether storage can fulfil the read query
 type Storage func(query storage.Query, store storage.Storage) bool
 
+// StorageWrite determines whether a write should be sent to storage
+type StorageWrite func(write *storage.WriteQuery, store storage.Storage) bool
+
 // LocalOnly filters out all remote storages
 func LocalOnly(query storage.Query, store storage.Storage) bool {
 	return store.Type() == storage.TypeLocalDC
@@ -39,3 +46,102 @@ func AllowAll(_ storage.Query, _ storage.Storage) bool {
 func AllowNone(_ storage.Query, _ storage.Storage) bool {
 	return false
 }
+
+// LocalOnlyWrite filters out writes to all remote storages
+func LocalOnlyWrite(_ *storage.WriteQuery, store storage.Storage) bool {
+	return store.Type() == storage.TypeLocalDC
+}
+
+// AllowAllWrite does not filter any writes
+func AllowAllWrite(_ *storage.WriteQuery, _ storage.Storage) bool {
+	return true
+}
+
+// AllowNoneWrite filters all writes
+func AllowNoneWrite(_ *storage.WriteQuery, _ storage.Storage) bool {
+	return false
+}
+
+// MatchResolution filters out storages whose resolution is coarser than the
+// step of a fetch query, so that only storages able to serve the step at the
+// requested granularity are read from. Queries without a step match all
+// storages.
+func MatchResolution(query storage.Query, store storage.Storage) bool {
+	fetch, ok := query.(*storage.FetchQuery)
+	if !ok || fetch.Interval <= 0 {
+		return true
+	}
+	return store.Resolution() <= fetch.Interval
+}
+
+// WithinRetention filters out storages that do not retain the start of a
+// fetch query, so queries reaching further back than a storage's retention
+// are not fanned out to it. Storages with an unknown retention and queries
+// other than fetches are allowed
+func WithinRetention(query storage.Query, store storage.Storage) bool {
+	fetch, ok := query.(*storage.FetchQuery)
+	if !ok {
+		return true
+	}
+	retention := store.Retention()
+	if retention <= 0 {
+		return true
+	}
+	return !fetch.Start.Before(time.Now().Add(-retention))
+}
+
+// MatchTag filters out storages whose selector has the tag name set to value
+// when the matchers of a fetch query cannot match that value, so queries for
+// other values of the tag are not fanned out to storages dedicated to it.
+// Storages without the tag in their selector and queries other than fetches
+// are allowed
+func MatchTag(name, value string) Storage {
+	return func(query storage.Query, store storage.Storage) bool {
+		fetch, ok := query.(*storage.FetchQuery)
+		if !ok {
+			return true
+		}
+		if v, ok := store.Selector()[name]; !ok || v != value {
+			return true
+		}
+		for _, matcher := range fetch.TagMatchers {
+			if matcher.Name == name && !matcher.Matches(value) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// And allows a storage only if every filter allows it, stopping at the first
+// filter that does not. And of no filters allows all storages
+func And(filters ...Storage) Storage {
+	return func(query storage.Query, store storage.Storage) bool {
+		for _, filter := range filters {
+			if !filter(query, store) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or allows a storage if any filter allows it, stopping at the first filter
+// that does. Or of no filters allows no storages
+func Or(filters ...Storage) Storage {
+	return func(query storage.Query, store storage.Storage) bool {
+		for _, filter := range filters {
+			if filter(query, store) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not allows the storages the filter does not allow
+func Not(filter Storage) Storage {
+	return func(query storage.Query, store storage.Storage) bool {
+		return !filter(query, store)
+	}
+}
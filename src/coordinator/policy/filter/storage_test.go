@@ -22,11 +22,14 @@ package filter
 
 import (
 	"testing"
+	"time"
 
+	"github.com/m3db/m3db/src/coordinator/models"
 	"github.com/m3db/m3db/src/coordinator/storage"
 	"github.com/m3db/m3db/src/coordinator/storage/mock"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -54,3 +57,102 @@ func TestAllowNone(t *testing.T) {
 	assert.False(t, AllowNone(q, remote))
 	assert.False(t, AllowNone(q, multi))
 }
+
+func TestWriteFilters(t *testing.T) {
+	w := &storage.WriteQuery{}
+	assert.True(t, LocalOnlyWrite(w, local))
+	assert.False(t, LocalOnlyWrite(w, remote))
+	assert.False(t, LocalOnlyWrite(w, multi))
+
+	assert.True(t, AllowAllWrite(w, local))
+	assert.True(t, AllowAllWrite(w, remote))
+
+	assert.False(t, AllowNoneWrite(w, local))
+	assert.False(t, AllowNoneWrite(w, remote))
+}
+
+func TestMatchResolution(t *testing.T) {
+	raw := mock.NewMockStorageWithResolution(storage.TypeLocalDC, 0)
+	minute := mock.NewMockStorageWithResolution(storage.TypeLocalDC, time.Minute)
+	hour := mock.NewMockStorageWithResolution(storage.TypeLocalDC, time.Hour)
+
+	stepQuery := &storage.FetchQuery{Interval: 5 * time.Minute}
+	assert.True(t, MatchResolution(stepQuery, raw))
+	assert.True(t, MatchResolution(stepQuery, minute))
+	assert.False(t, MatchResolution(stepQuery, hour))
+
+	assert.True(t, MatchResolution(q, hour))
+	assert.True(t, MatchResolution(&storage.WriteQuery{}, hour))
+}
+
+func TestWithinRetention(t *testing.T) {
+	week := mock.NewMockStorageWithRetention(storage.TypeLocalDC, 7*24*time.Hour)
+	unknown := mock.NewMockStorageWithRetention(storage.TypeRemoteDC, 0)
+
+	now := time.Now()
+	recent := &storage.FetchQuery{Start: now.Add(-24 * time.Hour), End: now}
+	old := &storage.FetchQuery{Start: now.Add(-90 * 24 * time.Hour), End: now}
+
+	assert.True(t, WithinRetention(recent, week))
+	assert.False(t, WithinRetention(old, week))
+	assert.True(t, WithinRetention(old, unknown))
+	assert.True(t, WithinRetention(&storage.WriteQuery{}, week))
+}
+
+func TestMatchTag(t *testing.T) {
+	team := mock.NewMockStorageWithSelector(storage.TypeLocalDC, models.Tags{"team": "foo"})
+	other := mock.NewMockStorageWithSelector(storage.TypeLocalDC, models.Tags{"team": "bar"})
+	filter := MatchTag("team", "foo")
+
+	newQuery := func(matchType models.MatchType, name, value string) *storage.FetchQuery {
+		matcher, err := models.NewMatcher(matchType, name, value)
+		require.NoError(t, err)
+		return &storage.FetchQuery{TagMatchers: models.Matchers{matcher}}
+	}
+
+	assert.True(t, filter(newQuery(models.MatchEqual, "team", "foo"), team))
+	assert.False(t, filter(newQuery(models.MatchEqual, "team", "baz"), team))
+	assert.True(t, filter(newQuery(models.MatchRegexp, "team", "f.*"), team))
+	assert.False(t, filter(newQuery(models.MatchNotEqual, "team", "foo"), team))
+	assert.True(t, filter(newQuery(models.MatchEqual, "job", "api"), team))
+	assert.True(t, filter(q, team))
+	assert.True(t, filter(&storage.WriteQuery{}, team))
+
+	// Storages not dedicated to the tag value are always allowed
+	assert.True(t, filter(newQuery(models.MatchEqual, "team", "baz"), other))
+	assert.True(t, filter(newQuery(models.MatchEqual, "team", "baz"), local))
+}
+
+func TestAnd(t *testing.T) {
+	assert.True(t, And()(q, local))
+	assert.True(t, And(AllowAll, LocalOnly)(q, local))
+	assert.False(t, And(AllowAll, LocalOnly)(q, remote))
+
+	called := false
+	tracked := func(storage.Query, storage.Storage) bool {
+		called = true
+		return true
+	}
+	assert.False(t, And(AllowNone, tracked)(q, local))
+	assert.False(t, called, "And did not short circuit")
+}
+
+func TestOr(t *testing.T) {
+	assert.False(t, Or()(q, local))
+	assert.True(t, Or(AllowNone, LocalOnly)(q, local))
+	assert.False(t, Or(AllowNone, LocalOnly)(q, remote))
+
+	called := false
+	tracked := func(storage.Query, storage.Storage) bool {
+		called = true
+		return false
+	}
+	assert.True(t, Or(AllowAll, tracked)(q, local))
+	assert.False(t, called, "Or did not short circuit")
+}
+
+func TestNot(t *testing.T) {
+	assert.False(t, Not(LocalOnly)(q, local))
+	assert.True(t, Not(LocalOnly)(q, remote))
+	assert.True(t, And(AllowAll, Not(AllowNone))(q, multi))
+}
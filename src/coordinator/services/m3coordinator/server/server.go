@@ -40,6 +40,7 @@ import (
 	"github.com/m3db/m3db/src/coordinator/policy/filter"
 	"github.com/m3db/m3db/src/coordinator/storage"
 	"github.com/m3db/m3db/src/coordinator/storage/fanout"
+	"github.com/m3db/m3db/src/coordinator/storage/health"
 	"github.com/m3db/m3db/src/coordinator/storage/local"
 	"github.com/m3db/m3db/src/coordinator/storage/remote"
 	"github.com/m3db/m3db/src/coordinator/stores/m3db"
@@ -59,6 +60,7 @@ import (
 const (
 	defaultWorkerPoolCount = 4096
 	defaultWorkerPoolSize  = 20
+	shutdownTimeout        = 30 * time.Second
 )
 
 var (
@@ -203,7 +205,7 @@ func Run(runOpts RunOptions) {
 		return workerPool
 	})
 
-	fanoutStorage, storageCleanup := setupStorages(logger, clusters, cfg, objectPool)
+	fanoutStorage, healthTracker, storageCleanup := setupStorages(logger, clusters, cfg, objectPool)
 	defer storageCleanup()
 
 	var clusterClient clusterclient.Client
@@ -220,11 +222,12 @@ func Run(runOpts RunOptions) {
 	if err != nil {
 		logger.Fatal("unable to set up handlers", zap.Any("error", err))
 	}
+	handler.SetStorageHealthTracker(healthTracker)
 	handler.RegisterRoutes()
 
 	logger.Info("starting server", zap.String("address", cfg.ListenAddress))
 	go func() {
-		if err := http.ListenAndServe(cfg.ListenAddress, handler.Router); err != nil {
+		if err := handler.ListenAndServe(cfg.ListenAddress); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("unable to serve on listen address",
 				zap.Any("address", cfg.ListenAddress), zap.Any("error", err))
 		}
@@ -234,16 +237,31 @@ func Run(runOpts RunOptions) {
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
 	<-sigChan
+	logger.Info("draining in-flight requests", zap.Duration("timeout", shutdownTimeout))
+	shutdownCtx, cancel := context.WithTimeout(ctx, shutdownTimeout)
+	if err := handler.Shutdown(shutdownCtx); err != nil {
+		logger.Error("unable to drain in-flight requests", zap.Any("error", err))
+	}
+	cancel()
+
 	if err := clusters.Close(); err != nil {
 		logger.Fatal("unable to close M3DB cluster sessions", zap.Any("error", err))
 	}
 }
 
-func setupStorages(logger *zap.Logger, clusters local.Clusters, cfg config.Configuration, workerPool pool.ObjectPool) (storage.Storage, func()) {
+// setupStorages returns the fanout storage over the local and any remote
+// stores, each wrapped to track its health with the returned tracker
+func setupStorages(
+	logger *zap.Logger,
+	clusters local.Clusters,
+	cfg config.Configuration,
+	workerPool pool.ObjectPool,
+) (storage.Storage, *health.Tracker, func()) {
 	cleanup := func() {}
 
-	localStorage := local.NewStorage(clusters, workerPool)
-	stores := []storage.Storage{localStorage}
+	tracker := health.NewTracker()
+	localStorage := local.NewStorage(clusters, workerPool, cfg.Read.ReadOptions(), cfg.Selector)
+	stores := []storage.Storage{health.NewStorage(localStorage, "local", tracker)}
 	remoteEnabled := false
 	if cfg.RPC != nil && cfg.RPC.Enabled {
 		logger.Info("rpc enabled")
@@ -258,7 +276,7 @@ func setupStorages(logger *zap.Logger, clusters local.Clusters, cfg config.Confi
 				logger.Fatal("unable to start remote clients for addresses", zap.Any("error", err))
 			}
 
-			stores = append(stores, remote.NewStorage(client))
+			stores = append(stores, health.NewStorage(remote.NewStorage(client, cfg.RPC.RemoteSelector), "remote", tracker))
 			remoteEnabled = true
 		}
 	}
@@ -268,8 +286,19 @@ func setupStorages(logger *zap.Logger, clusters local.Clusters, cfg config.Confi
 		readFilter = filter.AllowAll
 	}
 
+	// Skip reading from stores whose selector the query cannot match
+	readFilters := []filter.Storage{readFilter}
+	for _, store := range stores {
+		for name, value := range store.Selector() {
+			readFilters = append(readFilters, filter.MatchTag(name, value))
+		}
+	}
+	if len(readFilters) > 1 {
+		readFilter = filter.And(readFilters...)
+	}
+
 	fanoutStorage := fanout.NewStorage(stores, readFilter, filter.LocalOnly)
-	return fanoutStorage, cleanup
+	return fanoutStorage, tracker, cleanup
 }
 
 func startGrpcServer(logger *zap.Logger, storage storage.Storage, cfg *config.RPCConfiguration) *grpc.Server {
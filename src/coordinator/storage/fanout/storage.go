@@ -22,6 +22,7 @@ package fanout
 
 import (
 	"context"
+	"time"
 
 	"github.com/m3db/m3db/src/coordinator/block"
 	"github.com/m3db/m3db/src/coordinator/errors"
@@ -115,6 +116,55 @@ func (s *fanoutStorage) Type() storage.Type {
 	return storage.TypeMultiDC
 }
 
+func (s *fanoutStorage) Resolution() time.Duration {
+	var resolution time.Duration
+	for i, store := range s.stores {
+		if r := store.Resolution(); i == 0 || r < resolution {
+			resolution = r
+		}
+	}
+	return resolution
+}
+
+func (s *fanoutStorage) Retention() time.Duration {
+	var retention time.Duration
+	for _, store := range s.stores {
+		r := store.Retention()
+		if r == 0 {
+			// Unknown for any store means unknown for all of them
+			return 0
+		}
+		if r > retention {
+			retention = r
+		}
+	}
+	return retention
+}
+
+func (s *fanoutStorage) Selector() models.Tags {
+	var selector models.Tags
+	for i, store := range s.stores {
+		storeSelector := store.Selector()
+		if storeSelector == nil {
+			// Any series for any store means any series for all of them
+			return nil
+		}
+		if i == 0 {
+			selector = make(models.Tags, len(storeSelector))
+			for k, v := range storeSelector {
+				selector[k] = v
+			}
+			continue
+		}
+		for k, v := range selector {
+			if storeValue, ok := storeSelector[k]; !ok || storeValue != v {
+				delete(selector, k)
+			}
+		}
+	}
+	return selector
+}
+
 func (s *fanoutStorage) FetchBlocks(
 	ctx context.Context, query *storage.FetchQuery, options *storage.FetchOptions) (block.Result, error) {
 	stores := filterStores(s.stores, s.writeFilter, query)
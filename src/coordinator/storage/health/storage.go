@@ -0,0 +1,97 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package health
+
+import (
+	"context"
+	"time"
+
+	"github.com/m3db/m3db/src/coordinator/block"
+	"github.com/m3db/m3db/src/coordinator/models"
+	"github.com/m3db/m3db/src/coordinator/storage"
+)
+
+type healthStorage struct {
+	store   storage.Storage
+	tracker *Tracker
+}
+
+// NewStorage wraps a store so that the success, failure and latency of
+// every fetch and write is recorded in the tracker under the given name.
+// The returned store is the key to use when looking up status from the
+// tracker.
+func NewStorage(store storage.Storage, name string, tracker *Tracker) storage.Storage {
+	s := &healthStorage{store: store, tracker: tracker}
+	tracker.register(s, name)
+	return s
+}
+
+func (s *healthStorage) Fetch(ctx context.Context, query *storage.FetchQuery, options *storage.FetchOptions) (*storage.FetchResult, error) {
+	start := s.tracker.nowFn()
+	result, err := s.store.Fetch(ctx, query, options)
+	s.record(start, err)
+	return result, err
+}
+
+func (s *healthStorage) FetchTags(ctx context.Context, query *storage.FetchQuery, options *storage.FetchOptions) (*storage.SearchResults, error) {
+	start := s.tracker.nowFn()
+	result, err := s.store.FetchTags(ctx, query, options)
+	s.record(start, err)
+	return result, err
+}
+
+func (s *healthStorage) FetchBlocks(
+	ctx context.Context, query *storage.FetchQuery, options *storage.FetchOptions) (block.Result, error) {
+	start := s.tracker.nowFn()
+	result, err := s.store.FetchBlocks(ctx, query, options)
+	s.record(start, err)
+	return result, err
+}
+
+func (s *healthStorage) Write(ctx context.Context, query *storage.WriteQuery) error {
+	start := s.tracker.nowFn()
+	err := s.store.Write(ctx, query)
+	s.record(start, err)
+	return err
+}
+
+func (s *healthStorage) Type() storage.Type {
+	return s.store.Type()
+}
+
+func (s *healthStorage) Resolution() time.Duration {
+	return s.store.Resolution()
+}
+
+func (s *healthStorage) Retention() time.Duration {
+	return s.store.Retention()
+}
+
+func (s *healthStorage) Selector() models.Tags {
+	return s.store.Selector()
+}
+
+func (s *healthStorage) Close() error {
+	return s.store.Close()
+}
+
+func (s *healthStorage) record(start time.Time, err error) {
+	s.tracker.record(s, s.tracker.nowFn().Sub(start), err)
+}
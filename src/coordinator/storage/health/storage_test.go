@@ -0,0 +1,103 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/m3db/m3db/src/coordinator/storage"
+	"github.com/m3db/m3db/src/coordinator/storage/mock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type errorStorage struct {
+	storage.Storage
+	err error
+}
+
+func (s *errorStorage) Write(ctx context.Context, query *storage.WriteQuery) error {
+	return s.err
+}
+
+func newTestTracker() *Tracker {
+	// Each call to now advances the clock so every call takes 10ms
+	now := time.Unix(0, 0)
+	return newTracker(func() time.Time {
+		now = now.Add(10 * time.Millisecond)
+		return now
+	})
+}
+
+func TestStorageRecordsHealth(t *testing.T) {
+	tracker := newTestTracker()
+	store := &errorStorage{Storage: mock.NewMockStorageWithType(storage.TypeRemoteDC)}
+	wrapped := NewStorage(store, "remote", tracker)
+	assert.Equal(t, storage.TypeRemoteDC, wrapped.Type())
+
+	ctx := context.Background()
+	_, err := wrapped.Fetch(ctx, &storage.FetchQuery{}, &storage.FetchOptions{})
+	require.NoError(t, err)
+
+	store.err = errors.New("write failed")
+	require.Error(t, wrapped.Write(ctx, &storage.WriteQuery{}))
+	require.Error(t, wrapped.Write(ctx, &storage.WriteQuery{}))
+
+	status, ok := tracker.Status(wrapped)
+	require.True(t, ok)
+	assert.Equal(t, "remote", status.Name)
+	assert.Equal(t, int64(1), status.Successes)
+	assert.Equal(t, int64(2), status.Failures)
+	assert.Equal(t, int64(2), status.ConsecutiveFailures)
+	assert.Equal(t, 10*time.Millisecond, status.LastLatency)
+	assert.Equal(t, 10*time.Millisecond, status.AverageLatency)
+	assert.True(t, status.LastFailure.After(status.LastSuccess))
+
+	store.err = nil
+	require.NoError(t, wrapped.Write(ctx, &storage.WriteQuery{}))
+	status, ok = tracker.Status(wrapped)
+	require.True(t, ok)
+	assert.Equal(t, int64(0), status.ConsecutiveFailures)
+	assert.Equal(t, int64(2), status.Successes)
+}
+
+func TestTrackerSnapshot(t *testing.T) {
+	tracker := newTestTracker()
+	local := NewStorage(mock.NewMockStorageWithType(storage.TypeLocalDC), "local", tracker)
+	NewStorage(mock.NewMockStorageWithType(storage.TypeRemoteDC), "remote", tracker)
+
+	_, ok := tracker.Status(mock.NewMockStorage())
+	assert.False(t, ok)
+
+	_, err := local.FetchTags(context.Background(), &storage.FetchQuery{}, &storage.FetchOptions{})
+	require.NoError(t, err)
+
+	byName := make(map[string]Status)
+	for _, status := range tracker.Snapshot() {
+		byName[status.Name] = status
+	}
+	require.Len(t, byName, 2)
+	assert.Equal(t, int64(1), byName["local"].Successes)
+	assert.Equal(t, int64(0), byName["remote"].Successes)
+}
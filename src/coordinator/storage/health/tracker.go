@@ -0,0 +1,121 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package health
+
+import (
+	"sync"
+	"time"
+
+	"github.com/m3db/m3db/src/coordinator/storage"
+)
+
+// latencyDecay is the weight given to the latest observed latency when
+// updating a store's moving average latency.
+const latencyDecay = 0.2
+
+// Status is a point in time view of a store's health.
+type Status struct {
+	Name                string        `json:"name"`
+	Successes           int64         `json:"successes"`
+	Failures            int64         `json:"failures"`
+	ConsecutiveFailures int64         `json:"consecutiveFailures"`
+	LastLatency         time.Duration `json:"lastLatency"`
+	AverageLatency      time.Duration `json:"averageLatency"`
+	LastSuccess         time.Time     `json:"lastSuccess"`
+	LastFailure         time.Time     `json:"lastFailure"`
+}
+
+// Tracker records the success, failure and latency of calls made against
+// stores wrapped with NewStorage, for use by storage filters and debugging.
+type Tracker struct {
+	sync.RWMutex
+	nowFn    func() time.Time
+	statuses map[storage.Storage]*Status
+}
+
+// NewTracker creates a new health tracker.
+func NewTracker() *Tracker {
+	return newTracker(time.Now)
+}
+
+func newTracker(nowFn func() time.Time) *Tracker {
+	return &Tracker{
+		nowFn:    nowFn,
+		statuses: make(map[storage.Storage]*Status),
+	}
+}
+
+// Status returns the health status of a store, the store must be the
+// wrapped store returned from NewStorage.
+func (t *Tracker) Status(store storage.Storage) (Status, bool) {
+	t.RLock()
+	status, ok := t.statuses[store]
+	t.RUnlock()
+	if !ok {
+		return Status{}, false
+	}
+	return *status, true
+}
+
+// Snapshot returns the health status of all tracked stores.
+func (t *Tracker) Snapshot() []Status {
+	t.RLock()
+	defer t.RUnlock()
+	result := make([]Status, 0, len(t.statuses))
+	for _, status := range t.statuses {
+		result = append(result, *status)
+	}
+	return result
+}
+
+func (t *Tracker) register(store storage.Storage, name string) {
+	t.Lock()
+	t.statuses[store] = &Status{Name: name}
+	t.Unlock()
+}
+
+func (t *Tracker) record(store storage.Storage, latency time.Duration, err error) {
+	now := t.nowFn()
+
+	t.Lock()
+	defer t.Unlock()
+	status, ok := t.statuses[store]
+	if !ok {
+		return
+	}
+
+	if err != nil {
+		status.Failures++
+		status.ConsecutiveFailures++
+		status.LastFailure = now
+	} else {
+		status.Successes++
+		status.ConsecutiveFailures = 0
+		status.LastSuccess = now
+	}
+
+	status.LastLatency = latency
+	if status.Successes+status.Failures == 1 {
+		status.AverageLatency = latency
+	} else {
+		status.AverageLatency = time.Duration(latencyDecay*float64(latency) +
+			(1-latencyDecay)*float64(status.AverageLatency))
+	}
+}
@@ -49,6 +49,15 @@ type Storage interface {
 	Appender
 	// Type identifies the type of the underlying storage
 	Type() Type
+	// Resolution is the finest resolution of the data held by the storage,
+	// zero means it holds raw unaggregated datapoints or is unknown
+	Resolution() time.Duration
+	// Retention is the longest retention of the data held by the storage,
+	// zero means it is unknown
+	Retention() time.Duration
+	// Selector is the set of tags every series held by the storage has,
+	// nil means the storage may hold any series
+	Selector() models.Tags
 	// Close is used to close the underlying storage and free up resources
 	Close() error
 }
@@ -24,6 +24,7 @@ import (
 	"context"
 	goerrors "errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -43,14 +44,39 @@ var (
 	errNoLocalClustersFulfillsQuery = goerrors.New("no clusters can fulfill query")
 )
 
+// ReadOptions configures which cluster namespaces are read from.
+type ReadOptions struct {
+	// DefaultNamespace is the only namespace read from when it retains the
+	// full range of a query, ignored if empty.
+	DefaultNamespace string
+
+	// FallbackToLongestRetention reads from the namespace with the longest
+	// retention when no namespace retains the full range of a query rather
+	// than returning an error.
+	FallbackToLongestRetention bool
+}
+
 type localStorage struct {
 	clusters   Clusters
 	workerPool pool.ObjectPool
+	readOpts   ReadOptions
+	selector   models.Tags
 }
 
-// NewStorage creates a new local Storage instance.
-func NewStorage(clusters Clusters, workerPool pool.ObjectPool) storage.Storage {
-	return &localStorage{clusters: clusters, workerPool: workerPool}
+// NewStorage creates a new local Storage instance, selector is the set of
+// tags every series in the clusters has and may be nil.
+func NewStorage(
+	clusters Clusters,
+	workerPool pool.ObjectPool,
+	readOpts ReadOptions,
+	selector models.Tags,
+) storage.Storage {
+	return &localStorage{
+		clusters:   clusters,
+		workerPool: workerPool,
+		readOpts:   readOpts,
+		selector:   selector,
+	}
 }
 
 func (s *localStorage) Fetch(ctx context.Context, query *storage.FetchQuery, options *storage.FetchOptions) (*storage.FetchResult, error) {
@@ -72,43 +98,104 @@ func (s *localStorage) Fetch(ctx context.Context, query *storage.FetchQuery, opt
 	// cluster that can completely fulfill this range and then prefer the
 	// highest resolution (most fine grained) results.
 	// This needs to be optimized, however this is a start.
+	namespaces, err := s.readNamespaces(query.Start)
+	if err != nil {
+		return nil, err
+	}
+
 	var (
-		opts       = storage.FetchOptionsToM3Options(options, query)
-		namespaces = s.clusters.ClusterNamespaces()
-		now        = time.Now()
-		fetches    = 0
-		result     multiFetchResult
-		wg         sync.WaitGroup
+		opts    = storage.FetchOptionsToM3Options(options, query)
+		results = make([]*storage.FetchResult, len(namespaces))
+		errs    = make([]error, len(namespaces))
+		wg      sync.WaitGroup
 	)
-	for _, namespace := range namespaces {
-		namespace := namespace // Capture var
+	for i, namespace := range namespaces {
+		i, namespace := i, namespace // Capture vars
 
+		wg.Add(1)
+		go func() {
+			results[i], errs[i] = s.fetch(namespace, m3query, opts)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	// Merge in namespace order so the result does not depend on which
+	// fetch completed first
+	var result multiFetchResult
+	for i, namespace := range namespaces {
+		result.add(namespace.Attributes(), results[i], errs[i])
+	}
+	if err := result.err.FinalError(); err != nil {
+		return nil, err
+	}
+	return result.result, nil
+}
+
+// readNamespaces returns the cluster namespaces to read from for a query
+// starting at the given time, in the order their results are merged:
+//   - Only namespaces that retain the full query range are read, ordered by
+//     finest resolution and then by namespace ID. Results are merged per
+//     series keeping the first namespace's series when resolutions are equal,
+//     so each series is read from the finest resolution that has it.
+//   - If the default namespace retains the full query range it is the only
+//     namespace read.
+//   - If no namespace retains the full query range and falling back is
+//     enabled the namespace with the longest retention is read, with ties
+//     broken by finest resolution and then by namespace ID.
+func (s *localStorage) readNamespaces(start time.Time) ([]ClusterNamespace, error) {
+	var (
+		all        = s.clusters.ClusterNamespaces()
+		now        = time.Now()
+		namespaces []ClusterNamespace
+	)
+	for _, namespace := range all {
 		clusterStart := now.Add(-1 * namespace.Attributes().Retention)
 
 		// Only include if cluster can completely fulfill the range
-		if clusterStart.After(query.Start) {
+		if clusterStart.After(start) {
 			continue
 		}
 
-		fetches++
+		if s.readOpts.DefaultNamespace != "" &&
+			namespace.NamespaceID().String() == s.readOpts.DefaultNamespace {
+			return []ClusterNamespace{namespace}, nil
+		}
 
-		wg.Add(1)
-		go func() {
-			r, err := s.fetch(namespace, m3query, opts)
-			result.add(namespace.Attributes(), r, err)
-			wg.Done()
-		}()
+		namespaces = append(namespaces, namespace)
 	}
 
-	if fetches == 0 {
-		return nil, errNoLocalClustersFulfillsQuery
+	if len(namespaces) == 0 {
+		if !s.readOpts.FallbackToLongestRetention || len(all) == 0 {
+			return nil, errNoLocalClustersFulfillsQuery
+		}
+
+		longest := all[0]
+		for _, namespace := range all[1:] {
+			retention := namespace.Attributes().Retention
+			longestRetention := longest.Attributes().Retention
+			if retention > longestRetention ||
+				(retention == longestRetention && finerNamespace(namespace, longest)) {
+				longest = namespace
+			}
+		}
+		return []ClusterNamespace{longest}, nil
 	}
 
-	wg.Wait()
-	if err := result.err.FinalError(); err != nil {
-		return nil, err
+	sort.Slice(namespaces, func(i, j int) bool {
+		return finerNamespace(namespaces[i], namespaces[j])
+	})
+	return namespaces, nil
+}
+
+// finerNamespace returns whether namespace a has a finer resolution than b,
+// or the same resolution and a lower namespace ID.
+func finerNamespace(a, b ClusterNamespace) bool {
+	resolutionA, resolutionB := a.Attributes().Resolution, b.Attributes().Resolution
+	if resolutionA != resolutionB {
+		return resolutionA < resolutionB
 	}
-	return result.result, nil
+	return a.NamespaceID().String() < b.NamespaceID().String()
 }
 
 func (s *localStorage) fetch(
@@ -143,38 +230,34 @@ func (s *localStorage) FetchTags(ctx context.Context, query *storage.FetchQuery,
 		return nil, err
 	}
 
+	namespaces, err := s.readNamespaces(query.Start)
+	if err != nil {
+		return nil, err
+	}
+
 	var (
-		opts       = storage.FetchOptionsToM3Options(options, query)
-		namespaces = s.clusters.ClusterNamespaces()
-		now        = time.Now()
-		fetches    = 0
-		result     multiFetchTagsResult
-		wg         sync.WaitGroup
+		opts    = storage.FetchOptionsToM3Options(options, query)
+		results = make([]*storage.SearchResults, len(namespaces))
+		errs    = make([]error, len(namespaces))
+		wg      sync.WaitGroup
 	)
-	for _, namespace := range namespaces {
-		namespace := namespace // Capture var
-
-		clusterStart := now.Add(-1 * namespace.Attributes().Retention)
-
-		// Only include if cluster can completely fulfill the range
-		if clusterStart.After(query.Start) {
-			continue
-		}
-
-		fetches++
+	for i, namespace := range namespaces {
+		i, namespace := i, namespace // Capture vars
 
 		wg.Add(1)
 		go func() {
-			result.add(s.fetchTags(namespace, m3query, opts))
+			results[i], errs[i] = s.fetchTags(namespace, m3query, opts)
 			wg.Done()
 		}()
 	}
+	wg.Wait()
 
-	if fetches == 0 {
-		return nil, errNoLocalClustersFulfillsQuery
+	// Merge in namespace order so the result does not depend on which
+	// fetch completed first
+	var result multiFetchTagsResult
+	for i := range namespaces {
+		result.add(results[i], errs[i])
 	}
-
-	wg.Wait()
 	if err := result.err.FinalError(); err != nil {
 		return nil, err
 	}
@@ -243,6 +326,30 @@ func (s *localStorage) Write(ctx context.Context, query *storage.WriteQuery) err
 	return execution.ExecuteParallel(ctx, requests)
 }
 
+func (s *localStorage) Resolution() time.Duration {
+	var resolution time.Duration
+	for i, namespace := range s.clusters.ClusterNamespaces() {
+		if r := namespace.Attributes().Resolution; i == 0 || r < resolution {
+			resolution = r
+		}
+	}
+	return resolution
+}
+
+func (s *localStorage) Retention() time.Duration {
+	var retention time.Duration
+	for _, namespace := range s.clusters.ClusterNamespaces() {
+		if r := namespace.Attributes().Retention; r > retention {
+			retention = r
+		}
+	}
+	return retention
+}
+
+func (s *localStorage) Selector() models.Tags {
+	return s.selector
+}
+
 func (s *localStorage) Type() storage.Type {
 	return storage.TypeLocalDC
 }
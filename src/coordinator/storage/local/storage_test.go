@@ -61,6 +61,14 @@ func (s testSessions) forEach(fn func(session *client.MockSession)) {
 func setup(
 	t *testing.T,
 	ctrl *gomock.Controller,
+) (storage.Storage, testSessions) {
+	return setupWithReadOptions(t, ctrl, ReadOptions{})
+}
+
+func setupWithReadOptions(
+	t *testing.T,
+	ctrl *gomock.Controller,
+	readOpts ReadOptions,
 ) (storage.Storage, testSessions) {
 	logging.InitWithCores(nil)
 	logger := logging.WithContext(context.TODO())
@@ -78,7 +86,7 @@ func setup(
 		Resolution:  time.Minute,
 	})
 	require.NoError(t, err)
-	storage := NewStorage(clusters, nil)
+	storage := NewStorage(clusters, nil, readOpts, nil)
 	return storage, testSessions{
 		unaggregated1MonthRetention:                unaggregated1MonthRetention,
 		aggregated1MonthRetention1MinuteResolution: aggregated1MonthRetention1MinuteResolution,
@@ -130,6 +138,17 @@ func setupLocalWrite(t *testing.T, ctrl *gomock.Controller) storage.Storage {
 	return store
 }
 
+func TestLocalSelector(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store, _ := setup(t, ctrl)
+	assert.Nil(t, store.Selector())
+
+	selector := models.Tags{"dc": "east"}
+	store = NewStorage(nil, nil, ReadOptions{}, selector)
+	assert.Equal(t, selector, store.Selector())
+}
+
 func TestLocalWriteEmpty(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -235,6 +254,53 @@ func TestLocalReadNoClustersForTimeRangeError(t *testing.T) {
 	assert.Equal(t, errNoLocalClustersFulfillsQuery, err)
 }
 
+func TestLocalReadDefaultNamespace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store, sessions := setupWithReadOptions(t, ctrl, ReadOptions{
+		DefaultNamespace: "metrics_aggregated",
+	})
+	testTags := seriesiter.GenerateTag()
+	sessions.aggregated1MonthRetention1MinuteResolution.EXPECT().
+		FetchTagged(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(seriesiter.NewMockSeriesIters(ctrl, testTags, 1, 2), true, nil)
+
+	results, err := store.Fetch(context.TODO(), newFetchReq(), &storage.FetchOptions{Limit: 100})
+	require.NoError(t, err)
+	require.Len(t, results.SeriesList, 1)
+}
+
+func TestLocalReadFallbackToLongestRetention(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store, sessions := setupWithReadOptions(t, ctrl, ReadOptions{
+		FallbackToLongestRetention: true,
+	})
+	testTags := seriesiter.GenerateTag()
+	// Retentions are equal so the finest resolution namespace is read
+	sessions.unaggregated1MonthRetention.EXPECT().
+		FetchTagged(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(seriesiter.NewMockSeriesIters(ctrl, testTags, 1, 2), true, nil)
+
+	searchReq := newFetchReq()
+	searchReq.Start = time.Now().Add(-2 * testRetention)
+	results, err := store.Fetch(context.TODO(), searchReq, &storage.FetchOptions{Limit: 100})
+	require.NoError(t, err)
+	require.Len(t, results.SeriesList, 1)
+}
+
+func TestLocalReadNamespacesOrderedByResolution(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	store, _ := setup(t, ctrl)
+
+	namespaces, err := store.(*localStorage).readNamespaces(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, namespaces, 2)
+	assert.Equal(t, "metrics_unaggregated", namespaces[0].NamespaceID().String())
+	assert.Equal(t, "metrics_aggregated", namespaces[1].NamespaceID().String())
+}
+
 func TestLocalSearchError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
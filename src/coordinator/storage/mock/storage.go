@@ -22,14 +22,19 @@ package mock
 
 import (
 	"context"
+	"time"
 
 	"github.com/m3db/m3db/src/coordinator/block"
+	"github.com/m3db/m3db/src/coordinator/models"
 	"github.com/m3db/m3db/src/coordinator/storage"
 )
 
 type mockStorage struct {
-	sType  storage.Type
-	blocks []block.Block
+	sType      storage.Type
+	blocks     []block.Block
+	resolution time.Duration
+	retention  time.Duration
+	selector   models.Tags
 }
 
 // NewMockStorage creates a new mock Storage instance.
@@ -47,6 +52,24 @@ func NewMockStorageWithType(sType storage.Type) storage.Storage {
 	return &mockStorage{sType: sType}
 }
 
+// NewMockStorageWithResolution creates a new mock Storage instance that
+// reports the given resolution.
+func NewMockStorageWithResolution(sType storage.Type, resolution time.Duration) storage.Storage {
+	return &mockStorage{sType: sType, resolution: resolution}
+}
+
+// NewMockStorageWithRetention creates a new mock Storage instance that
+// reports the given retention.
+func NewMockStorageWithRetention(sType storage.Type, retention time.Duration) storage.Storage {
+	return &mockStorage{sType: sType, retention: retention}
+}
+
+// NewMockStorageWithSelector creates a new mock Storage instance that
+// reports the given selector.
+func NewMockStorageWithSelector(sType storage.Type, selector models.Tags) storage.Storage {
+	return &mockStorage{sType: sType, selector: selector}
+}
+
 func (s *mockStorage) Fetch(ctx context.Context, query *storage.FetchQuery, _ *storage.FetchOptions) (*storage.FetchResult, error) {
 	return nil, nil
 }
@@ -63,6 +86,18 @@ func (s *mockStorage) Type() storage.Type {
 	return s.sType
 }
 
+func (s *mockStorage) Resolution() time.Duration {
+	return s.resolution
+}
+
+func (s *mockStorage) Retention() time.Duration {
+	return s.retention
+}
+
+func (s *mockStorage) Selector() models.Tags {
+	return s.selector
+}
+
 func (s *mockStorage) Close() error {
 	return nil
 }
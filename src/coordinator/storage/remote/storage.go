@@ -22,20 +22,24 @@ package remote
 
 import (
 	"context"
+	"time"
 
 	"github.com/m3db/m3db/src/coordinator/block"
 	"github.com/m3db/m3db/src/coordinator/errors"
+	"github.com/m3db/m3db/src/coordinator/models"
 	"github.com/m3db/m3db/src/coordinator/storage"
 	"github.com/m3db/m3db/src/coordinator/tsdb/remote"
 )
 
 type remoteStorage struct {
-	client remote.Client
+	client   remote.Client
+	selector models.Tags
 }
 
-// NewStorage creates a new remote Storage instance.
-func NewStorage(c remote.Client) storage.Storage {
-	return &remoteStorage{client: c}
+// NewStorage creates a new remote Storage instance, selector is the set of
+// tags every series held by the remote has and may be nil.
+func NewStorage(c remote.Client, selector models.Tags) storage.Storage {
+	return &remoteStorage{client: c, selector: selector}
 }
 
 func (s *remoteStorage) Fetch(ctx context.Context, query *storage.FetchQuery, options *storage.FetchOptions) (*storage.FetchResult, error) {
@@ -55,6 +59,18 @@ func (s *remoteStorage) Type() storage.Type {
 	return storage.TypeRemoteDC
 }
 
+func (s *remoteStorage) Resolution() time.Duration {
+	return 0
+}
+
+func (s *remoteStorage) Retention() time.Duration {
+	return 0
+}
+
+func (s *remoteStorage) Selector() models.Tags {
+	return s.selector
+}
+
 func (s *remoteStorage) Close() error {
 	return nil
 }
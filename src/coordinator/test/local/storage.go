@@ -54,6 +54,6 @@ func NewStorageAndSession(
 		Retention:   TestRetention,
 	})
 	require.NoError(t, err)
-	storage := local.NewStorage(clusters, nil)
+	storage := local.NewStorage(clusters, nil, local.ReadOptions{}, nil)
 	return storage, session
 }
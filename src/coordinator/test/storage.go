@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/m3db/m3db/src/coordinator/block"
+	"github.com/m3db/m3db/src/coordinator/models"
 	"github.com/m3db/m3db/src/coordinator/storage"
 )
 
@@ -58,6 +59,18 @@ func (s *slowStorage) Type() storage.Type {
 	return storage.TypeMultiDC
 }
 
+func (s *slowStorage) Resolution() time.Duration {
+	return s.storage.Resolution()
+}
+
+func (s *slowStorage) Retention() time.Duration {
+	return s.storage.Retention()
+}
+
+func (s *slowStorage) Selector() models.Tags {
+	return s.storage.Selector()
+}
+
 func (s *slowStorage) Close() error {
 	return nil
 }
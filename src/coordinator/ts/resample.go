@@ -0,0 +1,149 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ts
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// AggregationFunc aggregates the values of a resampled bucket into a single
+// value, it is only called with non empty buckets
+type AggregationFunc func(values []float64) float64
+
+// The built-in aggregation functions propagate NaN, returning NaN for any
+// bucket containing a NaN value, wrap them with SkipNaN to ignore NaN values.
+var (
+	// AggregateMin returns the minimum value of a bucket
+	AggregateMin AggregationFunc = propagateNaN(func(values []float64) float64 {
+		min := values[0]
+		for _, v := range values[1:] {
+			min = math.Min(min, v)
+		}
+		return min
+	})
+
+	// AggregateMax returns the maximum value of a bucket
+	AggregateMax AggregationFunc = propagateNaN(func(values []float64) float64 {
+		max := values[0]
+		for _, v := range values[1:] {
+			max = math.Max(max, v)
+		}
+		return max
+	})
+
+	// AggregateSum returns the sum of the values of a bucket
+	AggregateSum AggregationFunc = propagateNaN(sum)
+
+	// AggregateMean returns the mean of the values of a bucket
+	AggregateMean AggregationFunc = propagateNaN(func(values []float64) float64 {
+		return sum(values) / float64(len(values))
+	})
+
+	// AggregateLast returns the last value of a bucket
+	AggregateLast AggregationFunc = propagateNaN(func(values []float64) float64 {
+		return values[len(values)-1]
+	})
+)
+
+func sum(values []float64) float64 {
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func propagateNaN(agg AggregationFunc) AggregationFunc {
+	return func(values []float64) float64 {
+		for _, v := range values {
+			if math.IsNaN(v) {
+				return math.NaN()
+			}
+		}
+		return agg(values)
+	}
+}
+
+// SkipNaN wraps an aggregation function to ignore NaN values, buckets
+// containing only NaN values aggregate to NaN
+func SkipNaN(agg AggregationFunc) AggregationFunc {
+	return func(values []float64) float64 {
+		filtered := make([]float64, 0, len(values))
+		for _, v := range values {
+			if !math.IsNaN(v) {
+				filtered = append(filtered, v)
+			}
+		}
+		if len(filtered) == 0 {
+			return math.NaN()
+		}
+		return agg(filtered)
+	}
+}
+
+// Resample buckets the values of the series into steps of stepMillis
+// milliseconds starting at the time of the first value and aggregates each
+// bucket with agg, steps without any values are NaN. Values are expected to
+// be in time order. It panics if stepMillis is not positive.
+func (s *Series) Resample(stepMillis int, agg AggregationFunc) *Series {
+	if stepMillis <= 0 {
+		panic(fmt.Sprintf("resample step must be positive, got %d", stepMillis))
+	}
+
+	step := time.Duration(stepMillis) * time.Millisecond
+	numValues := s.vals.Len()
+	if numValues == 0 {
+		return NewSeries(s.name, NewFixedStepValues(step, 0, math.NaN(), time.Time{}), s.Tags)
+	}
+
+	var start time.Time
+	if fixed, ok := s.vals.(FixedResolutionMutableValues); ok {
+		start = fixed.StartTime()
+	} else {
+		start = s.vals.DatapointAt(0).Timestamp
+	}
+
+	last := s.vals.DatapointAt(numValues - 1).Timestamp
+	numSteps := int(last.Sub(start)/step) + 1
+	resampled := NewFixedStepValues(step, numSteps, math.NaN(), start)
+
+	var bucket []float64
+	bucketIdx := -1
+	for i := 0; i < numValues; i++ {
+		dp := s.vals.DatapointAt(i)
+		idx := int(dp.Timestamp.Sub(start) / step)
+		if idx != bucketIdx {
+			if len(bucket) > 0 {
+				resampled.SetValueAt(bucketIdx, agg(bucket))
+			}
+			bucket = bucket[:0]
+			bucketIdx = idx
+		}
+		bucket = append(bucket, dp.Value)
+	}
+	if len(bucket) > 0 {
+		resampled.SetValueAt(bucketIdx, agg(bucket))
+	}
+
+	return NewSeries(s.name, resampled, s.Tags)
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ts
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/m3db/m3db/src/coordinator/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesResample(t *testing.T) {
+	start := time.Unix(0, 0)
+	values := NewFixedStepValues(time.Minute, 7, 0, start)
+	for i, v := range []float64{1, 5, 3, 2, math.NaN(), 4, 6} {
+		values.SetValueAt(i, v)
+	}
+	tags := models.Tags{"foo": "bar"}
+	series := NewSeries("metrics", values, tags)
+
+	tests := []struct {
+		name     string
+		agg      AggregationFunc
+		expected []float64
+	}{
+		{name: "min", agg: AggregateMin, expected: []float64{1, math.NaN(), 6}},
+		{name: "max", agg: AggregateMax, expected: []float64{5, math.NaN(), 6}},
+		{name: "sum", agg: AggregateSum, expected: []float64{9, math.NaN(), 6}},
+		{name: "mean", agg: AggregateMean, expected: []float64{3, math.NaN(), 6}},
+		{name: "last", agg: AggregateLast, expected: []float64{3, math.NaN(), 6}},
+		{name: "skip nan min", agg: SkipNaN(AggregateMin), expected: []float64{1, 2, 6}},
+		{name: "skip nan mean", agg: SkipNaN(AggregateMean), expected: []float64{3, 3, 6}},
+		{name: "skip nan last", agg: SkipNaN(AggregateLast), expected: []float64{3, 4, 6}},
+	}
+	for _, test := range tests {
+		resampled := series.Resample(180000, test.agg)
+		assert.Equal(t, "metrics", resampled.Name(), test.name)
+		assert.Equal(t, tags, resampled.Tags, test.name)
+
+		fixed, ok := resampled.Values().(FixedResolutionMutableValues)
+		require.True(t, ok, test.name)
+		assert.Equal(t, 3*time.Minute, fixed.Resolution(), test.name)
+		assert.Equal(t, start, fixed.StartTime(), test.name)
+
+		require.Equal(t, len(test.expected), resampled.Len(), test.name)
+		for i, expected := range test.expected {
+			if math.IsNaN(expected) {
+				assert.True(t, math.IsNaN(resampled.ValueAt(i)), test.name)
+				continue
+			}
+			assert.Equal(t, expected, resampled.ValueAt(i), test.name)
+		}
+	}
+}
+
+func TestSeriesResampleDatapoints(t *testing.T) {
+	start := time.Unix(0, 0)
+	datapoints := Datapoints{
+		{Timestamp: start, Value: 1},
+		{Timestamp: start.Add(10 * time.Second), Value: 2},
+		{Timestamp: start.Add(75 * time.Second), Value: 3},
+	}
+	resampled := NewSeries("raw", datapoints, nil).Resample(30000, AggregateSum)
+
+	require.Equal(t, 3, resampled.Len())
+	assert.Equal(t, 3.0, resampled.ValueAt(0))
+	assert.True(t, math.IsNaN(resampled.ValueAt(1)))
+	assert.Equal(t, 3.0, resampled.ValueAt(2))
+
+	assert.Equal(t, 0, NewSeries("empty", Datapoints{}, nil).Resample(30000, AggregateSum).Len())
+	assert.Panics(t, func() { NewSeries("raw", datapoints, nil).Resample(0, AggregateSum) })
+}
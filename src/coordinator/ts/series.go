@@ -22,7 +22,11 @@ package ts
 
 import (
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/m3db/m3db/src/coordinator/errors"
 	"github.com/m3db/m3db/src/coordinator/models"
@@ -45,6 +49,57 @@ func NewSeries(name string, vals Values, tags models.Tags) *Series {
 	}
 }
 
+// reservedTagPrefix is the prefix of tag names reserved for internal use
+const reservedTagPrefix = "__"
+
+// NewSeriesChecked creates a new Series like NewSeries after validating the
+// name and tags, returning an error if the name is empty or not valid UTF-8,
+// or if a tag is not a valid Prometheus label. Tag names must match
+// [a-zA-Z_][a-zA-Z0-9_]* and must not start with the reserved "__" prefix
+// other than the metric name tag, and tag values must be valid UTF-8.
+func NewSeriesChecked(name string, vals Values, tags models.Tags) (*Series, error) {
+	if name == "" {
+		return nil, fmt.Errorf("series name must not be empty")
+	}
+	if !utf8.ValidString(name) {
+		return nil, fmt.Errorf("series name %q is not valid UTF-8", name)
+	}
+	for k, v := range tags {
+		if err := validateTag(k, v); err != nil {
+			return nil, fmt.Errorf("series %s has invalid tag: %v", name, err)
+		}
+	}
+	return NewSeries(name, vals, tags), nil
+}
+
+func validateTag(name, value string) error {
+	if !validTagName(name) {
+		return fmt.Errorf("tag name %q must match [a-zA-Z_][a-zA-Z0-9_]*", name)
+	}
+	if name != models.MetricName && strings.HasPrefix(name, reservedTagPrefix) {
+		return fmt.Errorf("tag name %q is reserved", name)
+	}
+	if !utf8.ValidString(value) {
+		return fmt.Errorf("tag %s value %q is not valid UTF-8", name, value)
+	}
+	return nil
+}
+
+func validTagName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // Name returns the name of the timeseries block
 func (s *Series) Name() string { return s.name }
 
@@ -54,6 +109,107 @@ func (s *Series) Len() int { return s.vals.Len() }
 // Values returns the underlying values interface
 func (s *Series) Values() Values { return s.vals }
 
+// WithTags returns a shallow copy of the series with its tags replaced, the
+// original series is left untouched and the values are shared between them
+func (s *Series) WithTags(tags models.Tags) *Series {
+	return NewSeries(s.name, s.vals, tags)
+}
+
+// AddTag returns a shallow copy of the series with the tag added to a copy
+// of its tags, replacing any existing value for the name, the original
+// series and its tags are left untouched
+func (s *Series) AddTag(name, value string) *Series {
+	tags := make(models.Tags, len(s.Tags)+1)
+	for k, v := range s.Tags {
+		tags[k] = v
+	}
+	tags[name] = value
+	return s.WithTags(tags)
+}
+
+// ValueAt returns the value at the ith step, it panics if i is out of range
+// as indexing a slice would
+func (s *Series) ValueAt(i int) float64 { return s.vals.ValueAt(i) }
+
+// DatapointAt returns the timestamp and value at the ith step, it panics if i
+// is out of range as indexing a slice would
+func (s *Series) DatapointAt(i int) (time.Time, float64) {
+	dp := s.vals.DatapointAt(i)
+	return dp.Timestamp, dp.Value
+}
+
+// StartTimeForStep returns the start time of the ith step given steps of
+// stepMillis milliseconds, measured from the timestamp of the first step. It
+// panics if i is out of range as indexing a slice would
+func (s *Series) StartTimeForStep(i int, stepMillis int) time.Time {
+	if i < 0 || i >= s.vals.Len() {
+		panic(fmt.Sprintf("step index out of range [%d] with length %d", i, s.vals.Len()))
+	}
+	start := s.vals.DatapointAt(0).Timestamp
+	return start.Add(time.Duration(i*stepMillis) * time.Millisecond)
+}
+
+// NonNullRange returns the timestamps of the first and last non NaN values in
+// the series, ok is false if the series is empty or all values are NaN
+func (s *Series) NonNullRange() (start, end time.Time, ok bool) {
+	first := -1
+	for i := 0; i < s.vals.Len(); i++ {
+		if !math.IsNaN(s.vals.ValueAt(i)) {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return time.Time{}, time.Time{}, false
+	}
+
+	last := first
+	for i := s.vals.Len() - 1; i > first; i-- {
+		if !math.IsNaN(s.vals.ValueAt(i)) {
+			last = i
+			break
+		}
+	}
+
+	return s.vals.DatapointAt(first).Timestamp, s.vals.DatapointAt(last).Timestamp, true
+}
+
+// CountNonNull returns the number of non NaN values in the series
+func (s *Series) CountNonNull() int {
+	count := 0
+	for i := 0; i < s.vals.Len(); i++ {
+		if !math.IsNaN(s.vals.ValueAt(i)) {
+			count++
+		}
+	}
+	return count
+}
+
+// NonNullValues returns the non NaN values in the series in order, it returns
+// an empty slice if the series is empty or all values are NaN
+func (s *Series) NonNullValues() []float64 {
+	values := make([]float64, 0, s.CountNonNull())
+	for i := 0; i < s.vals.Len(); i++ {
+		if v := s.vals.ValueAt(i); !math.IsNaN(v) {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// DatapointsNonNull returns the datapoints with non NaN values in the series
+// in order, it returns empty datapoints if the series is empty or all values
+// are NaN
+func (s *Series) DatapointsNonNull() Datapoints {
+	datapoints := make(Datapoints, 0, s.CountNonNull())
+	for i := 0; i < s.vals.Len(); i++ {
+		if !math.IsNaN(s.vals.ValueAt(i)) {
+			datapoints = append(datapoints, s.vals.DatapointAt(i))
+		}
+	}
+	return datapoints
+}
+
 // Align adjusts the datapoints to start, end and a fixed interval
 func (s *Series) Align(start, end time.Time, interval time.Duration) (*Series, error) {
 	fixedVals, err := alignValues(s.Values(), start, end, interval)
@@ -76,6 +232,125 @@ func alignValues(values Values, start, end time.Time, interval time.Duration) (F
 	}
 }
 
+// ConsolidateSeries concatenates the values of several fixed resolution
+// series for the same logical series, such as those read from adjacent
+// storage blocks, into a single series in time order. The series must share
+// the same resolution and be contiguous, an error is returned if any two of
+// them overlap or leave a gap between them. The name and tags of the
+// earliest series are used for the result.
+func ConsolidateSeries(series []*Series) (*Series, error) {
+	if len(series) == 0 {
+		return nil, fmt.Errorf("no series to consolidate")
+	}
+
+	blocks := make([]FixedResolutionMutableValues, 0, len(series))
+	for _, s := range series {
+		vals, ok := s.vals.(FixedResolutionMutableValues)
+		if !ok {
+			return nil, errors.ErrOnlyFixedResSupported
+		}
+		blocks = append(blocks, vals)
+	}
+
+	sorted := make([]*Series, len(series))
+	copy(sorted, series)
+	sort.Sort(consolidateByStart{series: sorted, vals: blocks})
+
+	var (
+		first    = blocks[0]
+		step     = first.MillisPerStep()
+		numSteps = first.Len()
+	)
+	for i := 1; i < len(blocks); i++ {
+		prev, curr := blocks[i-1], blocks[i]
+		if curr.MillisPerStep() != step {
+			return nil, fmt.Errorf("cannot consolidate series %s: resolution %v does not match %v",
+				sorted[i].name, curr.MillisPerStep(), step)
+		}
+		expected := prev.StartTime().Add(time.Duration(prev.Len()) * step)
+		switch start := curr.StartTime(); {
+		case start.Before(expected):
+			return nil, fmt.Errorf("cannot consolidate series %s: block starting at %v overlaps previous block ending at %v",
+				sorted[i].name, start, expected)
+		case start.After(expected):
+			return nil, fmt.Errorf("cannot consolidate series %s: gap between previous block ending at %v and block starting at %v",
+				sorted[i].name, expected, start)
+		}
+		numSteps += curr.Len()
+	}
+
+	vals := newFixedStepValues(step, numSteps, math.NaN(), first.StartTime())
+	idx := 0
+	for _, block := range blocks {
+		for i := 0; i < block.Len(); i++ {
+			vals.values[idx] = block.ValueAt(i)
+			idx++
+		}
+	}
+
+	return NewSeries(sorted[0].name, vals, sorted[0].Tags), nil
+}
+
+// consolidateByStart sorts series along with their fixed resolution values
+// by start time
+type consolidateByStart struct {
+	series []*Series
+	vals   []FixedResolutionMutableValues
+}
+
+func (c consolidateByStart) Len() int { return len(c.series) }
+
+func (c consolidateByStart) Less(i, j int) bool {
+	return c.vals[i].StartTime().Before(c.vals[j].StartTime())
+}
+
+func (c consolidateByStart) Swap(i, j int) {
+	c.series[i], c.series[j] = c.series[j], c.series[i]
+	c.vals[i], c.vals[j] = c.vals[j], c.vals[i]
+}
+
+// TimePartition is the list of series from evaluating a query over a time
+// range, the values of each series start at the start of the range
+type TimePartition struct {
+	Start  time.Time
+	Series []*Series
+}
+
+// MergeTimePartitions combines the series from each partition into series of
+// numSteps steps from start, such as when a query is evaluated over adjacent
+// time ranges. Series are matched across partitions by name and tags, steps
+// missing from every partition are left as NaN and values beyond numSteps
+// are dropped.
+func MergeTimePartitions(
+	start time.Time,
+	step time.Duration,
+	numSteps int,
+	partitions []TimePartition,
+) []*Series {
+	var (
+		merged []*Series
+		byID   = make(map[string]FixedResolutionMutableValues)
+	)
+	for _, partition := range partitions {
+		offset := int(partition.Start.Sub(start) / step)
+		for _, series := range partition.Series {
+			id := series.Name() + series.Tags.ID()
+			values, ok := byID[id]
+			if !ok {
+				values = NewFixedStepValues(step, numSteps, math.NaN(), start)
+				byID[id] = values
+				merged = append(merged, NewSeries(series.Name(), values, series.Tags))
+			}
+
+			for i := 0; i < series.Len() && offset+i < numSteps; i++ {
+				values.SetValueAt(offset+i, series.ValueAt(i))
+			}
+		}
+	}
+
+	return merged
+}
+
 // SeriesList represents a slice of series pointers
 type SeriesList []*Series
 
@@ -0,0 +1,171 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ts
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/m3db/m3db/src/coordinator/models"
+)
+
+const (
+	jsonPosInf = "+Inf"
+	jsonNegInf = "-Inf"
+)
+
+// seriesJSON is the stable JSON schema for a Series. Fixed resolution series
+// are encoded with a start time, resolution and a dense list of values, all
+// other series are encoded as a list of raw datapoints.
+type seriesJSON struct {
+	Name       string          `json:"name"`
+	Tags       models.Tags     `json:"tags"`
+	StartTime  *time.Time      `json:"startTime,omitempty"`
+	Resolution string          `json:"resolution,omitempty"`
+	Values     []jsonValue     `json:"values,omitempty"`
+	Datapoints []datapointJSON `json:"datapoints,omitempty"`
+}
+
+type datapointJSON struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     jsonValue `json:"value"`
+}
+
+// jsonValue is a float64 that can be represented in JSON. Since JSON has no
+// representation for NaN or infinity, NaN is encoded as null and infinities
+// are encoded as the strings "+Inf" and "-Inf".
+type jsonValue float64
+
+func (v jsonValue) MarshalJSON() ([]byte, error) {
+	f := float64(v)
+	switch {
+	case math.IsNaN(f):
+		return []byte("null"), nil
+	case math.IsInf(f, 1):
+		return json.Marshal(jsonPosInf)
+	case math.IsInf(f, -1):
+		return json.Marshal(jsonNegInf)
+	default:
+		return json.Marshal(f)
+	}
+}
+
+func (v *jsonValue) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*v = jsonValue(math.NaN())
+		return nil
+	}
+
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		switch str {
+		case jsonPosInf:
+			*v = jsonValue(math.Inf(1))
+		case jsonNegInf:
+			*v = jsonValue(math.Inf(-1))
+		default:
+			return fmt.Errorf("invalid series value: %s", str)
+		}
+		return nil
+	}
+
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	*v = jsonValue(f)
+	return nil
+}
+
+// MarshalJSON encodes the series as JSON. NaN values are encoded as null
+// and infinite values are encoded as the strings "+Inf" and "-Inf".
+func (s *Series) MarshalJSON() ([]byte, error) {
+	encoded := seriesJSON{
+		Name: s.name,
+		Tags: s.Tags,
+	}
+
+	switch vals := s.vals.(type) {
+	case nil:
+	case FixedResolutionMutableValues:
+		startTime := vals.StartTime()
+		encoded.StartTime = &startTime
+		encoded.Resolution = vals.Resolution().String()
+		encoded.Values = make([]jsonValue, vals.Len())
+		for i := range encoded.Values {
+			encoded.Values[i] = jsonValue(vals.ValueAt(i))
+		}
+	default:
+		encoded.Datapoints = make([]datapointJSON, vals.Len())
+		for i := range encoded.Datapoints {
+			dp := vals.DatapointAt(i)
+			encoded.Datapoints[i] = datapointJSON{
+				Timestamp: dp.Timestamp,
+				Value:     jsonValue(dp.Value),
+			}
+		}
+	}
+
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes a series previously encoded with MarshalJSON.
+func (s *Series) UnmarshalJSON(data []byte) error {
+	var decoded seriesJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	if decoded.StartTime != nil && len(decoded.Datapoints) > 0 {
+		return fmt.Errorf("series %s has both fixed resolution values and datapoints", decoded.Name)
+	}
+
+	if decoded.StartTime == nil && len(decoded.Values) > 0 {
+		return fmt.Errorf("series %s has fixed resolution values but no start time", decoded.Name)
+	}
+
+	var vals Values
+	if decoded.StartTime != nil {
+		resolution, err := time.ParseDuration(decoded.Resolution)
+		if err != nil {
+			return fmt.Errorf("invalid resolution for series %s: %v", decoded.Name, err)
+		}
+
+		fixed := newFixedStepValues(resolution, len(decoded.Values), math.NaN(), *decoded.StartTime)
+		for i, v := range decoded.Values {
+			fixed.values[i] = float64(v)
+		}
+		vals = fixed
+	} else {
+		datapoints := make(Datapoints, len(decoded.Datapoints))
+		for i, dp := range decoded.Datapoints {
+			datapoints[i] = Datapoint{Timestamp: dp.Timestamp, Value: float64(dp.Value)}
+		}
+		vals = datapoints
+	}
+
+	s.name = decoded.Name
+	s.vals = vals
+	s.Tags = decoded.Tags
+	return nil
+}
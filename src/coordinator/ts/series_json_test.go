@@ -0,0 +1,104 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package ts
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/m3db/m3db/src/coordinator/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesJSONFixedResolutionRoundTrip(t *testing.T) {
+	start := time.Unix(1500000000, 0).UTC()
+	values := NewFixedStepValues(10*time.Second, 4, 0, start)
+	values.SetValueAt(0, 1.5)
+	values.SetValueAt(1, math.NaN())
+	values.SetValueAt(2, math.Inf(1))
+	values.SetValueAt(3, math.Inf(-1))
+	series := NewSeries("foo", values, models.Tags{"biz": "baz"})
+
+	data, err := json.Marshal(series)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"name": "foo",
+		"tags": {"biz": "baz"},
+		"startTime": "2017-07-14T02:40:00Z",
+		"resolution": "10s",
+		"values": [1.5, null, "+Inf", "-Inf"]
+	}`, string(data))
+
+	var decoded Series
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "foo", decoded.Name())
+	assert.Equal(t, models.Tags{"biz": "baz"}, decoded.Tags)
+
+	fixed, ok := decoded.Values().(FixedResolutionMutableValues)
+	require.True(t, ok)
+	assert.True(t, start.Equal(fixed.StartTime()))
+	assert.Equal(t, 10*time.Second, fixed.Resolution())
+	require.Equal(t, 4, fixed.Len())
+	assert.Equal(t, 1.5, fixed.ValueAt(0))
+	assert.True(t, math.IsNaN(fixed.ValueAt(1)))
+	assert.True(t, math.IsInf(fixed.ValueAt(2), 1))
+	assert.True(t, math.IsInf(fixed.ValueAt(3), -1))
+}
+
+func TestSeriesJSONDatapointsRoundTrip(t *testing.T) {
+	start := time.Unix(1500000000, 0).UTC()
+	datapoints := Datapoints{
+		{Timestamp: start, Value: 1},
+		{Timestamp: start.Add(time.Second), Value: math.NaN()},
+	}
+	series := NewSeries("bar", datapoints, models.Tags{})
+
+	data, err := json.Marshal(series)
+	require.NoError(t, err)
+
+	var decoded Series
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "bar", decoded.Name())
+
+	decodedDatapoints, ok := decoded.Values().(Datapoints)
+	require.True(t, ok)
+	require.Len(t, decodedDatapoints, 2)
+	assert.True(t, start.Equal(decodedDatapoints[0].Timestamp))
+	assert.Equal(t, 1.0, decodedDatapoints[0].Value)
+	assert.True(t, start.Add(time.Second).Equal(decodedDatapoints[1].Timestamp))
+	assert.True(t, math.IsNaN(decodedDatapoints[1].Value))
+}
+
+func TestSeriesJSONInvalidValue(t *testing.T) {
+	var decoded Series
+	err := json.Unmarshal([]byte(`{"name":"foo","startTime":"2017-07-14T02:40:00Z","resolution":"10s","values":["bad"]}`), &decoded)
+	assert.Error(t, err)
+}
+
+func TestSeriesJSONValuesWithoutStartTime(t *testing.T) {
+	var decoded Series
+	err := json.Unmarshal([]byte(`{"name":"foo","resolution":"10s","values":[1,2]}`), &decoded)
+	assert.Error(t, err)
+}
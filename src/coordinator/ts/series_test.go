@@ -21,12 +21,14 @@
 package ts
 
 import (
+	"math"
 	"testing"
 	"time"
 
 	"github.com/m3db/m3db/src/coordinator/models"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCreateNewSeries(t *testing.T) {
@@ -38,3 +40,216 @@ func TestCreateNewSeries(t *testing.T) {
 	assert.Equal(t, 10000, series.Len())
 	assert.Equal(t, 1.0, series.Values().ValueAt(0))
 }
+
+func TestNewSeriesChecked(t *testing.T) {
+	values := NewFixedStepValues(time.Minute, 5, 1, time.Now())
+
+	series, err := NewSeriesChecked("metrics", values,
+		models.Tags{models.MetricName: "metrics", "foo_1": "bar", "_biz": "ünïcode"})
+	require.NoError(t, err)
+	assert.Equal(t, "metrics", series.Name())
+
+	for _, test := range []struct {
+		name string
+		tags models.Tags
+	}{
+		{name: ""},
+		{name: "bad\xff"},
+		{name: "metrics", tags: models.Tags{"": "bar"}},
+		{name: "metrics", tags: models.Tags{"1foo": "bar"}},
+		{name: "metrics", tags: models.Tags{"foo-bar": "bar"}},
+		{name: "metrics", tags: models.Tags{"__reserved": "bar"}},
+		{name: "metrics", tags: models.Tags{"foo": "bad\xff"}},
+	} {
+		_, err := NewSeriesChecked(test.name, values, test.tags)
+		assert.Error(t, err, "name %q tags %v", test.name, test.tags)
+	}
+}
+
+func TestNonNullRange(t *testing.T) {
+	start := time.Unix(0, 0)
+	values := NewFixedStepValues(time.Minute, 5, math.NaN(), start)
+	series := NewSeries("metrics", values, nil)
+
+	_, _, ok := series.NonNullRange()
+	assert.False(t, ok)
+
+	values.SetValueAt(1, 1)
+	first, last, ok := series.NonNullRange()
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(time.Minute), first)
+	assert.Equal(t, start.Add(time.Minute), last)
+
+	values.SetValueAt(3, 2)
+	first, last, ok = series.NonNullRange()
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(time.Minute), first)
+	assert.Equal(t, start.Add(3*time.Minute), last)
+
+	datapoints := Datapoints{
+		{Timestamp: start, Value: math.NaN()},
+		{Timestamp: start.Add(time.Second), Value: 1},
+		{Timestamp: start.Add(2 * time.Second), Value: math.NaN()},
+	}
+	first, last, ok = NewSeries("raw", datapoints, nil).NonNullRange()
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(time.Second), first)
+	assert.Equal(t, start.Add(time.Second), last)
+
+	_, _, ok = NewSeries("empty", Datapoints{}, nil).NonNullRange()
+	assert.False(t, ok)
+}
+
+func TestNonNullValues(t *testing.T) {
+	start := time.Unix(0, 0)
+
+	allNaN := NewSeries("nan", NewFixedStepValues(time.Minute, 3, math.NaN(), start), nil)
+	assert.Equal(t, 0, allNaN.CountNonNull())
+	assert.Empty(t, allNaN.NonNullValues())
+	assert.Empty(t, allNaN.DatapointsNonNull())
+
+	noNaN := NewSeries("values", NewFixedStepValues(time.Minute, 3, 1, start), nil)
+	assert.Equal(t, 3, noNaN.CountNonNull())
+	assert.Equal(t, []float64{1, 1, 1}, noNaN.NonNullValues())
+	assert.Equal(t, Datapoints{
+		{Timestamp: start, Value: 1},
+		{Timestamp: start.Add(time.Minute), Value: 1},
+		{Timestamp: start.Add(2 * time.Minute), Value: 1},
+	}, noNaN.DatapointsNonNull())
+
+	datapoints := Datapoints{
+		{Timestamp: start, Value: math.NaN()},
+		{Timestamp: start.Add(time.Second), Value: 1},
+		{Timestamp: start.Add(2 * time.Second), Value: math.NaN()},
+		{Timestamp: start.Add(3 * time.Second), Value: 2},
+	}
+	mixed := NewSeries("raw", datapoints, nil)
+	assert.Equal(t, 2, mixed.CountNonNull())
+	assert.Equal(t, []float64{1, 2}, mixed.NonNullValues())
+	assert.Equal(t, Datapoints{datapoints[1], datapoints[3]}, mixed.DatapointsNonNull())
+
+	empty := NewSeries("empty", Datapoints{}, nil)
+	assert.Equal(t, 0, empty.CountNonNull())
+	assert.Empty(t, empty.NonNullValues())
+	assert.Empty(t, empty.DatapointsNonNull())
+}
+
+func TestSeriesStepAccessors(t *testing.T) {
+	start := time.Unix(0, 0)
+	values := NewFixedStepValues(time.Minute, 3, 0, start)
+	for i := 0; i < 3; i++ {
+		values.SetValueAt(i, float64(i*10))
+	}
+	series := NewSeries("metrics", values, nil)
+
+	assert.Equal(t, 20.0, series.ValueAt(2))
+	ts, v := series.DatapointAt(1)
+	assert.Equal(t, start.Add(time.Minute), ts)
+	assert.Equal(t, 10.0, v)
+	assert.Equal(t, start.Add(2*time.Minute), series.StartTimeForStep(2, 60000))
+
+	assert.Panics(t, func() { series.ValueAt(3) })
+	assert.Panics(t, func() { series.DatapointAt(-1) })
+	assert.Panics(t, func() { series.StartTimeForStep(3, 60000) })
+	assert.Panics(t, func() { NewSeries("empty", Datapoints{}, nil).StartTimeForStep(0, 1000) })
+}
+
+func TestSeriesTagMutation(t *testing.T) {
+	tags := models.Tags{"foo": "bar"}
+	values := NewFixedStepValues(time.Minute, 3, 1, time.Unix(0, 0))
+	series := NewSeries("metrics", values, tags)
+
+	replaced := series.WithTags(models.Tags{"biz": "baz"})
+	assert.Equal(t, models.Tags{"biz": "baz"}, replaced.Tags)
+	assert.Equal(t, "metrics", replaced.Name())
+	assert.Equal(t, values, replaced.Values())
+
+	added := series.AddTag("biz", "baz")
+	assert.Equal(t, models.Tags{"foo": "bar", "biz": "baz"}, added.Tags)
+	overridden := added.AddTag("foo", "qux")
+	assert.Equal(t, models.Tags{"foo": "qux", "biz": "baz"}, overridden.Tags)
+
+	// The originals are left untouched
+	assert.Equal(t, models.Tags{"foo": "bar"}, series.Tags)
+	assert.Equal(t, models.Tags{"foo": "bar", "biz": "baz"}, added.Tags)
+}
+
+func TestConsolidateSeries(t *testing.T) {
+	start := time.Unix(1000, 0)
+	step := 10 * time.Second
+	tags := models.Tags{"foo": "bar"}
+	newBlock := func(offset, n int) *Series {
+		vals := NewFixedStepValues(step, n, 0, start.Add(time.Duration(offset)*step))
+		for i := 0; i < n; i++ {
+			vals.SetValueAt(i, float64(offset+i))
+		}
+		return NewSeries("metric", vals, tags)
+	}
+
+	series, err := ConsolidateSeries([]*Series{newBlock(5, 3), newBlock(0, 2), newBlock(2, 3)})
+	require.NoError(t, err)
+	assert.Equal(t, "metric", series.Name())
+	assert.Equal(t, tags, series.Tags)
+	require.Equal(t, 8, series.Len())
+	vals, ok := series.Values().(FixedResolutionMutableValues)
+	require.True(t, ok)
+	assert.Equal(t, step, vals.MillisPerStep())
+	assert.True(t, vals.StartTime().Equal(start))
+	for i := 0; i < series.Len(); i++ {
+		assert.Equal(t, float64(i), series.ValueAt(i))
+	}
+
+	single, err := ConsolidateSeries([]*Series{newBlock(0, 2)})
+	require.NoError(t, err)
+	assert.Equal(t, 2, single.Len())
+
+	_, err = ConsolidateSeries(nil)
+	assert.Error(t, err)
+
+	_, err = ConsolidateSeries([]*Series{newBlock(0, 2), newBlock(3, 2)})
+	assert.Error(t, err, "gap")
+
+	_, err = ConsolidateSeries([]*Series{newBlock(0, 3), newBlock(2, 2)})
+	assert.Error(t, err, "overlap")
+
+	other := NewSeries("metric", NewFixedStepValues(step*2, 2, 0, start.Add(2*step)), tags)
+	_, err = ConsolidateSeries([]*Series{newBlock(0, 2), other})
+	assert.Error(t, err, "resolution mismatch")
+
+	raw := NewSeries("metric", Datapoints{{Timestamp: start, Value: 1}}, tags)
+	_, err = ConsolidateSeries([]*Series{raw})
+	assert.Error(t, err)
+}
+
+func TestMergeTimePartitions(t *testing.T) {
+	start := time.Unix(0, 0)
+	newSeries := func(name string, start time.Time, values ...float64) *Series {
+		vals := NewFixedStepValues(time.Minute, len(values), math.NaN(), start)
+		for i, v := range values {
+			vals.SetValueAt(i, v)
+		}
+		return NewSeries(name, vals, models.Tags{"name": name})
+	}
+
+	second := start.Add(2 * time.Minute)
+	merged := MergeTimePartitions(start, time.Minute, 4, []TimePartition{
+		{Start: start, Series: []*Series{newSeries("a", start, 1, 2)}},
+		{Start: second, Series: []*Series{newSeries("b", second, 30, 40), newSeries("a", second, 3, 4, 5)}},
+	})
+	require.Len(t, merged, 2)
+
+	assert.Equal(t, "a", merged[0].Name())
+	assert.Equal(t, "b", merged[1].Name())
+	assert.Equal(t, models.Tags{"name": "b"}, merged[1].Tags)
+
+	require.Equal(t, 4, merged[0].Len())
+	for i, expected := range []float64{1, 2, 3, 4} {
+		assert.Equal(t, expected, merged[0].ValueAt(i))
+	}
+	assert.True(t, math.IsNaN(merged[1].ValueAt(0)))
+	assert.True(t, math.IsNaN(merged[1].ValueAt(1)))
+	assert.Equal(t, 30.0, merged[1].ValueAt(2))
+	assert.Equal(t, 40.0, merged[1].ValueAt(3))
+
+	assert.Empty(t, MergeTimePartitions(start, time.Minute, 4, []TimePartition{{Start: start}}))
+}
@@ -116,6 +116,18 @@ func (s *mockStorage) Type() storage.Type {
 	return storage.Type(0)
 }
 
+func (s *mockStorage) Resolution() time.Duration {
+	return 0
+}
+
+func (s *mockStorage) Retention() time.Duration {
+	return 0
+}
+
+func (s *mockStorage) Selector() models.Tags {
+	return nil
+}
+
 func (s *mockStorage) FetchBlocks(
 	ctx context.Context, query *storage.FetchQuery, options *storage.FetchOptions) (block.Result, error) {
 	return block.Result{}, fmt.Errorf("not implemented")
@@ -324,6 +336,18 @@ func (s *errStorage) Type() storage.Type {
 	return storage.Type(-1)
 }
 
+func (s *errStorage) Resolution() time.Duration {
+	return 0
+}
+
+func (s *errStorage) Retention() time.Duration {
+	return 0
+}
+
+func (s *errStorage) Selector() models.Tags {
+	return nil
+}
+
 func (s *errStorage) Close() error {
 	return nil
 }
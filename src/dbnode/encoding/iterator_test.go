@@ -200,8 +200,9 @@ type testNoopReader struct {
 	n int // return for "n", also required so that each struct construction has its address
 }
 
-func (r *testNoopReader) Read(p []byte) (int, error)        { return r.n, nil }
-func (r *testNoopReader) Segment() (ts.Segment, error)      { return ts.Segment{}, nil }
-func (r *testNoopReader) Reset(ts.Segment)                  {}
-func (r *testNoopReader) Finalize()                         {}
-func (r *testNoopReader) Clone() (xio.SegmentReader, error) { return r, nil }
+func (r *testNoopReader) Read(p []byte) (int, error)          { return r.n, nil }
+func (r *testNoopReader) Segment() (ts.Segment, error)        { return ts.Segment{}, nil }
+func (r *testNoopReader) Reset(ts.Segment)                    {}
+func (r *testNoopReader) ResetAndFinalizePrevious(ts.Segment) {}
+func (r *testNoopReader) Finalize()                           {}
+func (r *testNoopReader) Clone() (xio.SegmentReader, error)   { return r, nil }
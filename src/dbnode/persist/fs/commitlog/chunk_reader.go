@@ -22,7 +22,7 @@ package commitlog
 
 import (
 	"bufio"
-	"os"
+	"io"
 
 	"github.com/m3db/m3db/src/dbnode/digest"
 )
@@ -37,10 +37,11 @@ const (
 )
 
 type chunkReader struct {
-	fd        *os.File
+	fd        io.ReadCloser
 	buffer    *bufio.Reader
 	remaining int
 	charBuff  []byte
+	bytesRead int64
 }
 
 func newChunkReader(bufferLen int) *chunkReader {
@@ -50,10 +51,11 @@ func newChunkReader(bufferLen int) *chunkReader {
 	}
 }
 
-func (r *chunkReader) reset(fd *os.File) {
+func (r *chunkReader) reset(fd io.ReadCloser) {
 	r.fd = fd
 	r.buffer.Reset(fd)
 	r.remaining = 0
+	r.bytesRead = 0
 }
 
 func (r *chunkReader) readHeader() error {
@@ -79,6 +81,7 @@ func (r *chunkReader) readHeader() error {
 	if _, err := r.buffer.Discard(chunkHeaderLen); err != nil {
 		return err
 	}
+	r.bytesRead += chunkHeaderLen
 
 	// Verify data checksum
 	data, err := r.buffer.Peek(int(size))
@@ -105,6 +108,7 @@ func (r *chunkReader) Read(p []byte) (int, error) {
 		if r.remaining > 0 {
 			n, err := r.buffer.Read(p[:r.remaining])
 			r.remaining -= n
+			r.bytesRead += int64(n)
 			read += n
 			if err != nil {
 				return read, err
@@ -127,6 +131,7 @@ func (r *chunkReader) Read(p []byte) (int, error) {
 
 	n, err := r.buffer.Read(p)
 	r.remaining -= n
+	r.bytesRead += int64(n)
 	read += n
 	return read, err
 }
@@ -21,7 +21,9 @@
 package commitlog
 
 import (
+	stdcontext "context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -39,11 +41,45 @@ var (
 	// when the queue is full
 	ErrCommitLogQueueFull = errors.New("commit log queue is full")
 
-	errCommitLogClosed = errors.New("commit log is closed")
+	// ErrCommitLogClosing is raised when trying to write to the commit log
+	// after it has been quiesced in preparation for closing
+	ErrCommitLogClosing = errors.New("commit log is closing")
+
+	// ErrCommitLogClosed is raised when trying to use, reopen or close the
+	// commit log after it has been closed
+	ErrCommitLogClosed = errors.New("commit log is closed")
+
+	// ErrCommitLogAlreadyOpen is raised when trying to open the commit log
+	// when it is already open
+	ErrCommitLogAlreadyOpen = errors.New("commit log is already open")
+
+	// ErrCommitLogNotOpen is raised when trying to quiesce, sync or close
+	// the commit log before it has been successfully opened
+	ErrCommitLogNotOpen = errors.New("commit log is not open")
 
 	timeZero = time.Time{}
 )
 
+const (
+	// rotationQueueSize is the number of rotations that can be queued for
+	// the rotation callback before the write loop waits for it
+	rotationQueueSize = 16
+)
+
+// TimestampOutsideWindowError is raised when trying to write a datapoint with
+// a timestamp outside the valid time window, an unbounded side of the window
+// is the zero time
+type TimestampOutsideWindowError struct {
+	Timestamp time.Time
+	Earliest  time.Time
+	Latest    time.Time
+}
+
+func (e TimestampOutsideWindowError) Error() string {
+	return fmt.Sprintf("commit log write timestamp %v is outside the valid time window [%v, %v]",
+		e.Timestamp, e.Earliest, e.Latest)
+}
+
 type newCommitLogWriterFn func(
 	flushFn flushFn,
 	opts Options,
@@ -80,21 +116,47 @@ type commitLog struct {
 	lastFlushAt     time.Time
 	pendingFlushFns []completionFn
 
+	fileMutex  sync.RWMutex
+	activeFile string
+
+	// rotations are delivered to the rotation callback in order by a single
+	// goroutine, which closes rotationsDone once rotations is closed
+	rotations     chan rotation
+	rotationsDone chan struct{}
+
+	// hotSeries is nil unless hot series sampling is enabled
+	hotSeries *hotSeries
+
 	writerExpireAt time.Time
+	opened         bool
+	closing        bool
 	closed         bool
 	closeErr       chan error
 
+	// lastSequenceNumber is only accessed by "Open()" and "write()"
+	lastSequenceNumber uint64
+
 	metrics commitLogMetrics
 }
 
+type rotation struct {
+	oldPath string
+	newPath string
+}
+
 type commitLogMetrics struct {
-	queued      tally.Gauge
-	success     tally.Counter
-	errors      tally.Counter
-	openErrors  tally.Counter
-	closeErrors tally.Counter
-	flushErrors tally.Counter
-	flushDone   tally.Counter
+	queued       tally.Gauge
+	enqueued     tally.Counter
+	blockTimeout tally.Counter
+	success      tally.Counter
+	errors       tally.Counter
+	openErrors   tally.Counter
+	closeErrors  tally.Counter
+	flushErrors  tally.Counter
+	flushDone    tally.Counter
+	fsyncErrors  tally.Counter
+	fsyncTime    tally.Timer
+	windowReject tally.Counter
 }
 
 type valueType int
@@ -103,6 +165,7 @@ type valueType int
 const (
 	writeValueType valueType = iota
 	flushValueType
+	syncValueType
 )
 
 type commitLogWrite struct {
@@ -131,17 +194,28 @@ func NewCommitLog(opts Options) (CommitLog, error) {
 		newCommitLogWriterFn: newCommitLogWriter,
 		writes:               make(chan commitLogWrite, opts.BacklogQueueSize()),
 		closeErr:             make(chan error),
+		rotations:            make(chan rotation, rotationQueueSize),
+		rotationsDone:        make(chan struct{}),
 		metrics: commitLogMetrics{
-			queued:      scope.Gauge("writes.queued"),
-			success:     scope.Counter("writes.success"),
-			errors:      scope.Counter("writes.errors"),
-			openErrors:  scope.Counter("writes.open-errors"),
-			closeErrors: scope.Counter("writes.close-errors"),
-			flushErrors: scope.Counter("writes.flush-errors"),
-			flushDone:   scope.Counter("writes.flush-done"),
+			queued:       scope.Gauge("writes.queued"),
+			enqueued:     scope.Counter("writes.enqueued"),
+			blockTimeout: scope.Counter("writes.queue-full-timeouts"),
+			success:      scope.Counter("writes.success"),
+			errors:       scope.Counter("writes.errors"),
+			openErrors:   scope.Counter("writes.open-errors"),
+			closeErrors:  scope.Counter("writes.close-errors"),
+			flushErrors:  scope.Counter("writes.flush-errors"),
+			flushDone:    scope.Counter("writes.flush-done"),
+			fsyncErrors:  scope.Counter("writes.fsync-errors"),
+			fsyncTime:    scope.Timer("writes.fsync-latency"),
+			windowReject: scope.Counter("writes.time-window-rejected"),
 		},
 	}
 
+	if rate := opts.HotSeriesSampling(); rate > 0 {
+		commitLog.hotSeries = newHotSeries(rate, hotSeriesCapacity)
+	}
+
 	switch opts.Strategy() {
 	case StrategyWriteWait:
 		commitLog.writeFn = commitLog.writeWait
@@ -152,7 +226,37 @@ func NewCommitLog(opts Options) (CommitLog, error) {
 	return commitLog, nil
 }
 
-func (l *commitLog) Open() error {
+func (l *commitLog) Open() (err error) {
+	l.Lock()
+	if l.closed {
+		l.Unlock()
+		return ErrCommitLogClosed
+	}
+	if l.opened {
+		l.Unlock()
+		return ErrCommitLogAlreadyOpen
+	}
+	l.opened = true
+	l.Unlock()
+
+	defer func() {
+		if err != nil {
+			// Allow retrying to open after a failure
+			l.Lock()
+			l.opened = false
+			l.Unlock()
+		}
+	}()
+
+	if l.opts.SequenceNumbersEnabled() {
+		// Resume numbering after the last entry written before a restart
+		seq, err := lastSequenceNumber(l.opts)
+		if err != nil {
+			return err
+		}
+		l.lastSequenceNumber = seq
+	}
+
 	// Open the buffered commit log writer
 	if err := l.openWriter(l.nowFn()); err != nil {
 		return err
@@ -172,6 +276,7 @@ func (l *commitLog) Open() error {
 	}
 
 	// Asynchronously write
+	go l.notifyRotations()
 	go l.write()
 
 	if flushInterval := l.opts.FlushInterval(); flushInterval > 0 {
@@ -179,9 +284,38 @@ func (l *commitLog) Open() error {
 		go l.flushEvery(flushInterval)
 	}
 
+	if fsyncInterval := l.opts.FsyncInterval(); fsyncInterval > 0 {
+		// Continually fsync the active commit log file at given interval if set
+		go l.fsyncEvery(fsyncInterval)
+	}
+
 	return nil
 }
 
+func (l *commitLog) fsyncEvery(interval time.Duration) {
+	// Periodically fsync the active commit log file so that writes are
+	// durable without waiting for the file to be rotated or closed
+	onSync := func(err error) {
+		if err != nil {
+			l.log.Errorf("failed to fsync commit log: %v", err)
+		}
+	}
+
+	for {
+		time.Sleep(interval)
+
+		// Request an fsync
+		l.RLock()
+		if l.closed {
+			l.RUnlock()
+			return
+		}
+
+		l.writes <- commitLogWrite{valueType: syncValueType, completionFn: onSync}
+		l.RUnlock()
+	}
+}
+
 func (l *commitLog) flushEvery(interval time.Duration) {
 	// Periodically flush the underlying commit log writer to cover
 	// the case when writes stall for a considerable time
@@ -223,6 +357,18 @@ func (l *commitLog) flushEvery(interval time.Duration) {
 
 func (l *commitLog) write() {
 	for write := range l.writes {
+		if write.valueType == syncValueType {
+			// Flushing will complete any pending acks for earlier writes
+			start := l.nowFn()
+			err := l.writer.Sync()
+			l.metrics.fsyncTime.Record(l.nowFn().Sub(start))
+			if err != nil {
+				l.metrics.fsyncErrors.Inc(1)
+			}
+			write.completionFn(err)
+			continue
+		}
+
 		// For writes requiring acks add to pending acks
 		if write.completionFn != nil {
 			l.pendingFlushFns = append(l.pendingFlushFns, write.completionFn)
@@ -248,8 +394,16 @@ func (l *commitLog) write() {
 			}
 		}
 
-		err := l.writer.Write(write.series,
-			write.datapoint, write.unit, write.annotation)
+		var seq uint64
+		if l.opts.SequenceNumbersEnabled() {
+			// Advance even if the write fails since the entry may have been
+			// partially buffered, numbers may have gaps but are never reused
+			l.lastSequenceNumber++
+			seq = l.lastSequenceNumber
+		}
+
+		err := l.writer.Write(write.series, write.datapoint, write.unit,
+			write.annotation, seq, l.opts.AnnotationVersion())
 
 		if err != nil {
 			l.metrics.errors.Inc(1)
@@ -262,6 +416,15 @@ func (l *commitLog) write() {
 			continue
 		}
 		l.metrics.success.Inc(1)
+		if l.hotSeries != nil {
+			l.hotSeries.record(write.series)
+		}
+
+		if l.opts.FlushInterval() == 0 {
+			// Without a flush interval every write is flushed as soon as it
+			// is written, completing any pending acks
+			l.writer.Flush()
+		}
 	}
 
 	l.Lock()
@@ -269,6 +432,7 @@ func (l *commitLog) write() {
 
 	writer := l.writer
 	l.writer = nil
+	l.setActiveFile("")
 	l.closeErr <- writer.Close()
 }
 
@@ -327,10 +491,44 @@ func (l *commitLog) openWriter(now time.Time) error {
 	}
 
 	l.writerExpireAt = start.Add(blockSize)
+	l.setActiveFile(l.writer.FilePath())
 
 	return nil
 }
 
+// setActiveFile records the path of the file being written to, queueing the
+// rotation for the rotation callback if the commit log has rotated from
+// another file so that the callback does not run on the write loop.
+func (l *commitLog) setActiveFile(filePath string) {
+	l.fileMutex.Lock()
+	prevFilePath := l.activeFile
+	l.activeFile = filePath
+	l.fileMutex.Unlock()
+
+	if l.opts.RotationCallback() == nil || prevFilePath == "" || filePath == "" {
+		return
+	}
+	l.rotations <- rotation{oldPath: prevFilePath, newPath: filePath}
+}
+
+// notifyRotations invokes the rotation callback with each rotation in the
+// order they happened until the rotations channel is closed.
+func (l *commitLog) notifyRotations() {
+	defer close(l.rotationsDone)
+
+	fn := l.opts.RotationCallback()
+	for r := range l.rotations {
+		fn(r.oldPath, r.newPath)
+	}
+}
+
+func (l *commitLog) ActiveFile() string {
+	l.fileMutex.RLock()
+	filePath := l.activeFile
+	l.fileMutex.RUnlock()
+	return filePath
+}
+
 func (l *commitLog) Write(
 	ctx context.Context,
 	series Series,
@@ -338,9 +536,115 @@ func (l *commitLog) Write(
 	unit xtime.Unit,
 	annotation ts.Annotation,
 ) error {
+	if err := l.checkTimestamp(datapoint.Timestamp); err != nil {
+		return err
+	}
 	return l.writeFn(ctx, series, datapoint, unit, annotation)
 }
 
+// checkTimestamp returns a TimestampOutsideWindowError if the timestamp is
+// outside the valid time window. Timestamps are never rewritten since the
+// commit log must hold the same datapoint as the series it is written to.
+func (l *commitLog) checkTimestamp(timestamp time.Time) error {
+	past, future := l.opts.ValidTimeWindow()
+	if past == 0 && future == 0 {
+		return nil
+	}
+
+	var (
+		now              = l.nowFn()
+		earliest, latest time.Time
+	)
+	if past > 0 {
+		earliest = now.Add(-past)
+	}
+	if future > 0 {
+		latest = now.Add(future)
+	}
+	if (past > 0 && timestamp.Before(earliest)) ||
+		(future > 0 && timestamp.After(latest)) {
+		l.metrics.windowReject.Inc(1)
+		return TimestampOutsideWindowError{
+			Timestamp: timestamp,
+			Earliest:  earliest,
+			Latest:    latest,
+		}
+	}
+	return nil
+}
+
+func (l *commitLog) WriteBatch(
+	ctx context.Context,
+	writes []BatchWrite,
+) error {
+	var (
+		wait    = l.opts.Strategy() == StrategyWriteWait
+		results = make([]error, len(writes))
+		wg      sync.WaitGroup
+	)
+
+	l.RLock()
+	if l.closed {
+		l.RUnlock()
+		return ErrCommitLogClosed
+	}
+	if l.closing {
+		l.RUnlock()
+		return ErrCommitLogClosing
+	}
+
+	for i := range writes {
+		if err := l.checkTimestamp(writes[i].Datapoint.Timestamp); err != nil {
+			results[i] = err
+			continue
+		}
+
+		write := commitLogWrite{
+			series:     writes[i].Series,
+			datapoint:  writes[i].Datapoint,
+			unit:       writes[i].Unit,
+			annotation: writes[i].Annotation,
+		}
+		if wait {
+			i := i
+			write.completionFn = func(err error) {
+				results[i] = err
+				wg.Done()
+			}
+			wg.Add(1)
+		}
+
+		if !l.enqueue(write) {
+			if wait {
+				wg.Done()
+			}
+			results[i] = ErrCommitLogQueueFull
+		}
+	}
+	l.RUnlock()
+
+	wg.Wait()
+
+	var errs BatchWriteErrors
+	for i, err := range results {
+		if err != nil {
+			errs = append(errs, BatchWriteError{Index: i, Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (e BatchWriteErrors) Error() string {
+	if len(e) == 0 {
+		return "no commit log batch writes failed"
+	}
+	return fmt.Sprintf("%d commit log batch writes failed, first at index %d: %v",
+		len(e), e[0].Index, e[0].Err)
+}
+
 func (l *commitLog) writeWait(
 	ctx context.Context,
 	series Series,
@@ -351,7 +655,11 @@ func (l *commitLog) writeWait(
 	l.RLock()
 	if l.closed {
 		l.RUnlock()
-		return errCommitLogClosed
+		return ErrCommitLogClosed
+	}
+	if l.closing {
+		l.RUnlock()
+		return ErrCommitLogClosing
 	}
 
 	var (
@@ -374,13 +682,7 @@ func (l *commitLog) writeWait(
 		completionFn: completion,
 	}
 
-	enqueued := false
-
-	select {
-	case l.writes <- write:
-		enqueued = true
-	default:
-	}
+	enqueued := l.enqueue(write)
 
 	l.RUnlock()
 
@@ -403,7 +705,11 @@ func (l *commitLog) writeBehind(
 	l.RLock()
 	if l.closed {
 		l.RUnlock()
-		return errCommitLogClosed
+		return ErrCommitLogClosed
+	}
+	if l.closing {
+		l.RUnlock()
+		return ErrCommitLogClosing
 	}
 
 	write := commitLogWrite{
@@ -413,28 +719,132 @@ func (l *commitLog) writeBehind(
 		annotation: annotation,
 	}
 
-	enqueued := false
+	enqueued := l.enqueue(write)
+
+	l.RUnlock()
 
+	if !enqueued {
+		return ErrCommitLogQueueFull
+	}
+
+	return nil
+}
+
+// enqueue adds the write to the queue, returning false if the queue is full
+// and the backlog queue full policy is to reject writes. The read lock must
+// be held, with the block policy this waits for the write loop to make space
+// for at most the backlog queue full timeout, since the write context cannot
+// be cancelled and the lock blocks Close while waiting.
+func (l *commitLog) enqueue(write commitLogWrite) bool {
 	select {
 	case l.writes <- write:
-		enqueued = true
+		l.metrics.enqueued.Inc(1)
+		return true
 	default:
 	}
 
+	if l.opts.BacklogQueueFullPolicy() != BacklogQueueFullBlock {
+		return false
+	}
+
+	timer := time.NewTimer(l.opts.BacklogQueueFullTimeout())
+	defer timer.Stop()
+
+	select {
+	case l.writes <- write:
+		l.metrics.enqueued.Inc(1)
+		return true
+	case <-timer.C:
+		l.metrics.blockTimeout.Inc(1)
+		return false
+	}
+}
+
+func (l *commitLog) Quiesce(ctx stdcontext.Context) error {
+	l.Lock()
+	if l.closed {
+		l.Unlock()
+		return ErrCommitLogClosed
+	}
+	if !l.opened {
+		// Without the write loop the sync request would never complete
+		l.Unlock()
+		return ErrCommitLogNotOpen
+	}
+	l.closing = true
+	l.Unlock()
+
+	return l.syncEnqueued(ctx)
+}
+
+// syncEnqueued enqueues a sync request and waits for all writes enqueued
+// before it to be written, flushed and synced to disk
+func (l *commitLog) syncEnqueued(ctx stdcontext.Context) error {
+	// All writes enqueued before the sync request are written before it
+	// is processed since the queue is processed in order
+	synced := make(chan error, 1)
+	write := commitLogWrite{
+		valueType: syncValueType,
+		completionFn: func(err error) {
+			synced <- err
+		},
+	}
+
+	l.RLock()
+	if l.closed {
+		l.RUnlock()
+		return ErrCommitLogClosed
+	}
+	select {
+	case l.writes <- write:
+	case <-ctx.Done():
+		l.RUnlock()
+		return ctx.Err()
+	}
 	l.RUnlock()
 
-	if !enqueued {
-		return ErrCommitLogQueueFull
+	select {
+	case err := <-synced:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	return nil
+func (l *commitLog) TopSeries(n int) []SeriesWriteStat {
+	if l.hotSeries == nil {
+		return nil
+	}
+	return l.hotSeries.top(n)
+}
+
+func (l *commitLog) Sync() error {
+	l.RLock()
+	closed, opened, closing := l.closed, l.opened, l.closing
+	l.RUnlock()
+
+	switch {
+	case closed:
+		return ErrCommitLogClosed
+	case !opened:
+		// Without the write loop the sync request would never complete
+		return ErrCommitLogNotOpen
+	case closing:
+		return ErrCommitLogClosing
+	}
+	return l.syncEnqueued(stdcontext.Background())
 }
 
 func (l *commitLog) Close() error {
 	l.Lock()
 	if l.closed {
 		l.Unlock()
-		return nil
+		return ErrCommitLogClosed
+	}
+	if !l.opened {
+		// There is no write loop to close the writer and report the result
+		l.Unlock()
+		return ErrCommitLogNotOpen
 	}
 
 	l.closed = true
@@ -442,5 +852,11 @@ func (l *commitLog) Close() error {
 	l.Unlock()
 
 	// Receive the result of closing the writer from asynchronous writer
-	return <-l.closeErr
+	err := <-l.closeErr
+
+	// The write loop has exited so there are no more rotations, wait for
+	// the callbacks of those already queued
+	close(l.rotations)
+	<-l.rotationsDone
+	return err
 }
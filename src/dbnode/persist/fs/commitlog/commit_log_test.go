@@ -21,10 +21,14 @@
 package commitlog
 
 import (
+	stdcontext "context"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"math/rand"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -38,6 +42,7 @@ import (
 	"github.com/m3db/m3x/context"
 	"github.com/m3db/m3x/ident"
 	"github.com/m3db/m3x/instrument"
+	"github.com/m3db/m3x/pool"
 	xtime "github.com/m3db/m3x/time"
 
 	mclock "github.com/facebookgo/clock"
@@ -156,6 +161,7 @@ type mockCommitLogWriter struct {
 	openFn  func(start time.Time, duration time.Duration) error
 	writeFn func(Series, ts.Datapoint, xtime.Unit, ts.Annotation) error
 	flushFn func() error
+	syncFn  func() error
 	closeFn func() error
 }
 
@@ -170,6 +176,9 @@ func newMockCommitLogWriter() *mockCommitLogWriter {
 		flushFn: func() error {
 			return nil
 		},
+		syncFn: func() error {
+			return nil
+		},
 		closeFn: func() error {
 			return nil
 		},
@@ -185,6 +194,8 @@ func (w *mockCommitLogWriter) Write(
 	datapoint ts.Datapoint,
 	unit xtime.Unit,
 	annotation ts.Annotation,
+	sequenceNumber uint64,
+	annotationVersion uint32,
 ) error {
 	return w.writeFn(series, datapoint, unit, annotation)
 }
@@ -193,6 +204,14 @@ func (w *mockCommitLogWriter) Flush() error {
 	return w.flushFn()
 }
 
+func (w *mockCommitLogWriter) Sync() error {
+	return w.syncFn()
+}
+
+func (w *mockCommitLogWriter) FilePath() string {
+	return ""
+}
+
 func (w *mockCommitLogWriter) Close() error {
 	return w.closeFn()
 }
@@ -368,6 +387,58 @@ func TestCommitLogWrite(t *testing.T) {
 	assertCommitLogWritesByIterating(t, commitLog, writes)
 }
 
+func TestCommitLogZeroFlushIntervalFlushesEveryWrite(t *testing.T) {
+	flushInterval := time.Duration(0)
+	opts, _ := newTestOptions(t, overrides{
+		flushInterval: &flushInterval,
+		strategy:      StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+
+	ctx := context.NewContext()
+	defer ctx.Close()
+
+	var writes []testWrite
+	for i, id := range []string{"foo.bar", "foo.baz"} {
+		write := testWrite{testSeries(uint64(i), id, testTags1, 127), time.Now(), float64(i), xtime.Second, nil, nil}
+		dp := ts.Datapoint{Timestamp: write.t, Value: write.v}
+		require.NoError(t, commitLog.Write(ctx, write.series, dp, write.u, write.a))
+		writes = append(writes, write)
+
+		// Each write is readable as soon as it is acked without closing
+		var read int
+		err := ForEach(IteratorOpts{
+			CommitLogOptions:      opts,
+			FileFilterPredicate:   ReadAllPredicate(),
+			SeriesFilterPredicate: ReadAllSeriesPredicate(),
+		}, func(
+			series Series,
+			datapoint ts.Datapoint,
+			unit xtime.Unit,
+			annotation ts.Annotation,
+		) error {
+			require.True(t, read < len(writes))
+			writes[read].assert(t, series, datapoint, unit, annotation)
+			read++
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, len(writes), read)
+	}
+
+	require.NoError(t, commitLog.Close())
+}
+
+func TestNewCommitLogNegativeFlushInterval(t *testing.T) {
+	opts, _ := newTestOptions(t, overrides{})
+	defer cleanup(t, opts)
+
+	_, err := NewCommitLog(opts.SetFlushInterval(-time.Millisecond))
+	require.Equal(t, errFlushIntervalNonNegative, err)
+}
+
 func TestReadCommitLogMissingMetadata(t *testing.T) {
 	readConc := 4
 	// Make sure we're not leaking goroutines
@@ -467,7 +538,7 @@ func TestCommitLogReaderIsNotReusable(t *testing.T) {
 	require.Equal(t, 1, len(files))
 
 	// Assert commitlog cannot be opened more than once
-	reader := newCommitLogReader(opts, ReadAllSeriesPredicate())
+	reader := newCommitLogReader(opts, combineSeriesPredicates(ReadAllSeriesPredicate(), nil), nil)
 	_, _, _, err = reader.Open(files[0])
 	require.NoError(t, err)
 	reader.Close()
@@ -530,9 +601,9 @@ func TestCommitLogIteratorUsesPredicateFilter(t *testing.T) {
 	require.True(t, len(iterStruct.files) == 2)
 }
 
-func TestCommitLogWriteBehind(t *testing.T) {
+func TestCommitLogIteratorUsesValueFilterPredicate(t *testing.T) {
 	opts, scope := newTestOptions(t, overrides{
-		strategy: StrategyWriteBehind,
+		strategy: StrategyWriteWait,
 	})
 	defer cleanup(t, opts)
 
@@ -540,81 +611,101 @@ func TestCommitLogWriteBehind(t *testing.T) {
 
 	writes := []testWrite{
 		{testSeries(0, "foo.bar", testTags1, 127), time.Now(), 123.456, xtime.Millisecond, nil, nil},
-		{testSeries(1, "foo.baz", testTags2, 150), time.Now(), 456.789, xtime.Millisecond, nil, nil},
-		{testSeries(2, "foo.qux", testTags3, 291), time.Now(), 789.123, xtime.Millisecond, nil, nil},
+		{testSeries(1, "foo.baz", testTags2, 150), time.Now(), 1e10, xtime.Millisecond, nil, nil},
+		{testSeries(2, "foo.qux", testTags3, 291), time.Now(), math.NaN(), xtime.Millisecond, nil, nil},
 	}
 
-	// Call write behind
-	writeCommitLogs(t, scope, commitLog, writes)
+	// Call write sync
+	writeCommitLogs(t, scope, commitLog, writes).Wait()
 
 	// Close the commit log and consequently flush
 	require.NoError(t, commitLog.Close())
 
-	// Assert writes occurred by reading the commit log
-	assertCommitLogWritesByIterating(t, commitLog, writes)
-}
-
-func TestCommitLogWriteErrorOnClosed(t *testing.T) {
-	opts, _ := newTestOptions(t, overrides{})
-	defer cleanup(t, opts)
-
-	commitLog := newTestCommitLog(t, opts)
-	require.NoError(t, commitLog.Close())
-
-	series := testSeries(0, "foo.bar", testTags1, 127)
-	datapoint := ts.Datapoint{Timestamp: time.Now(), Value: 123.456}
+	iterOpts := IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+		ValueFilterPredicate: func(dp ts.Datapoint) bool {
+			return math.IsNaN(dp.Value) || dp.Value > 1e9
+		},
+	}
+	iter, err := NewIterator(iterOpts)
+	require.NoError(t, err)
+	defer iter.Close()
 
-	ctx := context.NewContext()
-	defer ctx.Close()
+	var read []string
+	for iter.Next() {
+		series, _, _, _ := iter.Current()
+		read = append(read, series.ID.String())
+	}
+	require.NoError(t, iter.Err())
 
-	err := commitLog.Write(ctx, series, datapoint, xtime.Millisecond, nil)
-	require.Error(t, err)
-	require.Equal(t, errCommitLogClosed, err)
+	sort.Strings(read)
+	require.Equal(t, []string{"foo.baz", "foo.qux"}, read)
 }
 
-func TestCommitLogWriteErrorOnFull(t *testing.T) {
-	// Set backlog of size one and don't automatically flush
-	backlogQueueSize := 1
-	flushInterval := time.Duration(0)
-	opts, _ := newTestOptions(t, overrides{
-		backlogQueueSize: &backlogQueueSize,
-		flushInterval:    &flushInterval,
-		strategy:         StrategyWriteBehind,
+func TestCommitLogMergedIteratorOrdersAcrossFiles(t *testing.T) {
+	clock := mclock.NewMock()
+	opts, scope := newTestOptions(t, overrides{
+		clock:    clock,
+		strategy: StrategyWriteWait,
 	})
 	defer cleanup(t, opts)
 
 	commitLog := newTestCommitLog(t, opts)
 
-	// Test filling queue
-	var writes []testWrite
-	series := testSeries(0, "foo.bar", testTags1, 127)
-	dp := ts.Datapoint{Timestamp: time.Now(), Value: 123.456}
-	unit := xtime.Millisecond
+	blockSize := opts.BlockSize()
+	alignedStart := clock.Now().Truncate(blockSize)
 
-	ctx := context.NewContext()
-	defer ctx.Close()
+	// Each block's file contains datapoints interleaved in time with the other
+	fileWrites := [][]testWrite{
+		{
+			{testSeries(0, "foo.a", testTags1, 127), alignedStart.Add(1 * time.Minute), 1, xtime.Millisecond, nil, nil},
+			{testSeries(1, "foo.c", testTags2, 150), alignedStart.Add(3 * time.Minute), 3, xtime.Millisecond, nil, nil},
+		},
+		{
+			{testSeries(2, "foo.b", testTags3, 291), alignedStart.Add(2 * time.Minute), 2, xtime.Millisecond, nil, nil},
+			{testSeries(3, "foo.d", testTags1, 127), alignedStart.Add(4 * time.Minute), 4, xtime.Millisecond, nil, nil},
+		},
+	}
 
-	for {
-		if err := commitLog.Write(ctx, series, dp, unit, nil); err != nil {
-			// Ensure queue full error
-			require.Equal(t, ErrCommitLogQueueFull, err)
-			break
-		}
-		writes = append(writes, testWrite{series, dp.Timestamp, dp.Value, unit, nil, nil})
+	for i, writes := range fileWrites {
+		// Set clock to align with the block for this file
+		clock.Add(alignedStart.Add(time.Duration(i) * blockSize).Sub(clock.Now()))
 
-		// Increment timestamp and value for next write
-		dp.Timestamp = dp.Timestamp.Add(time.Second)
-		dp.Value += 1.0
+		wg := writeCommitLogs(t, scope, commitLog, writes)
+
+		// Flush until finished, this is required as timed flusher not active when clock is mocked
+		flushUntilDone(commitLog, wg)
 	}
 
-	// Close and consequently flush
+	// Close the commit log and consequently flush
 	require.NoError(t, commitLog.Close())
 
-	// Assert write flushed by reading the commit log
-	assertCommitLogWritesByIterating(t, commitLog, writes)
+	iter, err := NewMergedIterator(IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+	})
+	require.NoError(t, err)
+	defer iter.Close()
+
+	var (
+		ids    []string
+		values []float64
+	)
+	for iter.Next() {
+		series, dp, _, _ := iter.Current()
+		ids = append(ids, series.ID.String())
+		values = append(values, dp.Value)
+	}
+	require.NoError(t, iter.Err())
+
+	require.Equal(t, []string{"foo.a", "foo.b", "foo.c", "foo.d"}, ids)
+	require.Equal(t, []float64{1, 2, 3, 4}, values)
 }
 
-func TestCommitLogExpiresWriter(t *testing.T) {
+func TestCommitLogIteratorConcurrency(t *testing.T) {
 	clock := mclock.NewMock()
 	opts, scope := newTestOptions(t, overrides{
 		clock:    clock,
@@ -627,131 +718,1508 @@ func TestCommitLogExpiresWriter(t *testing.T) {
 	blockSize := opts.BlockSize()
 	alignedStart := clock.Now().Truncate(blockSize)
 
-	// Writes spaced apart by block size
-	writes := []testWrite{
-		{testSeries(0, "foo.bar", testTags1, 127), alignedStart, 123.456, xtime.Millisecond, nil, nil},
-		{testSeries(1, "foo.baz", testTags2, 150), alignedStart.Add(1 * blockSize), 456.789, xtime.Millisecond, nil, nil},
-		{testSeries(2, "foo.qux", testTags3, 291), alignedStart.Add(2 * blockSize), 789.123, xtime.Millisecond, nil, nil},
-	}
-
-	for _, write := range writes {
-		// Set clock to align with the write
-		clock.Add(write.t.Sub(clock.Now()))
-
-		// Write entry
-		wg := writeCommitLogs(t, scope, commitLog, []testWrite{write})
+	const numFiles = 4
+	for i := 0; i < numFiles; i++ {
+		// Set clock to align with the block for this file
+		blockStart := alignedStart.Add(time.Duration(i) * blockSize)
+		clock.Add(blockStart.Sub(clock.Now()))
+
+		var writes []testWrite
+		for j := 0; j < 10; j++ {
+			idx := uint64(i*10 + j)
+			series := testSeries(idx, fmt.Sprintf("foo.%d", idx), testTags1, 127)
+			writes = append(writes, testWrite{series, blockStart.Add(time.Duration(j) * time.Second),
+				float64(idx), xtime.Millisecond, nil, nil})
+		}
+		wg := writeCommitLogs(t, scope, commitLog, writes)
 
 		// Flush until finished, this is required as timed flusher not active when clock is mocked
 		flushUntilDone(commitLog, wg)
 	}
 
-	// Ensure files present for each block size time window
-	fsopts := opts.FilesystemOptions()
-	files, err := fs.SortedCommitLogFiles(fs.CommitLogsDirPath(fsopts.FilePathPrefix()))
-	require.NoError(t, err)
-	require.True(t, len(files) == len(writes))
-
-	// Close and consequently flush
+	// Close the commit log and consequently flush
 	require.NoError(t, commitLog.Close())
 
-	// Assert write flushed by reading the commit log
-	assertCommitLogWritesByIterating(t, commitLog, writes)
+	readAll := func(concurrency int) ([]string, []float64) {
+		iter, err := NewIterator(IteratorOpts{
+			CommitLogOptions:      opts,
+			FileFilterPredicate:   ReadAllPredicate(),
+			SeriesFilterPredicate: ReadAllSeriesPredicate(),
+			Concurrency:           concurrency,
+		})
+		require.NoError(t, err)
+		defer iter.Close()
+
+		var (
+			ids    []string
+			values []float64
+		)
+		for iter.Next() {
+			series, dp, _, _ := iter.Current()
+			ids = append(ids, series.ID.String())
+			values = append(values, dp.Value)
+		}
+		require.NoError(t, iter.Err())
+		return ids, values
+	}
+
+	expectedIDs, expectedValues := readAll(0)
+	require.Len(t, expectedValues, numFiles*10)
+	for _, concurrency := range []int{2, numFiles, 2 * numFiles} {
+		ids, values := readAll(concurrency)
+		require.Equal(t, expectedIDs, ids, "concurrency %d", concurrency)
+		require.Equal(t, expectedValues, values, "concurrency %d", concurrency)
+	}
+
+	// Closing a parallel iterator before it is exhausted stops the workers
+	iter, err := NewIterator(IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+		Concurrency:           2,
+	})
+	require.NoError(t, err)
+	require.True(t, iter.Next())
+	iter.Close()
+	require.False(t, iter.Next())
 }
 
-func TestCommitLogFailOnWriteError(t *testing.T) {
+func TestCommitLogIteratorGlobalTimestampOrder(t *testing.T) {
 	opts, scope := newTestOptions(t, overrides{
-		strategy: StrategyWriteBehind,
+		strategy: StrategyWriteWait,
 	})
 	defer cleanup(t, opts)
 
-	commitLogI, err := NewCommitLog(opts)
-	require.NoError(t, err)
-	commitLog := commitLogI.(*commitLog)
-	writer := newMockCommitLogWriter()
-
-	writer.writeFn = func(Series, ts.Datapoint, xtime.Unit, ts.Annotation) error {
-		return fmt.Errorf("an error")
-	}
+	commitLog := newTestCommitLog(t, opts)
 
-	var opens int64
-	writer.openFn = func(start time.Time, duration time.Duration) error {
-		if atomic.AddInt64(&opens, 1) >= 2 {
-			return fmt.Errorf("an error")
-		}
-		return nil
+	// Writes across series are out of timestamp order within the file
+	now := time.Now()
+	writes := []testWrite{
+		{testSeries(0, "foo.a", testTags1, 127), now.Add(3 * time.Second), 3, xtime.Millisecond, nil, nil},
+		{testSeries(1, "foo.b", testTags2, 150), now.Add(1 * time.Second), 1, xtime.Millisecond, nil, nil},
+		{testSeries(2, "foo.c", testTags3, 291), now.Add(2 * time.Second), 2, xtime.Millisecond, nil, nil},
+		{testSeries(0, "foo.a", testTags1, 127), now.Add(4 * time.Second), 4, xtime.Millisecond, nil, nil},
 	}
+	writeCommitLogs(t, scope, commitLog, writes).Wait()
+	require.NoError(t, commitLog.Close())
 
-	writer.flushFn = func() error {
-		commitLog.onFlush(nil)
-		return nil
-	}
+	iter, err := NewIterator(IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+		OrderBy:               GlobalTimestampOrder,
+	})
+	require.NoError(t, err)
 
-	commitLog.newCommitLogWriterFn = func(
-		_ flushFn,
-		_ Options,
-	) commitLogWriter {
-		return writer
+	var values []float64
+	for iter.Next() {
+		_, dp, _, _ := iter.Current()
+		values = append(values, dp.Value)
 	}
+	require.NoError(t, iter.Err())
+	iter.Close()
 
-	require.NoError(t, commitLog.Open())
+	require.Equal(t, []float64{1, 2, 3, 4}, values)
 
-	wg := setupCloseOnFail(t, commitLog)
+	// An order buffer too small to reorder the entries fails the iteration
+	iter, err = NewIterator(IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+		OrderBy:               GlobalTimestampOrder,
+		OrderBufferSize:       1,
+	})
+	require.NoError(t, err)
+	defer iter.Close()
 
-	writes := []testWrite{
-		{testSeries(0, "foo.bar", testTags1, 127), time.Now(), 123.456, xtime.Millisecond, nil, nil},
+	for iter.Next() {
 	}
-
-	writeCommitLogs(t, scope, commitLog, writes)
-
-	wg.Wait()
-
-	// Check stats
-	errors, ok := snapshotCounterValue(scope, "commitlog.writes.errors")
-	require.True(t, ok)
-	require.Equal(t, int64(1), errors.Value())
+	require.Equal(t, ErrOrderBufferExceeded, iter.Err())
 }
 
-func TestCommitLogFailOnOpenError(t *testing.T) {
+func TestCommitLogIteratorSkipCorruptTail(t *testing.T) {
+	clock := mclock.NewMock()
 	opts, scope := newTestOptions(t, overrides{
-		strategy: StrategyWriteBehind,
+		clock:    clock,
+		strategy: StrategyWriteWait,
 	})
 	defer cleanup(t, opts)
 
-	commitLogI, err := NewCommitLog(opts)
-	require.NoError(t, err)
-	commitLog := commitLogI.(*commitLog)
-	writer := newMockCommitLogWriter()
+	commitLog := newTestCommitLog(t, opts)
 
-	var opens int64
-	writer.openFn = func(start time.Time, duration time.Duration) error {
-		if atomic.AddInt64(&opens, 1) >= 2 {
-			return fmt.Errorf("an error")
-		}
-		return nil
-	}
+	blockSize := opts.BlockSize()
+	alignedStart := clock.Now().Truncate(blockSize)
 
-	writer.flushFn = func() error {
-		commitLog.onFlush(nil)
-		return nil
+	fileWrites := [][]testWrite{
+		{
+			{testSeries(0, "foo.a", testTags1, 127), alignedStart.Add(1 * time.Minute), 1, xtime.Millisecond, nil, nil},
+			{testSeries(1, "foo.b", testTags2, 150), alignedStart.Add(2 * time.Minute), 2, xtime.Millisecond, nil, nil},
+		},
+		{
+			{testSeries(2, "foo.c", testTags3, 291), alignedStart.Add(blockSize), 3, xtime.Millisecond, nil, nil},
+		},
 	}
 
-	commitLog.newCommitLogWriterFn = func(
-		_ flushFn,
-		_ Options,
-	) commitLogWriter {
-		return writer
+	for i, writes := range fileWrites {
+		// Set clock to align with the block for this file
+		clock.Add(alignedStart.Add(time.Duration(i) * blockSize).Sub(clock.Now()))
+
+		// Flush each write separately so every write is in its own chunk
+		for j := range writes {
+			wg := writeCommitLogs(t, scope, commitLog, writes[j:j+1])
+			flushUntilDone(commitLog, wg)
+		}
 	}
 
-	require.NoError(t, commitLog.Open())
+	// Close the commit log and consequently flush
+	require.NoError(t, commitLog.Close())
 
-	wg := setupCloseOnFail(t, commitLog)
+	// Corrupt the last chunk of the first file as a torn write would
+	files, err := Files(opts)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(files))
 
-	func() {
-		commitLog.RLock()
-		defer commitLog.RUnlock()
-		// Expire the writer so it requires a new open
-		commitLog.writerExpireAt = timeZero
-	}()
+	data, err := ioutil.ReadFile(files[0].FilePath)
+	require.NoError(t, err)
+	data[len(data)-1] ^= 0xff
+	require.NoError(t, ioutil.WriteFile(files[0].FilePath, data, 0644))
+
+	iterOpts := IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+	}
+
+	iter, err := NewIterator(iterOpts)
+	require.NoError(t, err)
+	for iter.Next() {
+	}
+	require.Equal(t, errCommitLogReaderChunkSizeChecksumMismatch, iter.Err())
+	iter.Close()
+
+	iterOpts.ReadMode = ReadModeSkipCorruptTail
+	for _, newIter := range []func(IteratorOpts) (Iterator, error){NewIterator, NewMergedIterator} {
+		iter, err := newIter(iterOpts)
+		require.NoError(t, err)
+
+		var values []float64
+		for iter.Next() {
+			_, dp, _, _ := iter.Current()
+			values = append(values, dp.Value)
+		}
+		require.NoError(t, iter.Err())
+		require.Equal(t, 1, iter.Corrupt())
+		iter.Close()
+
+		require.Equal(t, []float64{1, 3}, values)
+	}
+}
+
+func TestCommitLogIteratorInstrumentation(t *testing.T) {
+	clock := mclock.NewMock()
+	opts, scope := newTestOptions(t, overrides{
+		clock:    clock,
+		strategy: StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+
+	blockSize := opts.BlockSize()
+	alignedStart := clock.Now().Truncate(blockSize)
+
+	fileWrites := [][]testWrite{
+		{
+			{testSeries(0, "foo.a", testTags1, 127), alignedStart.Add(1 * time.Minute), 1, xtime.Millisecond, nil, nil},
+			{testSeries(1, "foo.b", testTags2, 150), alignedStart.Add(2 * time.Minute), 2, xtime.Millisecond, nil, nil},
+		},
+		{
+			{testSeries(2, "foo.c", testTags3, 291), alignedStart.Add(blockSize), 3, xtime.Millisecond, nil, nil},
+		},
+	}
+
+	for i, writes := range fileWrites {
+		// Set clock to align with the block for this file
+		clock.Add(alignedStart.Add(time.Duration(i) * blockSize).Sub(clock.Now()))
+
+		// Flush each write separately so every write is in its own chunk
+		for j := range writes {
+			wg := writeCommitLogs(t, scope, commitLog, writes[j:j+1])
+			flushUntilDone(commitLog, wg)
+		}
+	}
+
+	// Close the commit log and consequently flush
+	require.NoError(t, commitLog.Close())
+
+	// Corrupt the last chunk of the first file so it is skipped
+	files, err := Files(opts)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(files))
+
+	data, err := ioutil.ReadFile(files[0].FilePath)
+	require.NoError(t, err)
+	data[len(data)-1] ^= 0xff
+	require.NoError(t, ioutil.WriteFile(files[0].FilePath, data, 0644))
+
+	var totalBytes int64
+	for _, f := range files {
+		info, err := os.Stat(f.FilePath)
+		require.NoError(t, err)
+		totalBytes += info.Size()
+	}
+
+	readAll := func(iterOpts IteratorOpts) {
+		iter, err := NewIterator(iterOpts)
+		require.NoError(t, err)
+		for iter.Next() {
+		}
+		require.NoError(t, iter.Err())
+		iter.Close()
+	}
+
+	iterOpts := IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+		ReadMode:              ReadModeSkipCorruptTail,
+	}
+
+	// Read metrics are not reported without instrument options
+	readAll(iterOpts)
+	_, ok := snapshotCounterValue(scope, "iterator.reads.records")
+	require.False(t, ok)
+
+	for _, concurrency := range []int{0, 2} {
+		readScope := tally.NewTestScope("", nil)
+		iterOpts.InstrumentOptions = instrument.NewOptions().SetMetricsScope(readScope)
+		iterOpts.Concurrency = concurrency
+		readAll(iterOpts)
+
+		for _, c := range []struct {
+			name  string
+			value int64
+		}{
+			{"iterator.reads.records", 2},
+			{"iterator.reads.files-opened", 2},
+			{"iterator.reads.decode-errors", 1},
+		} {
+			counter, ok := snapshotCounterValue(readScope, c.name)
+			require.True(t, ok, "concurrency %d: %s", concurrency, c.name)
+			require.Equal(t, c.value, counter.Value(), "concurrency %d: %s", concurrency, c.name)
+		}
+
+		// The corrupt chunk is not read so less than the total size is read
+		bytesRead, ok := snapshotCounterValue(readScope, "iterator.reads.bytes")
+		require.True(t, ok)
+		require.True(t, bytesRead.Value() > 0)
+		require.True(t, bytesRead.Value() < totalBytes)
+
+		timers := readScope.Snapshot().Timers()
+		fileDuration, ok := timers[tally.KeyForPrefixedStringMap("iterator.reads.file-duration", nil)]
+		require.True(t, ok)
+		require.Equal(t, 2, len(fileDuration.Values()))
+	}
+}
+
+func TestCommitLogIteratorAnnotationBytesPool(t *testing.T) {
+	opts, scope := newTestOptions(t, overrides{
+		strategy: StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+
+	writes := []testWrite{
+		{testSeries(0, "foo.bar", testTags1, 127), time.Now(), 1, xtime.Millisecond, []byte("first"), nil},
+		{testSeries(0, "foo.bar", testTags1, 127), time.Now(), 2, xtime.Millisecond, []byte("second"), nil},
+	}
+	writeCommitLogs(t, scope, commitLog, writes).Wait()
+	require.NoError(t, commitLog.Close())
+
+	annotationPool := pool.NewBytesPool([]pool.Bucket{
+		{Capacity: 16, Count: 1},
+	}, nil)
+	annotationPool.Init()
+
+	iter, err := NewIterator(IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+		AnnotationBytesPool:   annotationPool,
+	})
+	require.NoError(t, err)
+	defer iter.Close()
+
+	// Annotations are only valid until the next call to Next so copy them
+	var annotations []string
+	for iter.Next() {
+		_, _, _, annotation := iter.Current()
+		annotations = append(annotations, string(annotation))
+	}
+	require.NoError(t, iter.Err())
+	require.Equal(t, []string{"first", "second"}, annotations)
+}
+
+type testAnnotationCodec struct{}
+
+func (c testAnnotationCodec) Decode(annotation ts.Annotation) (interface{}, error) {
+	value := string(annotation)
+	if value == "invalid" {
+		return nil, fmt.Errorf("invalid annotation")
+	}
+	return strings.ToUpper(value), nil
+}
+
+func TestCommitLogIteratorDecodesAnnotations(t *testing.T) {
+	opts, scope := newTestOptions(t, overrides{
+		strategy: StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+
+	writes := []testWrite{
+		{testSeries(0, "foo.bar", testTags1, 127), time.Now(), 1, xtime.Millisecond, []byte("a"), nil},
+		{testSeries(1, "foo.baz", testTags2, 150), time.Now(), 2, xtime.Millisecond, nil, nil},
+		{testSeries(2, "foo.qux", testTags3, 291), time.Now(), 3, xtime.Millisecond, []byte("b"), nil},
+	}
+
+	// Call write sync
+	writeCommitLogs(t, scope, commitLog, writes).Wait()
+
+	// Close the commit log and consequently flush
+	require.NoError(t, commitLog.Close())
+
+	for _, newIter := range []func(IteratorOpts) (Iterator, error){NewIterator, NewMergedIterator} {
+		iter, err := newIter(IteratorOpts{
+			CommitLogOptions:      opts.SetAnnotationCodec(testAnnotationCodec{}),
+			FileFilterPredicate:   ReadAllPredicate(),
+			SeriesFilterPredicate: ReadAllSeriesPredicate(),
+		})
+		require.NoError(t, err)
+
+		decoded := make(map[string]interface{})
+		for iter.Next() {
+			series, _, _, annotation := iter.Current()
+			decoded[series.ID.String()] = iter.DecodedAnnotation()
+			if annotation != nil {
+				// Raw annotation is still returned alongside the decoded value
+				require.Equal(t, strings.ToUpper(string(annotation)), iter.DecodedAnnotation())
+			}
+		}
+		require.NoError(t, iter.Err())
+		iter.Close()
+
+		require.Equal(t, map[string]interface{}{
+			"foo.bar": "A",
+			"foo.baz": nil,
+			"foo.qux": "B",
+		}, decoded)
+	}
+}
+
+func TestCommitLogIteratorAnnotationDecodeError(t *testing.T) {
+	opts, scope := newTestOptions(t, overrides{
+		strategy: StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+
+	writes := []testWrite{
+		{testSeries(0, "foo.bar", testTags1, 127), time.Now(), 1, xtime.Millisecond, []byte("invalid"), nil},
+	}
+
+	// Call write sync
+	writeCommitLogs(t, scope, commitLog, writes).Wait()
+
+	// Close the commit log and consequently flush
+	require.NoError(t, commitLog.Close())
+
+	iter, err := NewIterator(IteratorOpts{
+		CommitLogOptions:      opts.SetAnnotationCodec(testAnnotationCodec{}),
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+	})
+	require.NoError(t, err)
+	defer iter.Close()
+
+	require.False(t, iter.Next())
+	require.Error(t, iter.Err())
+	require.Nil(t, iter.DecodedAnnotation())
+}
+
+func TestCommitLogIteratorSeriesAnnotationFilter(t *testing.T) {
+	opts, scope := newTestOptions(t, overrides{
+		strategy: StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+
+	writes := []testWrite{
+		{testSeries(0, "foo.bar", testTags1, 127), time.Now(), 1, xtime.Millisecond, []byte("tenant-a"), nil},
+		{testSeries(1, "foo.baz", testTags2, 150), time.Now(), 2, xtime.Millisecond, []byte("tenant-b"), nil},
+		{testSeries(0, "foo.bar", testTags1, 127), time.Now(), 3, xtime.Millisecond, []byte("tenant-b"), nil},
+		{testSeries(2, "foo.qux", testTags3, 291), time.Now(), 4, xtime.Millisecond, []byte("tenant-a"), nil},
+	}
+
+	// Call write sync
+	writeCommitLogs(t, scope, commitLog, writes).Wait()
+
+	// Close the commit log and consequently flush
+	require.NoError(t, commitLog.Close())
+
+	for _, newIter := range []func(IteratorOpts) (Iterator, error){NewIterator, NewMergedIterator} {
+		iter, err := newIter(IteratorOpts{
+			CommitLogOptions:    opts,
+			FileFilterPredicate: ReadAllPredicate(),
+			SeriesFilterPredicate: func(id ident.ID, _ ident.ID) bool {
+				return id.String() != "foo.qux"
+			},
+			SeriesAnnotationFilterPredicate: func(_ ident.ID, _ ident.ID, annotation ts.Annotation) bool {
+				return string(annotation) == "tenant-a"
+			},
+		})
+		require.NoError(t, err)
+
+		// The annotation of the first datapoint decides for the whole series
+		var values []float64
+		for iter.Next() {
+			_, dp, _, _ := iter.Current()
+			values = append(values, dp.Value)
+		}
+		require.NoError(t, iter.Err())
+		iter.Close()
+
+		require.Equal(t, []float64{1, 3}, values)
+	}
+}
+
+func TestCommitLogForEach(t *testing.T) {
+	opts, scope := newTestOptions(t, overrides{
+		strategy: StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+
+	writes := []testWrite{
+		{testSeries(0, "foo.bar", testTags1, 127), time.Now(), 123.456, xtime.Millisecond, []byte{1, 2, 3}, nil},
+		{testSeries(1, "foo.baz", testTags2, 150), time.Now(), 456.789, xtime.Second, nil, nil},
+		{testSeries(2, "foo.qux", testTags3, 291), time.Now(), 789.123, xtime.Millisecond, nil, nil},
+	}
+	writeCommitLogs(t, scope, commitLog, writes).Wait()
+	require.NoError(t, commitLog.Close())
+
+	iterOpts := IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+	}
+
+	var read int
+	err := ForEach(iterOpts, func(
+		series Series,
+		datapoint ts.Datapoint,
+		unit xtime.Unit,
+		annotation ts.Annotation,
+	) error {
+		require.True(t, read < len(writes))
+		writes[read].assert(t, series, datapoint, unit, annotation)
+		read++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, len(writes), read)
+
+	// Returning an error from the callback stops iteration
+	errStop := errors.New("stop")
+	read = 0
+	err = ForEach(iterOpts, func(Series, ts.Datapoint, xtime.Unit, ts.Annotation) error {
+		read++
+		return errStop
+	})
+	require.Equal(t, errStop, err)
+	require.Equal(t, 1, read)
+}
+
+func TestCommitLogSequenceNumbersIncreaseAcrossRotationsAndRestarts(t *testing.T) {
+	clock := mclock.NewMock()
+	opts, scope := newTestOptions(t, overrides{
+		clock:    clock,
+		strategy: StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	opts = opts.SetSequenceNumbersEnabled(true)
+	blockSize := opts.BlockSize()
+	alignedStart := clock.Now().Truncate(blockSize)
+
+	openCommitLog := func() *commitLog {
+		commitLogI, err := NewCommitLog(opts)
+		require.NoError(t, err)
+		commitLog := commitLogI.(*commitLog)
+		require.NoError(t, commitLog.Open())
+		return commitLog
+	}
+
+	writeBlock := func(commitLog *commitLog, block int, values ...float64) {
+		// Set clock to align with the block so the writer rotates files
+		blockStart := alignedStart.Add(time.Duration(block) * blockSize)
+		clock.Add(blockStart.Sub(clock.Now()))
+
+		var writes []testWrite
+		for i, v := range values {
+			writes = append(writes, testWrite{testSeries(uint64(i), fmt.Sprintf("foo.%d", i), testTags1, 127),
+				blockStart.Add(time.Duration(i) * time.Second), v, xtime.Millisecond, nil, nil})
+		}
+		wg := writeCommitLogs(t, scope, commitLog, writes)
+
+		// Flush until finished, this is required as timed flusher not active when clock is mocked
+		flushUntilDone(commitLog, wg)
+	}
+
+	// Write across a rotation
+	commitLog := openCommitLog()
+	writeBlock(commitLog, 0, 1, 2)
+	writeBlock(commitLog, 1, 3, 4)
+	require.NoError(t, commitLog.Close())
+
+	// Restart without writing, leaving the latest file without entries
+	require.NoError(t, openCommitLog().Close())
+
+	// Restart and write again, numbering must resume from the earlier files
+	commitLog = openCommitLog()
+	writeBlock(commitLog, 1, 5)
+	writeBlock(commitLog, 2, 6, 7)
+	require.NoError(t, commitLog.Close())
+
+	iter, err := NewIterator(IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+	})
+	require.NoError(t, err)
+	defer iter.Close()
+
+	// Values were written in increasing order so each must carry a strictly
+	// increasing sequence number
+	sequenceNumbers := make(map[float64]uint64)
+	for iter.Next() {
+		_, dp, _, _ := iter.Current()
+		sequenceNumbers[dp.Value] = iter.SequenceNumber()
+	}
+	require.NoError(t, iter.Err())
+
+	require.Equal(t, map[float64]uint64{
+		1: 1, 2: 2, 3: 3, 4: 4, 5: 5, 6: 6, 7: 7,
+	}, sequenceNumbers)
+}
+
+func TestCommitLogAnnotationVersion(t *testing.T) {
+	clock := mclock.NewMock()
+	opts, scope := newTestOptions(t, overrides{
+		clock:    clock,
+		strategy: StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	blockSize := opts.BlockSize()
+	alignedStart := clock.Now().Truncate(blockSize)
+
+	writeBlock := func(opts Options, block int, values ...float64) {
+		// Set clock to align with the block so each block is its own file
+		blockStart := alignedStart.Add(time.Duration(block) * blockSize)
+		clock.Add(blockStart.Sub(clock.Now()))
+
+		commitLogI, err := NewCommitLog(opts)
+		require.NoError(t, err)
+		commitLog := commitLogI.(*commitLog)
+		require.NoError(t, commitLog.Open())
+
+		var writes []testWrite
+		for i, v := range values {
+			writes = append(writes, testWrite{testSeries(uint64(i), fmt.Sprintf("foo.%d", i), testTags1, 127),
+				blockStart.Add(time.Duration(i) * time.Second), v, xtime.Millisecond, []byte{0x1}, nil})
+		}
+		wg := writeCommitLogs(t, scope, commitLog, writes)
+
+		// Flush until finished, this is required as timed flusher not active when clock is mocked
+		flushUntilDone(commitLog, wg)
+		require.NoError(t, commitLog.Close())
+	}
+
+	// Entries written before the schema changed have the default version
+	writeBlock(opts, 0, 1, 2)
+	writeBlock(opts.SetAnnotationVersion(2), 1, 3, 4)
+
+	iter, err := NewIterator(IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+	})
+	require.NoError(t, err)
+	defer iter.Close()
+
+	versions := make(map[float64]uint32)
+	for iter.Next() {
+		_, dp, _, _ := iter.Current()
+		versions[dp.Value] = iter.AnnotationVersion()
+	}
+	require.NoError(t, iter.Err())
+	require.Equal(t, map[float64]uint32{1: 0, 2: 0, 3: 2, 4: 2}, versions)
+}
+
+func TestCommitLogActiveFileAndRotationCallback(t *testing.T) {
+	clock := mclock.NewMock()
+	opts, scope := newTestOptions(t, overrides{
+		clock:    clock,
+		strategy: StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	rotations := make(chan rotation, 1)
+	opts = opts.SetRotationCallback(func(oldPath, newPath string) {
+		rotations <- rotation{oldPath: oldPath, newPath: newPath}
+	})
+
+	commitLog := newTestCommitLog(t, opts)
+
+	files, err := Files(opts)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(files))
+	firstPath := files[0].FilePath
+	require.Equal(t, firstPath, commitLog.ActiveFile())
+
+	// Move the clock to the next block so the next write rotates files
+	blockSize := opts.BlockSize()
+	nextBlockStart := clock.Now().Truncate(blockSize).Add(blockSize)
+	clock.Add(nextBlockStart.Sub(clock.Now()))
+
+	writes := []testWrite{
+		{testSeries(0, "foo.bar", testTags1, 127), nextBlockStart, 1, xtime.Second, nil, nil},
+	}
+	flushUntilDone(commitLog, writeCommitLogs(t, scope, commitLog, writes))
+
+	files, err = Files(opts)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(files))
+	secondPath := files[1].FilePath
+	require.Equal(t, secondPath, commitLog.ActiveFile())
+
+	select {
+	case r := <-rotations:
+		require.Equal(t, rotation{oldPath: firstPath, newPath: secondPath}, r)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "rotation callback was not called")
+	}
+
+	require.NoError(t, commitLog.Close())
+	require.Equal(t, "", commitLog.ActiveFile())
+
+	select {
+	case r := <-rotations:
+		require.Fail(t, "unexpected rotation callback", "%v", r)
+	default:
+	}
+}
+
+func TestCommitLogRotationCallbackOrderedAndWaitedOnClose(t *testing.T) {
+	clock := mclock.NewMock()
+	opts, scope := newTestOptions(t, overrides{
+		clock:    clock,
+		strategy: StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	var (
+		rotations []rotation
+		release   = make(chan struct{})
+	)
+	opts = opts.SetRotationCallback(func(oldPath, newPath string) {
+		<-release
+		rotations = append(rotations, rotation{oldPath: oldPath, newPath: newPath})
+	})
+
+	commitLog := newTestCommitLog(t, opts)
+
+	// Rotate twice, the callback is blocked so both rotations are queued
+	blockSize := opts.BlockSize()
+	for i := 0; i < 2; i++ {
+		nextBlockStart := clock.Now().Truncate(blockSize).Add(blockSize)
+		clock.Add(nextBlockStart.Sub(clock.Now()))
+
+		writes := []testWrite{
+			{testSeries(0, "foo.bar", testTags1, 127), nextBlockStart, 1, xtime.Second, nil, nil},
+		}
+		flushUntilDone(commitLog, writeCommitLogs(t, scope, commitLog, writes))
+	}
+
+	files, err := Files(opts)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(files))
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- commitLog.Close()
+	}()
+
+	select {
+	case err := <-closed:
+		require.FailNow(t, "close returned before rotation callbacks", "returned: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-closed)
+	require.Equal(t, []rotation{
+		{oldPath: files[0].FilePath, newPath: files[1].FilePath},
+		{oldPath: files[1].FilePath, newPath: files[2].FilePath},
+	}, rotations)
+}
+
+func TestCommitLogWriteBehind(t *testing.T) {
+	opts, scope := newTestOptions(t, overrides{
+		strategy: StrategyWriteBehind,
+	})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+
+	writes := []testWrite{
+		{testSeries(0, "foo.bar", testTags1, 127), time.Now(), 123.456, xtime.Millisecond, nil, nil},
+		{testSeries(1, "foo.baz", testTags2, 150), time.Now(), 456.789, xtime.Millisecond, nil, nil},
+		{testSeries(2, "foo.qux", testTags3, 291), time.Now(), 789.123, xtime.Millisecond, nil, nil},
+	}
+
+	// Call write behind
+	writeCommitLogs(t, scope, commitLog, writes)
+
+	// Close the commit log and consequently flush
+	require.NoError(t, commitLog.Close())
+
+	// Assert writes occurred by reading the commit log
+	assertCommitLogWritesByIterating(t, commitLog, writes)
+}
+
+func TestCommitLogWriteAndFlushMetrics(t *testing.T) {
+	opts, scope := newTestOptions(t, overrides{
+		strategy: StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+
+	writes := []testWrite{
+		{testSeries(0, "foo.bar", testTags1, 127), time.Now(), 123.456, xtime.Millisecond, nil, nil},
+		{testSeries(1, "foo.baz", testTags2, 150), time.Now(), 456.789, xtime.Millisecond, nil, nil},
+	}
+	writeCommitLogs(t, scope, commitLog, writes).Wait()
+	require.NoError(t, commitLog.Close())
+
+	enqueued, ok := snapshotCounterValue(scope, "commitlog.writes.enqueued")
+	require.True(t, ok)
+	require.Equal(t, int64(len(writes)), enqueued.Value())
+
+	flushes, ok := snapshotCounterValue(scope, "commitlog.writes.flush-done")
+	require.True(t, ok)
+	require.True(t, flushes.Value() > 0)
+
+	flushBytes, ok := snapshotCounterValue(scope, "commitlog.writes.flush-bytes")
+	require.True(t, ok)
+	require.True(t, flushBytes.Value() > 0)
+
+	timers := scope.Snapshot().Timers()
+	flushLatency, ok := timers[tally.KeyForPrefixedStringMap("commitlog.writes.flush-latency", nil)]
+	require.True(t, ok)
+	require.True(t, len(flushLatency.Values()) > 0)
+}
+
+func TestCommitLogQuiesce(t *testing.T) {
+	opts, scope := newTestOptions(t, overrides{
+		strategy: StrategyWriteBehind,
+	})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+
+	writes := []testWrite{
+		{testSeries(0, "foo.bar", testTags1, 127), time.Now(), 123.456, xtime.Millisecond, nil, nil},
+		{testSeries(1, "foo.baz", testTags2, 150), time.Now(), 456.789, xtime.Millisecond, nil, nil},
+		{testSeries(2, "foo.qux", testTags3, 291), time.Now(), 789.123, xtime.Millisecond, nil, nil},
+	}
+
+	// Call write behind and then quiesce to ensure the writes are synced
+	writeCommitLogs(t, scope, commitLog, writes).Wait()
+	require.NoError(t, commitLog.Quiesce(stdcontext.Background()))
+
+	// New writes are refused once quiesced
+	ctx := context.NewContext()
+	defer ctx.Close()
+	series := testSeries(3, "foo.quux", testTags1, 127)
+	err := commitLog.Write(ctx, series, ts.Datapoint{Timestamp: time.Now(), Value: 1}, xtime.Millisecond, nil)
+	require.Equal(t, ErrCommitLogClosing, err)
+	require.Equal(t, ErrCommitLogClosing, commitLog.Sync())
+
+	// Writes enqueued before quiescing are present even before closing
+	iter, err := NewIterator(IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+	})
+	require.NoError(t, err)
+	read := 0
+	for iter.Next() {
+		read++
+	}
+	require.NoError(t, iter.Err())
+	iter.Close()
+	require.Equal(t, len(writes), read)
+
+	require.NoError(t, commitLog.Close())
+	require.Equal(t, ErrCommitLogClosed, commitLog.Quiesce(stdcontext.Background()))
+}
+
+func TestCommitLogSync(t *testing.T) {
+	opts, scope := newTestOptions(t, overrides{
+		strategy: StrategyWriteBehind,
+	})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+
+	writes := []testWrite{
+		{testSeries(0, "foo.bar", testTags1, 127), time.Now(), 123.456, xtime.Millisecond, nil, nil},
+		{testSeries(1, "foo.baz", testTags2, 150), time.Now(), 456.789, xtime.Millisecond, nil, nil},
+	}
+
+	readAll := func() int {
+		iter, err := NewIterator(IteratorOpts{
+			CommitLogOptions:      opts,
+			FileFilterPredicate:   ReadAllPredicate(),
+			SeriesFilterPredicate: ReadAllSeriesPredicate(),
+		})
+		require.NoError(t, err)
+		defer iter.Close()
+		read := 0
+		for iter.Next() {
+			read++
+		}
+		require.NoError(t, iter.Err())
+		return read
+	}
+
+	// Writes enqueued before the sync are present without closing
+	writeCommitLogs(t, scope, commitLog, writes).Wait()
+	require.NoError(t, commitLog.Sync())
+	require.Equal(t, len(writes), readAll())
+
+	// The commit log keeps accepting writes after a sync
+	more := []testWrite{
+		{testSeries(2, "foo.qux", testTags3, 291), time.Now(), 789.123, xtime.Millisecond, nil, nil},
+	}
+	writeCommitLogs(t, scope, commitLog, more).Wait()
+	require.NoError(t, commitLog.Sync())
+	require.Equal(t, len(writes)+len(more), readAll())
+
+	require.NoError(t, commitLog.Close())
+	require.Equal(t, ErrCommitLogClosed, commitLog.Sync())
+}
+
+func TestCommitLogTopSeries(t *testing.T) {
+	opts, scope := newTestOptions(t, overrides{
+		strategy: StrategyWriteBehind,
+	})
+	defer cleanup(t, opts)
+
+	disabled := newTestCommitLog(t, opts)
+	require.Nil(t, disabled.TopSeries(10))
+	require.NoError(t, disabled.Close())
+
+	commitLogI, err := NewCommitLog(opts.SetHotSeriesSampling(1))
+	require.NoError(t, err)
+	commitLog := commitLogI.(*commitLog)
+	require.NoError(t, commitLog.Open())
+
+	hot := testSeries(0, "foo.hot", testTags1, 127)
+	cold := testSeries(1, "foo.cold", testTags2, 150)
+	writes := []testWrite{
+		{hot, time.Now(), 1, xtime.Millisecond, nil, nil},
+		{hot, time.Now(), 2, xtime.Millisecond, nil, nil},
+		{cold, time.Now(), 3, xtime.Millisecond, nil, nil},
+		{hot, time.Now(), 4, xtime.Millisecond, nil, nil},
+	}
+	writeCommitLogs(t, scope, commitLog, writes).Wait()
+
+	// Writes are counted once written, which a sync waits for
+	require.NoError(t, commitLog.Sync())
+	top := commitLog.TopSeries(1)
+	require.Len(t, top, 1)
+	require.Equal(t, "foo.hot", top[0].ID)
+	require.Equal(t, uint64(3), top[0].Writes)
+	require.Len(t, commitLog.TopSeries(10), 2)
+
+	require.NoError(t, commitLog.Close())
+
+	_, err = NewCommitLog(opts.SetHotSeriesSampling(1.5))
+	require.Equal(t, errHotSeriesSamplingRate, err)
+}
+
+func TestCommitLogFsyncInterval(t *testing.T) {
+	opts, scope := newTestOptions(t, overrides{
+		strategy: StrategyWriteBehind,
+	})
+	defer cleanup(t, opts)
+
+	opts = opts.SetFsyncInterval(time.Millisecond)
+	commitLogI, err := NewCommitLog(opts)
+	require.NoError(t, err)
+	commitLog := commitLogI.(*commitLog)
+
+	synced := make(chan struct{}, 2)
+	writer := newMockCommitLogWriter()
+	writer.syncFn = func() error {
+		select {
+		case synced <- struct{}{}:
+		default:
+		}
+		return errors.New("fsync failed")
+	}
+	commitLog.newCommitLogWriterFn = func(
+		_ flushFn,
+		_ Options,
+	) commitLogWriter {
+		return writer
+	}
+
+	require.NoError(t, commitLog.Open())
+	for i := 0; i < 2; i++ {
+		select {
+		case <-synced:
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "commit log was not fsync'd on interval")
+		}
+	}
+	require.NoError(t, commitLog.Close())
+
+	fsyncErrors, ok := snapshotCounterValue(scope, "commitlog.writes.fsync-errors")
+	require.True(t, ok)
+	require.True(t, fsyncErrors.Value() >= 2)
+
+	_, ok = scope.Snapshot().Timers()[tally.KeyForPrefixedStringMap("commitlog.writes.fsync-latency", nil)]
+	require.True(t, ok)
+}
+
+func TestNewCommitLogNegativeFsyncInterval(t *testing.T) {
+	opts, _ := newTestOptions(t, overrides{})
+	defer cleanup(t, opts)
+
+	_, err := NewCommitLog(opts.SetFsyncInterval(-time.Millisecond))
+	require.Equal(t, errFsyncIntervalNonNegative, err)
+}
+
+func TestCommitLogQuiesceContextCanceled(t *testing.T) {
+	opts, _ := newTestOptions(t, overrides{
+		strategy: StrategyWriteBehind,
+	})
+	defer cleanup(t, opts)
+
+	commitLogI, err := NewCommitLog(opts)
+	require.NoError(t, err)
+	commitLog := commitLogI.(*commitLog)
+
+	// Block the writer from completing the sync
+	syncing := make(chan struct{})
+	release := make(chan struct{})
+	writer := newMockCommitLogWriter()
+	writer.syncFn = func() error {
+		close(syncing)
+		<-release
+		return nil
+	}
+	commitLog.newCommitLogWriterFn = func(
+		_ flushFn,
+		_ Options,
+	) commitLogWriter {
+		return writer
+	}
+
+	require.NoError(t, commitLog.Open())
+	defer commitLog.Close()
+
+	ctx, cancel := stdcontext.WithCancel(stdcontext.Background())
+	go func() {
+		<-syncing
+		cancel()
+	}()
+	require.Equal(t, stdcontext.Canceled, commitLog.Quiesce(ctx))
+	close(release)
+}
+
+func TestCommitLogNotOpen(t *testing.T) {
+	opts, _ := newTestOptions(t, overrides{})
+	defer cleanup(t, opts)
+
+	commitLog, err := NewCommitLog(opts)
+	require.NoError(t, err)
+
+	require.Equal(t, ErrCommitLogNotOpen, commitLog.Quiesce(stdcontext.Background()))
+	require.Equal(t, ErrCommitLogNotOpen, commitLog.Sync())
+	require.Equal(t, ErrCommitLogNotOpen, commitLog.Close())
+}
+
+func TestCommitLogOpenCloseErrors(t *testing.T) {
+	opts, _ := newTestOptions(t, overrides{})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+	require.Equal(t, ErrCommitLogAlreadyOpen, commitLog.Open())
+
+	require.NoError(t, commitLog.Close())
+	require.Equal(t, ErrCommitLogClosed, commitLog.Close())
+	require.Equal(t, ErrCommitLogClosed, commitLog.Open())
+}
+
+func TestCommitLogWriteErrorOnClosed(t *testing.T) {
+	opts, _ := newTestOptions(t, overrides{})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+	require.NoError(t, commitLog.Close())
+
+	series := testSeries(0, "foo.bar", testTags1, 127)
+	datapoint := ts.Datapoint{Timestamp: time.Now(), Value: 123.456}
+
+	ctx := context.NewContext()
+	defer ctx.Close()
+
+	err := commitLog.Write(ctx, series, datapoint, xtime.Millisecond, nil)
+	require.Error(t, err)
+	require.Equal(t, ErrCommitLogClosed, err)
+}
+
+func TestCommitLogWriteRejectsFutureByDefault(t *testing.T) {
+	clock := mclock.NewMock()
+	opts, scope := newTestOptions(t, overrides{
+		clock: clock,
+	})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+	defer commitLog.Close()
+
+	series := testSeries(0, "foo.bar", testTags1, 127)
+	now := clock.Now()
+	datapoint := ts.Datapoint{Timestamp: now.Add(2 * defaultValidFuture), Value: 123.456}
+
+	ctx := context.NewContext()
+	defer ctx.Close()
+
+	err := commitLog.Write(ctx, series, datapoint, xtime.Millisecond, nil)
+	require.Equal(t, TimestampOutsideWindowError{
+		Timestamp: datapoint.Timestamp,
+		Latest:    now.Add(defaultValidFuture),
+	}, err)
+
+	rejected, ok := snapshotCounterValue(scope, "commitlog.writes.time-window-rejected")
+	require.True(t, ok)
+	require.Equal(t, int64(1), rejected.Value())
+}
+
+func TestCommitLogWriteRejectsOutsideValidTimeWindow(t *testing.T) {
+	clock := mclock.NewMock()
+	opts, scope := newTestOptions(t, overrides{
+		clock: clock,
+	})
+	defer cleanup(t, opts)
+
+	opts = opts.SetValidTimeWindow(time.Hour, time.Minute)
+	commitLog := newTestCommitLog(t, opts)
+	defer commitLog.Close()
+
+	series := testSeries(0, "foo.bar", testTags1, 127)
+	now := clock.Now()
+
+	ctx := context.NewContext()
+	defer ctx.Close()
+
+	for _, timestamp := range []time.Time{now.Add(-2 * time.Hour), now.Add(2 * time.Minute)} {
+		datapoint := ts.Datapoint{Timestamp: timestamp, Value: 123.456}
+		err := commitLog.Write(ctx, series, datapoint, xtime.Millisecond, nil)
+		require.Equal(t, TimestampOutsideWindowError{
+			Timestamp: timestamp,
+			Earliest:  now.Add(-time.Hour),
+			Latest:    now.Add(time.Minute),
+		}, err)
+	}
+
+	rejected, ok := snapshotCounterValue(scope, "commitlog.writes.time-window-rejected")
+	require.True(t, ok)
+	require.Equal(t, int64(2), rejected.Value())
+
+	datapoint := ts.Datapoint{Timestamp: now.Add(-30 * time.Minute), Value: 123.456}
+	require.NoError(t, commitLog.Write(ctx, series, datapoint, xtime.Millisecond, nil))
+}
+
+func TestCommitLogWriteUnboundedValidTimeWindow(t *testing.T) {
+	opts, _ := newTestOptions(t, overrides{})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+	defer commitLog.Close()
+
+	ctx := context.NewContext()
+	defer ctx.Close()
+
+	series := testSeries(0, "foo.bar", testTags1, 127)
+	datapoint := ts.Datapoint{Timestamp: time.Unix(0, 0), Value: 123.456}
+	require.NoError(t, commitLog.Write(ctx, series, datapoint, xtime.Millisecond, nil))
+}
+
+func TestNewCommitLogNegativeValidTimeWindow(t *testing.T) {
+	opts, _ := newTestOptions(t, overrides{})
+	defer cleanup(t, opts)
+
+	_, err := NewCommitLog(opts.SetValidTimeWindow(-time.Hour, 0))
+	require.Equal(t, errValidTimeWindowNonNegative, err)
+}
+
+func TestCommitLogWriteBatch(t *testing.T) {
+	opts, scope := newTestOptions(t, overrides{
+		strategy: StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	opts = opts.SetValidTimeWindow(0, time.Minute)
+	commitLog := newTestCommitLog(t, opts)
+
+	now := time.Now()
+	writes := []testWrite{
+		{testSeries(0, "foo.bar", testTags1, 127), now, 123.456, xtime.Millisecond, []byte{1, 2, 3}, nil},
+		{testSeries(1, "foo.baz", testTags2, 150), now, 456.789, xtime.Millisecond, nil, nil},
+	}
+
+	batch := []BatchWrite{
+		{Series: writes[0].series, Datapoint: ts.Datapoint{Timestamp: now, Value: 123.456}, Unit: xtime.Millisecond, Annotation: writes[0].a},
+		{Series: testSeries(2, "foo.qux", testTags3, 291), Datapoint: ts.Datapoint{Timestamp: now.Add(time.Hour), Value: 789.123}, Unit: xtime.Millisecond},
+		{Series: writes[1].series, Datapoint: ts.Datapoint{Timestamp: now, Value: 456.789}, Unit: xtime.Millisecond},
+	}
+
+	ctx := context.NewContext()
+	defer ctx.Close()
+
+	err := commitLog.WriteBatch(ctx, batch)
+	require.Error(t, err)
+	batchErrs, ok := err.(BatchWriteErrors)
+	require.True(t, ok)
+	require.Equal(t, 1, len(batchErrs))
+	require.Equal(t, 1, batchErrs[0].Index)
+	_, ok = batchErrs[0].Err.(TimestampOutsideWindowError)
+	require.True(t, ok)
+
+	rejected, ok := snapshotCounterValue(scope, "commitlog.writes.time-window-rejected")
+	require.True(t, ok)
+	require.Equal(t, int64(1), rejected.Value())
+
+	// Close the commit log and consequently flush
+	require.NoError(t, commitLog.Close())
+
+	// Assert the accepted writes occurred by reading the commit log
+	assertCommitLogWritesByIterating(t, commitLog, writes)
+}
+
+func TestCommitLogWriteBatchErrorOnClosed(t *testing.T) {
+	opts, _ := newTestOptions(t, overrides{})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+	require.NoError(t, commitLog.Close())
+
+	ctx := context.NewContext()
+	defer ctx.Close()
+
+	err := commitLog.WriteBatch(ctx, []BatchWrite{
+		{Series: testSeries(0, "foo.bar", testTags1, 127), Datapoint: ts.Datapoint{Timestamp: time.Now(), Value: 1}, Unit: xtime.Millisecond},
+	})
+	require.Equal(t, ErrCommitLogClosed, err)
+}
+
+func TestCommitLogWriteErrorOnFull(t *testing.T) {
+	// Set backlog of size one and don't automatically flush
+	backlogQueueSize := 1
+	flushInterval := time.Duration(0)
+	opts, _ := newTestOptions(t, overrides{
+		backlogQueueSize: &backlogQueueSize,
+		flushInterval:    &flushInterval,
+		strategy:         StrategyWriteBehind,
+	})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+
+	// Test filling queue
+	var writes []testWrite
+	series := testSeries(0, "foo.bar", testTags1, 127)
+	dp := ts.Datapoint{Timestamp: time.Now(), Value: 123.456}
+	unit := xtime.Millisecond
+
+	ctx := context.NewContext()
+	defer ctx.Close()
+
+	for {
+		if err := commitLog.Write(ctx, series, dp, unit, nil); err != nil {
+			// Ensure queue full error
+			require.Equal(t, ErrCommitLogQueueFull, err)
+			break
+		}
+		writes = append(writes, testWrite{series, dp.Timestamp, dp.Value, unit, nil, nil})
+
+		// Increment timestamp and value for next write
+		dp.Timestamp = dp.Timestamp.Add(time.Second)
+		dp.Value += 1.0
+	}
+
+	// Close and consequently flush
+	require.NoError(t, commitLog.Close())
+
+	// Assert write flushed by reading the commit log
+	assertCommitLogWritesByIterating(t, commitLog, writes)
+}
+
+func TestCommitLogBacklogQueueFullPolicy(t *testing.T) {
+	for _, policy := range []BacklogQueueFullPolicy{BacklogQueueFullReject, BacklogQueueFullBlock} {
+		testCommitLogBacklogQueueFullPolicy(t, policy, time.Minute)
+	}
+}
+
+func TestCommitLogBacklogQueueFullBlockTimeout(t *testing.T) {
+	testCommitLogBacklogQueueFullPolicy(t, BacklogQueueFullBlock, 10*time.Millisecond)
+}
+
+func testCommitLogBacklogQueueFullPolicy(
+	t *testing.T,
+	policy BacklogQueueFullPolicy,
+	blockTimeout time.Duration,
+) {
+	// Set backlog of size one and don't automatically flush
+	backlogQueueSize := 1
+	flushInterval := time.Duration(0)
+	opts, _ := newTestOptions(t, overrides{
+		backlogQueueSize: &backlogQueueSize,
+		flushInterval:    &flushInterval,
+		strategy:         StrategyWriteBehind,
+	})
+	defer cleanup(t, opts)
+
+	opts = opts.SetBacklogQueueFullPolicy(policy).
+		SetBacklogQueueFullTimeout(blockTimeout)
+	commitLogI, err := NewCommitLog(opts)
+	require.NoError(t, err)
+	commitLog := commitLogI.(*commitLog)
+
+	// Stall the write loop on the first write it receives
+	var (
+		stalled = make(chan struct{})
+		release = make(chan struct{})
+		once    sync.Once
+	)
+	writer := newMockCommitLogWriter()
+	writer.writeFn = func(Series, ts.Datapoint, xtime.Unit, ts.Annotation) error {
+		once.Do(func() { close(stalled) })
+		<-release
+		return nil
+	}
+	commitLog.newCommitLogWriterFn = func(
+		_ flushFn,
+		_ Options,
+	) commitLogWriter {
+		return writer
+	}
+
+	require.NoError(t, commitLog.Open())
+
+	ctx := context.NewContext()
+	defer ctx.Close()
+
+	series := testSeries(0, "foo.bar", testTags1, 127)
+	dp := ts.Datapoint{Timestamp: time.Now(), Value: 123.456}
+
+	// The first write stalls the write loop and the second fills the queue
+	require.NoError(t, commitLog.Write(ctx, series, dp, xtime.Millisecond, nil))
+	<-stalled
+	require.NoError(t, commitLog.Write(ctx, series, dp, xtime.Millisecond, nil))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- commitLog.Write(ctx, series, dp, xtime.Millisecond, nil)
+	}()
+
+	switch policy {
+	case BacklogQueueFullReject:
+		require.Equal(t, ErrCommitLogQueueFull, <-done)
+		close(release)
+	case BacklogQueueFullBlock:
+		if blockTimeout < 100*time.Millisecond {
+			// The blocked write gives up and is rejected
+			require.Equal(t, ErrCommitLogQueueFull, <-done)
+			close(release)
+			break
+		}
+
+		select {
+		case err := <-done:
+			require.FailNow(t, "write did not block on full queue", "returned: %v", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		// Unstalling the write loop makes space for the blocked write
+		close(release)
+		require.NoError(t, <-done)
+	}
+
+	require.NoError(t, commitLog.Close())
+}
+
+func TestCommitLogExpiresWriter(t *testing.T) {
+	clock := mclock.NewMock()
+	opts, scope := newTestOptions(t, overrides{
+		clock:    clock,
+		strategy: StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+
+	blockSize := opts.BlockSize()
+	alignedStart := clock.Now().Truncate(blockSize)
+
+	// Writes spaced apart by block size
+	writes := []testWrite{
+		{testSeries(0, "foo.bar", testTags1, 127), alignedStart, 123.456, xtime.Millisecond, nil, nil},
+		{testSeries(1, "foo.baz", testTags2, 150), alignedStart.Add(1 * blockSize), 456.789, xtime.Millisecond, nil, nil},
+		{testSeries(2, "foo.qux", testTags3, 291), alignedStart.Add(2 * blockSize), 789.123, xtime.Millisecond, nil, nil},
+	}
+
+	for _, write := range writes {
+		// Set clock to align with the write
+		clock.Add(write.t.Sub(clock.Now()))
+
+		// Write entry
+		wg := writeCommitLogs(t, scope, commitLog, []testWrite{write})
+
+		// Flush until finished, this is required as timed flusher not active when clock is mocked
+		flushUntilDone(commitLog, wg)
+	}
+
+	// Ensure files present for each block size time window
+	fsopts := opts.FilesystemOptions()
+	files, err := fs.SortedCommitLogFiles(fs.CommitLogsDirPath(fsopts.FilePathPrefix()))
+	require.NoError(t, err)
+	require.True(t, len(files) == len(writes))
+
+	// Close and consequently flush
+	require.NoError(t, commitLog.Close())
+
+	// Assert write flushed by reading the commit log
+	assertCommitLogWritesByIterating(t, commitLog, writes)
+}
+
+func TestCommitLogFailOnWriteError(t *testing.T) {
+	opts, scope := newTestOptions(t, overrides{
+		strategy: StrategyWriteBehind,
+	})
+	defer cleanup(t, opts)
+
+	commitLogI, err := NewCommitLog(opts)
+	require.NoError(t, err)
+	commitLog := commitLogI.(*commitLog)
+	writer := newMockCommitLogWriter()
+
+	writer.writeFn = func(Series, ts.Datapoint, xtime.Unit, ts.Annotation) error {
+		return fmt.Errorf("an error")
+	}
+
+	var opens int64
+	writer.openFn = func(start time.Time, duration time.Duration) error {
+		if atomic.AddInt64(&opens, 1) >= 2 {
+			return fmt.Errorf("an error")
+		}
+		return nil
+	}
+
+	writer.flushFn = func() error {
+		commitLog.onFlush(nil)
+		return nil
+	}
+
+	commitLog.newCommitLogWriterFn = func(
+		_ flushFn,
+		_ Options,
+	) commitLogWriter {
+		return writer
+	}
+
+	require.NoError(t, commitLog.Open())
+
+	wg := setupCloseOnFail(t, commitLog)
+
+	writes := []testWrite{
+		{testSeries(0, "foo.bar", testTags1, 127), time.Now(), 123.456, xtime.Millisecond, nil, nil},
+	}
+
+	writeCommitLogs(t, scope, commitLog, writes)
+
+	wg.Wait()
+
+	// Check stats
+	errors, ok := snapshotCounterValue(scope, "commitlog.writes.errors")
+	require.True(t, ok)
+	require.Equal(t, int64(1), errors.Value())
+}
+
+func TestCommitLogFailOnOpenError(t *testing.T) {
+	opts, scope := newTestOptions(t, overrides{
+		strategy: StrategyWriteBehind,
+	})
+	defer cleanup(t, opts)
+
+	commitLogI, err := NewCommitLog(opts)
+	require.NoError(t, err)
+	commitLog := commitLogI.(*commitLog)
+	writer := newMockCommitLogWriter()
+
+	var opens int64
+	writer.openFn = func(start time.Time, duration time.Duration) error {
+		if atomic.AddInt64(&opens, 1) >= 2 {
+			return fmt.Errorf("an error")
+		}
+		return nil
+	}
+
+	writer.flushFn = func() error {
+		commitLog.onFlush(nil)
+		return nil
+	}
+
+	commitLog.newCommitLogWriterFn = func(
+		_ flushFn,
+		_ Options,
+	) commitLogWriter {
+		return writer
+	}
+
+	require.NoError(t, commitLog.Open())
+
+	wg := setupCloseOnFail(t, commitLog)
+
+	func() {
+		commitLog.RLock()
+		defer commitLog.RUnlock()
+		// Expire the writer so it requires a new open
+		commitLog.writerExpireAt = timeZero
+	}()
 
 	writes := []testWrite{
 		{testSeries(0, "foo.bar", testTags1, 127), time.Now(), 123.456, xtime.Millisecond, nil, nil},
@@ -830,3 +2298,235 @@ var (
 	testTags2 = ident.NewTags(testTag2)
 	testTags3 = ident.NewTags(testTag3)
 )
+
+func BenchmarkCommitLogWriteWait(b *testing.B) {
+	benchmarkCommitLogWrite(b, StrategyWriteWait)
+}
+
+func BenchmarkCommitLogWriteBehind(b *testing.B) {
+	benchmarkCommitLogWrite(b, StrategyWriteBehind)
+}
+
+func BenchmarkCommitLogWriteBatchWait(b *testing.B) {
+	benchmarkCommitLogWriteBatch(b, StrategyWriteWait)
+}
+
+func BenchmarkCommitLogWriteBatchBehind(b *testing.B) {
+	benchmarkCommitLogWriteBatch(b, StrategyWriteBehind)
+}
+
+func newBenchmarkCommitLog(b *testing.B, strategy Strategy) (CommitLog, func()) {
+	dir, err := ioutil.TempDir("", "commitlog-bench")
+	require.NoError(b, err)
+
+	opts := NewOptions().
+		SetFilesystemOptions(fs.NewOptions().SetFilePathPrefix(dir)).
+		SetBlockSize(2 * time.Hour).
+		SetFlushInterval(time.Millisecond).
+		SetBacklogQueueSize(1 << 16).
+		SetStrategy(strategy)
+
+	commitLog, err := NewCommitLog(opts)
+	require.NoError(b, err)
+	require.NoError(b, commitLog.Open())
+
+	return commitLog, func() {
+		commitLog.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func benchmarkCommitLogWrite(b *testing.B, strategy Strategy) {
+	commitLog, closeFn := newBenchmarkCommitLog(b, strategy)
+	defer closeFn()
+
+	var idx uint64
+	b.ReportAllocs()
+	b.ResetTimer()
+	// Writers waiting for a flush block, so use enough concurrent writers
+	// to fill chunks the way a busy ingestion path would
+	b.SetParallelism(64)
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := context.NewContext()
+		defer ctx.Close()
+
+		i := atomic.AddUint64(&idx, 1)
+		series := testSeries(i, fmt.Sprintf("foo.%d", i), testTags1, 127)
+		for pb.Next() {
+			dp := ts.Datapoint{Timestamp: time.Now(), Value: 1}
+			err := commitLog.Write(ctx, series, dp, xtime.Second, nil)
+			if err != nil && err != ErrCommitLogQueueFull {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func benchmarkCommitLogWriteBatch(b *testing.B, strategy Strategy) {
+	commitLog, closeFn := newBenchmarkCommitLog(b, strategy)
+	defer closeFn()
+
+	const batchSize = 128
+
+	var idx uint64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.SetParallelism(64)
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := context.NewContext()
+		defer ctx.Close()
+
+		i := atomic.AddUint64(&idx, 1)
+		series := testSeries(i, fmt.Sprintf("foo.%d", i), testTags1, 127)
+		batch := make([]BatchWrite, 0, batchSize)
+		flush := func() {
+			err := commitLog.WriteBatch(ctx, batch)
+			if errs, ok := err.(BatchWriteErrors); ok {
+				for _, e := range errs {
+					if e.Err != ErrCommitLogQueueFull {
+						b.Fatal(e.Err)
+					}
+				}
+			} else if err != nil {
+				b.Fatal(err)
+			}
+			batch = batch[:0]
+		}
+		for pb.Next() {
+			batch = append(batch, BatchWrite{
+				Series:    series,
+				Datapoint: ts.Datapoint{Timestamp: time.Now(), Value: 1},
+				Unit:      xtime.Second,
+			})
+			if len(batch) == batchSize {
+				flush()
+			}
+		}
+		if len(batch) > 0 {
+			flush()
+		}
+	})
+}
+
+func BenchmarkCommitLogIteratorAnnotations(b *testing.B) {
+	commitLog, closeFn := newBenchmarkCommitLog(b, StrategyWriteBehind)
+	defer closeFn()
+
+	ctx := context.NewContext()
+	defer ctx.Close()
+
+	const numWrites = 10000
+	annotation := []byte("benchmark-annotation")
+	for i := 0; i < numWrites; i++ {
+		series := testSeries(uint64(i%100), fmt.Sprintf("foo.%d", i%100), testTags1, 127)
+		dp := ts.Datapoint{Timestamp: time.Now(), Value: float64(i)}
+		for {
+			err := commitLog.Write(ctx, series, dp, xtime.Second, annotation)
+			if err == nil {
+				break
+			}
+			require.Equal(b, ErrCommitLogQueueFull, err)
+			time.Sleep(time.Millisecond)
+		}
+	}
+	opts := commitLog.(*commitLog).opts
+	require.NoError(b, commitLog.Close())
+
+	annotationPool := pool.NewBytesPool([]pool.Bucket{
+		{Capacity: 64, Count: 1024},
+	}, nil)
+	annotationPool.Init()
+
+	for _, bench := range []struct {
+		name string
+		pool pool.BytesPool
+	}{
+		{name: "allocate"},
+		{name: "pooled", pool: annotationPool},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				iter, err := NewIterator(IteratorOpts{
+					CommitLogOptions:      opts,
+					FileFilterPredicate:   ReadAllPredicate(),
+					SeriesFilterPredicate: ReadAllSeriesPredicate(),
+					AnnotationBytesPool:   bench.pool,
+				})
+				require.NoError(b, err)
+				for iter.Next() {
+				}
+				require.NoError(b, iter.Err())
+				iter.Close()
+			}
+		})
+	}
+}
+
+func BenchmarkCommitLogIteratorConcurrency(b *testing.B) {
+	dir, err := ioutil.TempDir("", "commitlog-bench")
+	require.NoError(b, err)
+	defer os.RemoveAll(dir)
+
+	var (
+		opts = NewOptions().
+			SetFilesystemOptions(fs.NewOptions().SetFilePathPrefix(dir)).
+			SetBlockSize(2 * time.Hour).
+			SetBacklogQueueSize(1 << 16).
+			SetStrategy(StrategyWriteBehind)
+		blockStart = time.Now().Truncate(opts.BlockSize())
+	)
+
+	// Write a corpus of one file per block, each commit log writes a single
+	// file for the block its clock is in
+	const (
+		numFiles         = 8
+		numWritesPerFile = 20000
+	)
+	for i := 0; i < numFiles; i++ {
+		start := blockStart.Add(time.Duration(i-numFiles) * opts.BlockSize())
+		fileOpts := opts.SetClockOptions(opts.ClockOptions().SetNowFn(func() time.Time {
+			return start
+		}))
+		commitLog, err := NewCommitLog(fileOpts)
+		require.NoError(b, err)
+		require.NoError(b, commitLog.Open())
+
+		ctx := context.NewContext()
+		for j := 0; j < numWritesPerFile; j++ {
+			series := testSeries(uint64(j%1000), fmt.Sprintf("foo.%d", j%1000), testTags1, 127)
+			dp := ts.Datapoint{Timestamp: start.Add(time.Duration(j) * time.Millisecond), Value: float64(j)}
+			for {
+				err := commitLog.Write(ctx, series, dp, xtime.Millisecond, nil)
+				if err == nil {
+					break
+				}
+				require.Equal(b, ErrCommitLogQueueFull, err)
+				time.Sleep(time.Millisecond)
+			}
+		}
+		ctx.Close()
+		require.NoError(b, commitLog.Close())
+	}
+
+	for _, concurrency := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("concurrency-%d", concurrency), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				iter, err := NewIterator(IteratorOpts{
+					CommitLogOptions:      opts,
+					FileFilterPredicate:   ReadAllPredicate(),
+					SeriesFilterPredicate: ReadAllSeriesPredicate(),
+					Concurrency:           concurrency,
+				})
+				require.NoError(b, err)
+				read := 0
+				for iter.Next() {
+					read++
+				}
+				require.NoError(b, iter.Err())
+				require.Equal(b, numFiles*numWritesPerFile, read)
+				iter.Close()
+			}
+		})
+	}
+}
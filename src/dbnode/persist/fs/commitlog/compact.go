@@ -0,0 +1,181 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package commitlog
+
+import (
+	"errors"
+	"io"
+	"sort"
+)
+
+var (
+	errCompactDestinationIsSource = errors.New("commit log compaction destination must differ from the source")
+)
+
+type compactSeriesKey struct {
+	namespace string
+	id        string
+}
+
+// CompactFiles rewrites the entries of the commit log files selected by the
+// iterator options into a single commit log file per block start under the
+// dest file path prefix. Entries for each series keep the order they had
+// across the source files and series unique indexes are reassigned so that
+// series from files written by different processes do not collide. Set
+// SealedOnly to compact while the commit log is still writing to its active
+// file. Only the file filter predicate, SealedOnly and ReadMode are used,
+// every entry of a selected file is rewritten regardless of the series and
+// value predicates. The source files are left in place for the caller to
+// remove once it has switched over to the compacted files.
+func CompactFiles(iterOpts IteratorOpts, dest string) error {
+	opts := iterOpts.CommitLogOptions
+	if dest == opts.FilesystemOptions().FilePathPrefix() {
+		return errCompactDestinationIsSource
+	}
+
+	files, err := Files(opts)
+	if err != nil {
+		return err
+	}
+	if iterOpts.SealedOnly {
+		files = sealedFiles(files)
+	}
+	files = filterFiles(opts, files, iterOpts.FileFilterPredicate)
+
+	// Group the files by block, ordering files for the same block by the
+	// order they were written in so per series ordering is preserved
+	sort.Slice(files, func(i, j int) bool {
+		return fileAfter(files[j], files[i])
+	})
+
+	destOpts := opts.SetFilesystemOptions(
+		opts.FilesystemOptions().SetFilePathPrefix(dest))
+	for len(files) > 0 {
+		n := 1
+		for n < len(files) && sameBlock(files[n], files[0]) {
+			n++
+		}
+		if err := compactBlock(iterOpts, destOpts, files[:n]); err != nil {
+			return err
+		}
+		files = files[n:]
+	}
+
+	return nil
+}
+
+func sameBlock(a, b File) bool {
+	return a.Start.Equal(b.Start) && a.Duration == b.Duration
+}
+
+// compactBlock writes the entries of files, which all belong to the same
+// block, to a single file keeping the block start and duration of the
+// source files.
+func compactBlock(
+	iterOpts IteratorOpts,
+	destOpts Options,
+	files []File,
+) error {
+	var flushErr error
+	w := newCommitLogWriter(func(err error) {
+		if flushErr == nil {
+			flushErr = err
+		}
+	}, destOpts)
+	if err := w.Open(files[0].Start, files[0].Duration); err != nil {
+		return err
+	}
+
+	indexes := make(map[compactSeriesKey]uint64)
+	for _, file := range files {
+		if err := compactFile(iterOpts, file, w, indexes); err != nil {
+			w.Close()
+			return err
+		}
+	}
+
+	if err := w.Sync(); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return flushErr
+}
+
+// compactFile reads the file directly rather than with an iterator, which
+// would list every commit log file again for each file compacted.
+func compactFile(
+	iterOpts IteratorOpts,
+	file File,
+	w commitLogWriter,
+	indexes map[compactSeriesKey]uint64,
+) error {
+	opts := iterOpts.CommitLogOptions
+	reader := newCommitLogReader(opts,
+		combineSeriesPredicates(ReadAllSeriesPredicate(), nil), nil)
+	start, duration, index, err := reader.Open(file.FilePath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	switch {
+	case !file.Start.Equal(start):
+		return errStartDoesNotMatch
+	case file.Duration != duration:
+		return errDurationDoesNotMatch
+	case file.Index != index:
+		return errIndexDoesNotMatch
+	}
+
+	for {
+		series, datapoint, unit, annotation, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil && iterOpts.ReadMode == ReadModeSkipCorruptTail {
+			// Drop the partial record at the tail, the same as the iterator
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		key := compactSeriesKey{
+			namespace: series.Namespace.String(),
+			id:        series.ID.String(),
+		}
+		idx, ok := indexes[key]
+		if !ok {
+			idx = uint64(len(indexes))
+			indexes[key] = idx
+		}
+		series.UniqueIndex = idx
+
+		err = w.Write(series, datapoint, unit, annotation,
+			reader.SequenceNumber(), reader.AnnotationVersion())
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package commitlog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/m3db/m3db/src/dbnode/ts"
+	"github.com/m3db/m3x/context"
+	"github.com/m3db/m3x/ident"
+	xtime "github.com/m3db/m3x/time"
+
+	mclock "github.com/facebookgo/clock"
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactFilesMergesFilesPerBlock(t *testing.T) {
+	clock := mclock.NewMock()
+	opts, scope := newTestOptions(t, overrides{
+		clock:    clock,
+		strategy: StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	dest, err := ioutil.TempDir("", "compacted")
+	require.NoError(t, err)
+	defer os.RemoveAll(dest)
+
+	start := clock.Now().Truncate(opts.BlockSize())
+	clock.Add(start.Add(time.Minute).Sub(clock.Now()))
+
+	// Each session writes a file for the same block, the unique index of
+	// "foo.b" in the second file collides with "foo.a" in the first
+	sessions := [][]testWrite{
+		{
+			{testSeries(0, "foo.a", testTags1, 127), start.Add(time.Second), 1, xtime.Millisecond, nil, nil},
+		},
+		{
+			{testSeries(0, "foo.b", testTags2, 150), start.Add(2 * time.Second), 2, xtime.Millisecond, nil, nil},
+			{testSeries(1, "foo.a", testTags1, 127), start.Add(3 * time.Second), 3, xtime.Millisecond, nil, nil},
+		},
+	}
+	for _, writes := range sessions {
+		commitLogI, err := NewCommitLog(opts)
+		require.NoError(t, err)
+		commitLog := commitLogI.(*commitLog)
+		require.NoError(t, commitLog.Open())
+
+		wg := writeCommitLogs(t, scope, commitLog, writes)
+
+		// Flush until finished, this is required as timed flusher not active when clock is mocked
+		flushUntilDone(commitLog, wg)
+		require.NoError(t, commitLog.Close())
+	}
+
+	iterOpts := IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+	}
+	require.NoError(t, CompactFiles(iterOpts, dest))
+
+	destOpts := opts.SetFilesystemOptions(
+		opts.FilesystemOptions().SetFilePathPrefix(dest))
+	files, err := Files(destOpts)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.True(t, files[0].Start.Equal(start))
+
+	iterOpts.CommitLogOptions = destOpts
+	iter, err := NewIterator(iterOpts)
+	require.NoError(t, err)
+	defer iter.Close()
+
+	var (
+		values = make(map[string][]float64)
+		tags   = make(map[string]string)
+	)
+	for iter.Next() {
+		series, dp, _, _ := iter.Current()
+		id := series.ID.String()
+		values[id] = append(values[id], dp.Value)
+		tags[id] = series.Tags.Values()[0].Value.String()
+	}
+	require.NoError(t, iter.Err())
+
+	require.Equal(t, map[string][]float64{
+		"foo.a": {1, 3},
+		"foo.b": {2},
+	}, values)
+	require.Equal(t, map[string]string{
+		"foo.a": "val1",
+		"foo.b": "val2",
+	}, tags)
+}
+
+func TestCompactFilesRejectsSourceAsDestination(t *testing.T) {
+	opts, _ := newTestOptions(t, overrides{})
+	defer cleanup(t, opts)
+
+	err := CompactFiles(IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+	}, opts.FilesystemOptions().FilePathPrefix())
+	require.Equal(t, errCompactDestinationIsSource, err)
+}
+
+func TestCompactFilesIgnoresSeriesAndValuePredicates(t *testing.T) {
+	opts, scope := newTestOptions(t, overrides{strategy: StrategyWriteWait})
+	defer cleanup(t, opts)
+
+	dest, err := ioutil.TempDir("", "compacted")
+	require.NoError(t, err)
+	defer os.RemoveAll(dest)
+
+	commitLogI, err := NewCommitLog(opts)
+	require.NoError(t, err)
+	commitLog := commitLogI.(*commitLog)
+	require.NoError(t, commitLog.Open())
+
+	now := time.Now()
+	writes := []testWrite{
+		{testSeries(0, "foo.a", testTags1, 127), now, 1, xtime.Millisecond, nil, nil},
+		{testSeries(1, "foo.b", testTags2, 150), now, 2, xtime.Millisecond, nil, nil},
+	}
+	wg := writeCommitLogs(t, scope, commitLog, writes)
+	flushUntilDone(commitLog, wg)
+	require.NoError(t, commitLog.Close())
+
+	require.NoError(t, CompactFiles(IteratorOpts{
+		CommitLogOptions:    opts,
+		FileFilterPredicate: ReadAllPredicate(),
+		SeriesFilterPredicate: func(id ident.ID, _ ident.ID) bool {
+			return id.String() == "foo.a"
+		},
+		ValueFilterPredicate: func(dp ts.Datapoint) bool {
+			return dp.Value > 1
+		},
+	}, dest))
+
+	destOpts := opts.SetFilesystemOptions(
+		opts.FilesystemOptions().SetFilePathPrefix(dest))
+	values, err := readValuesBySeries(destOpts)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]float64{
+		"testNS/foo.a": {1},
+		"testNS/foo.b": {2},
+	}, values)
+}
+
+func TestCompactFilesPreservesWritesProp(t *testing.T) {
+	parameters := gopter.DefaultTestParameters()
+	seed := time.Now().UnixNano()
+	parameters.MinSuccessfulTests = 20
+	parameters.MaxSize = 40
+	parameters.Rng = rand.New(rand.NewSource(seed))
+	properties := gopter.NewProperties(parameters)
+
+	properties.Property("Compacted files contain every write in per series order", prop.ForAll(
+		func(writes []generatedWrite, sessions int) (bool, error) {
+			if err := testCompactFilesPreservesWrites(writes, sessions); err != nil {
+				return false, err
+			}
+			return true, nil
+		},
+		gen.SliceOf(genWrite()),
+		gen.IntRange(1, 4),
+	))
+	reporter := gopter.NewFormatedReporter(true, 160, os.Stdout)
+	if !properties.Run(reporter) {
+		t.Errorf("failed with initial seed: %d", seed)
+	}
+}
+
+// testCompactFilesPreservesWrites writes the writes over the given number of
+// commit log sessions, each of which writes its own file, and checks the
+// compacted files return every write in the order it was written per series.
+func testCompactFilesPreservesWrites(writes []generatedWrite, sessions int) error {
+	source, err := ioutil.TempDir("", "compact-source")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(source)
+
+	dest, err := ioutil.TempDir("", "compact-dest")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dest)
+
+	opts := NewOptions().
+		SetStrategy(StrategyWriteBehind).
+		SetFlushInterval(defaultTestFlushInterval)
+	opts = opts.SetFilesystemOptions(
+		opts.FilesystemOptions().SetFilePathPrefix(source))
+
+	perSession := len(writes)/sessions + 1
+	for remaining := writes; len(remaining) > 0; {
+		n := perSession
+		if n > len(remaining) {
+			n = len(remaining)
+		}
+		if err := writeSession(opts, remaining[:n]); err != nil {
+			return err
+		}
+		remaining = remaining[n:]
+	}
+
+	err = CompactFiles(IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+	}, dest)
+	if err != nil {
+		return err
+	}
+
+	destOpts := opts.SetFilesystemOptions(
+		opts.FilesystemOptions().SetFilePathPrefix(dest))
+	destFiles, err := Files(destOpts)
+	if err != nil {
+		return err
+	}
+	for _, f := range destFiles {
+		if f.Duration != opts.BlockSize() {
+			return fmt.Errorf("compacted file %s has duration %v, expected %v",
+				f.FilePath, f.Duration, opts.BlockSize())
+		}
+	}
+
+	expected := make(map[string][]float64)
+	for _, w := range writes {
+		id := w.series.Namespace.String() + "/" + w.series.ID.String()
+		expected[id] = append(expected[id], w.datapoint.Value)
+	}
+	values, err := readValuesBySeries(destOpts)
+	if err != nil {
+		return err
+	}
+	if len(values) != len(expected) {
+		return fmt.Errorf("read %d series, expected %d", len(values), len(expected))
+	}
+	for id, expectedValues := range expected {
+		if fmt.Sprint(values[id]) != fmt.Sprint(expectedValues) {
+			return fmt.Errorf("series %s read values %v, expected %v",
+				id, values[id], expectedValues)
+		}
+	}
+	return nil
+}
+
+func writeSession(opts Options, writes []generatedWrite) error {
+	cl, err := NewCommitLog(opts)
+	if err != nil {
+		return err
+	}
+	if err := cl.Open(); err != nil {
+		return err
+	}
+
+	ctx := context.NewContext()
+	defer ctx.Close()
+	for _, w := range writes {
+		if err := cl.Write(ctx, w.series, w.datapoint, w.unit, w.annotation); err != nil {
+			cl.Close()
+			return err
+		}
+	}
+	return cl.Close()
+}
+
+// readValuesBySeries returns the values of every series in the commit logs
+// keyed by namespace and series ID.
+func readValuesBySeries(opts Options) (map[string][]float64, error) {
+	iter, err := NewIterator(IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	values := make(map[string][]float64)
+	for iter.Next() {
+		series, dp, _, _ := iter.Current()
+		id := series.Namespace.String() + "/" + series.ID.String()
+		values[id] = append(values[id], dp.Value)
+	}
+	return values, iter.Err()
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package commitlog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// DumpFile writes the entries of a single commit log file to w in human
+// readable form, one namespace/series/shard/timestamp/value/unit line per
+// entry, followed by a summary of the number of entries read per series.
+func DumpFile(path string, w io.Writer, opts Options) error {
+	reader := newCommitLogReader(opts, combineSeriesPredicates(ReadAllSeriesPredicate(), nil), nil)
+	if _, _, _, err := reader.Open(path); err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	var (
+		buf    = bufio.NewWriter(w)
+		counts = make(map[string]int)
+	)
+	for {
+		series, dp, unit, _, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		key := fmt.Sprintf("%s/%s", series.Namespace.String(), series.ID.String())
+		counts[key]++
+		if _, err := fmt.Fprintf(buf, "%s/%d/%s/%v/%s\n", key, series.Shard,
+			dp.Timestamp.Format(time.RFC3339Nano), dp.Value, unit.String()); err != nil {
+			return err
+		}
+	}
+
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if _, err := fmt.Fprintf(buf, "summary: %d series\n", len(keys)); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if _, err := fmt.Fprintf(buf, "%s: %d entries\n", key, counts[key]); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
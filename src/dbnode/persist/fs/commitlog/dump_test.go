@@ -0,0 +1,79 @@
+// Copyright (c) 2017 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package commitlog
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	xtime "github.com/m3db/m3x/time"
+
+	mclock "github.com/facebookgo/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpFile(t *testing.T) {
+	clock := mclock.NewMock()
+	opts, scope := newTestOptions(t, overrides{
+		clock:    clock,
+		strategy: StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+
+	start := clock.Now()
+	writes := []testWrite{
+		{testSeries(0, "foo.a", testTags1, 127), start, 1, xtime.Millisecond, nil, nil},
+		{testSeries(1, "foo.b", testTags2, 150), start.Add(time.Second), 2, xtime.Second, nil, nil},
+		{testSeries(0, "foo.a", testTags1, 127), start.Add(2 * time.Second), 3.5, xtime.Millisecond, nil, nil},
+	}
+	writeCommitLogs(t, scope, commitLog, writes).Wait()
+	require.NoError(t, commitLog.Close())
+
+	files, err := Files(opts)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(files))
+
+	var buf bytes.Buffer
+	require.NoError(t, DumpFile(files[0].FilePath, &buf, opts))
+
+	var expected bytes.Buffer
+	for _, w := range writes {
+		fmt.Fprintf(&expected, "%s/%s/%d/%s/%v/%s\n", w.series.Namespace.String(),
+			w.series.ID.String(), w.series.Shard, w.t.Format(time.RFC3339Nano), w.v, w.u.String())
+	}
+	expected.WriteString("summary: 2 series\n")
+	expected.WriteString("testNS/foo.a: 2 entries\n")
+	expected.WriteString("testNS/foo.b: 1 entries\n")
+	require.Equal(t, expected.String(), buf.String())
+}
+
+func TestDumpFileMissing(t *testing.T) {
+	opts, _ := newTestOptions(t, overrides{})
+	defer cleanup(t, opts)
+
+	var buf bytes.Buffer
+	require.Error(t, DumpFile("/does/not/exist", &buf, opts))
+	require.Equal(t, 0, buf.Len())
+}
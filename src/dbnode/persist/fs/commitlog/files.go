@@ -109,3 +109,77 @@ func Files(opts Options) ([]File, error) {
 
 	return commitLogFiles, nil
 }
+
+// sealedFiles returns the files that are no longer being written to. The
+// commit log only ever writes to the file with the latest start and index so
+// every other file is sealed, the latest file is conservatively treated as
+// active even if the process writing to it is no longer running.
+func sealedFiles(files []File) []File {
+	if len(files) == 0 {
+		return files
+	}
+
+	active := 0
+	for i, f := range files {
+		if fileAfter(f, files[active]) {
+			active = i
+		}
+	}
+
+	sealed := make([]File, 0, len(files)-1)
+	sealed = append(sealed, files[:active]...)
+	return append(sealed, files[active+1:]...)
+}
+
+// fileAfter returns whether file a was opened for writing after file b.
+func fileAfter(a, b File) bool {
+	return a.Start.After(b.Start) ||
+		(a.Start.Equal(b.Start) && a.Index > b.Index)
+}
+
+// lastSequenceNumber returns the highest sequence number written to the
+// commit logs on disk. Files are scanned from the most recently opened
+// backwards until one containing entries is found, since a restart opens
+// a new file that may not have been written to before the next restart.
+// Entries that cannot be read from a partially written file are ignored
+// as they cannot be replayed either.
+func lastSequenceNumber(opts Options) (uint64, error) {
+	files, err := Files(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return fileAfter(files[i], files[j])
+	})
+
+	for _, file := range files {
+		var (
+			reader     = newCommitLogReader(opts, combineSeriesPredicates(ReadAllSeriesPredicate(), nil), nil)
+			max        uint64
+			hasEntries bool
+		)
+		if _, _, _, err := reader.Open(file.FilePath); err != nil {
+			// The file was not written to beyond its info header
+			continue
+		}
+		for {
+			_, _, _, _, err := reader.Read()
+			if err != nil {
+				break
+			}
+			hasEntries = true
+			if seq := reader.SequenceNumber(); seq > max {
+				max = seq
+			}
+		}
+		if err := reader.Close(); err != nil {
+			return 0, err
+		}
+		if hasEntries {
+			return max, nil
+		}
+	}
+
+	return 0, nil
+}
@@ -68,6 +68,72 @@ func TestFiles(t *testing.T) {
 	}
 }
 
+func TestSealedFiles(t *testing.T) {
+	start := time.Now().Truncate(10 * time.Minute)
+	files := []File{
+		{FilePath: "a", Start: start, Index: 0},
+		{FilePath: "b", Start: start.Add(10 * time.Minute), Index: 0},
+		{FilePath: "c", Start: start.Add(10 * time.Minute), Index: 1},
+		{FilePath: "d", Start: start.Add(-10 * time.Minute), Index: 0},
+	}
+
+	sealed := sealedFiles(files)
+	require.Equal(t, []File{files[0], files[1], files[3]}, sealed)
+	require.Equal(t, 0, len(sealedFiles(nil)))
+}
+
+func TestTimeRangeFilterPredicate(t *testing.T) {
+	start := time.Now().Truncate(10 * time.Minute)
+	pred := TimeRangeFilterPredicate(start, start.Add(20*time.Minute))
+
+	for _, test := range []struct {
+		start    time.Time
+		expected bool
+	}{
+		{start: start.Add(-20 * time.Minute), expected: false},
+		{start: start.Add(-10 * time.Minute), expected: false},
+		{start: start.Add(-5 * time.Minute), expected: true},
+		{start: start, expected: true},
+		{start: start.Add(10 * time.Minute), expected: true},
+		{start: start.Add(20 * time.Minute), expected: false},
+	} {
+		f := File{Start: test.start, Duration: 10 * time.Minute}
+		require.Equal(t, test.expected, pred(f), "file start %v", test.start)
+	}
+}
+
+func TestIteratorSealedOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "commitlogs")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	createTestCommitLogFiles(t, dir, 10*time.Minute, 5)
+
+	opts := NewOptions().SetBlockSize(10 * time.Minute)
+	opts = opts.SetFilesystemOptions(
+		opts.FilesystemOptions().
+			SetFilePathPrefix(dir),
+	)
+	files, err := Files(opts)
+	require.NoError(t, err)
+	require.Equal(t, 5, len(files))
+
+	var opened []File
+	iter, err := NewIterator(IteratorOpts{
+		CommitLogOptions: opts,
+		FileFilterPredicate: func(f File) bool {
+			opened = append(opened, f)
+			return true
+		},
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+		SealedOnly:            true,
+	})
+	require.NoError(t, err)
+	iter.Close()
+
+	require.Equal(t, files[:4], opened)
+}
+
 // createTestCommitLogFiles creates the specified number of commit log files
 // on disk with the appropriate block size. Commit log files will be valid
 // and contain readable metadata.
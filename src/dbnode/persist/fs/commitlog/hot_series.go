@@ -0,0 +1,174 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package commitlog
+
+import (
+	"container/heap"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// hotSeriesCapacity is the number of series tracked when sampling hot
+	// series, it bounds the memory used regardless of series cardinality
+	hotSeriesCapacity = 1024
+)
+
+// SeriesWriteStat is the approximate number of writes to a series, Writes is
+// an estimate that may overcount by up to MaxOverestimate
+type SeriesWriteStat struct {
+	Namespace       string
+	ID              string
+	Shard           uint32
+	Writes          uint64
+	MaxOverestimate uint64
+}
+
+// hotSeries tracks the most written series from a sample of writes using the
+// space saving algorithm. At most a fixed number of series are tracked, when
+// a write is sampled for an untracked series the series with the lowest count
+// is replaced and its count carried over as the new series' overestimate, so
+// any series written more than the total sampled writes divided by the
+// capacity is guaranteed to be tracked.
+type hotSeries struct {
+	sync.Mutex
+	rate     float64
+	rand     *rand.Rand
+	capacity int
+	entries  map[uint64]*hotSeriesEntry
+	byCount  hotSeriesEntries
+}
+
+type hotSeriesEntry struct {
+	uniqueIndex uint64
+	namespace   string
+	id          string
+	shard       uint32
+	count       uint64
+	overcount   uint64
+	heapIndex   int
+}
+
+func newHotSeries(rate float64, capacity int) *hotSeries {
+	return &hotSeries{
+		rate:     rate,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		capacity: capacity,
+		entries:  make(map[uint64]*hotSeriesEntry, capacity),
+		byCount:  make(hotSeriesEntries, 0, capacity),
+	}
+}
+
+// record samples a write to the series.
+func (h *hotSeries) record(series Series) {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.rate < 1 && h.rand.Float64() >= h.rate {
+		return
+	}
+
+	if entry, ok := h.entries[series.UniqueIndex]; ok {
+		entry.count++
+		heap.Fix(&h.byCount, entry.heapIndex)
+		return
+	}
+
+	if len(h.byCount) < h.capacity {
+		entry := &hotSeriesEntry{count: 1}
+		entry.reset(series)
+		h.entries[series.UniqueIndex] = entry
+		heap.Push(&h.byCount, entry)
+		return
+	}
+
+	// Replace the series with the lowest count
+	entry := h.byCount[0]
+	delete(h.entries, entry.uniqueIndex)
+	entry.reset(series)
+	entry.overcount = entry.count
+	entry.count++
+	h.entries[series.UniqueIndex] = entry
+	heap.Fix(&h.byCount, entry.heapIndex)
+}
+
+// top returns up to n of the most written series, most written first, with
+// counts scaled by the sampling rate.
+func (h *hotSeries) top(n int) []SeriesWriteStat {
+	h.Lock()
+	stats := make([]SeriesWriteStat, 0, len(h.byCount))
+	for _, entry := range h.byCount {
+		stats = append(stats, SeriesWriteStat{
+			Namespace:       entry.namespace,
+			ID:              entry.id,
+			Shard:           entry.shard,
+			Writes:          uint64(float64(entry.count) / h.rate),
+			MaxOverestimate: uint64(float64(entry.overcount) / h.rate),
+		})
+	}
+	h.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Writes > stats[j].Writes
+	})
+	if n < 0 {
+		n = 0
+	}
+	if n < len(stats) {
+		stats = stats[:n]
+	}
+	return stats
+}
+
+func (e *hotSeriesEntry) reset(series Series) {
+	e.uniqueIndex = series.UniqueIndex
+	e.namespace = series.Namespace.String()
+	e.id = series.ID.String()
+	e.shard = series.Shard
+}
+
+type hotSeriesEntries []*hotSeriesEntry
+
+func (e hotSeriesEntries) Len() int { return len(e) }
+
+func (e hotSeriesEntries) Less(i, j int) bool { return e[i].count < e[j].count }
+
+func (e hotSeriesEntries) Swap(i, j int) {
+	e[i], e[j] = e[j], e[i]
+	e[i].heapIndex = i
+	e[j].heapIndex = j
+}
+
+func (e *hotSeriesEntries) Push(x interface{}) {
+	entry := x.(*hotSeriesEntry)
+	entry.heapIndex = len(*e)
+	*e = append(*e, entry)
+}
+
+func (e *hotSeriesEntries) Pop() interface{} {
+	old := *e
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	*e = old[:n-1]
+	return entry
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package commitlog
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHotSeriesTop(t *testing.T) {
+	h := newHotSeries(1, 3)
+	record := func(idx uint64, times int) {
+		series := testSeries(idx, fmt.Sprintf("foo.%d", idx), testTags1, 127)
+		for i := 0; i < times; i++ {
+			h.record(series)
+		}
+	}
+
+	record(0, 5)
+	record(1, 3)
+	record(2, 1)
+
+	top := h.top(2)
+	require.Equal(t, []SeriesWriteStat{
+		{Namespace: "testNS", ID: "foo.0", Shard: 127, Writes: 5},
+		{Namespace: "testNS", ID: "foo.1", Shard: 127, Writes: 3},
+	}, top)
+
+	// A new series replaces the least written one and inherits its count as
+	// the maximum overestimate
+	record(3, 5)
+	top = h.top(10)
+	require.Len(t, top, 3)
+	require.Equal(t, SeriesWriteStat{
+		Namespace:       "testNS",
+		ID:              "foo.3",
+		Shard:           127,
+		Writes:          6,
+		MaxOverestimate: 1,
+	}, top[0])
+	require.Equal(t, "foo.0", top[1].ID)
+	require.Equal(t, "foo.1", top[2].ID)
+
+	require.Empty(t, h.top(0))
+	require.Empty(t, h.top(-1))
+}
+
+func TestHotSeriesSampling(t *testing.T) {
+	h := newHotSeries(0.5, 3)
+	series := testSeries(0, "foo.0", testTags1, 127)
+	for i := 0; i < 10000; i++ {
+		h.record(series)
+	}
+
+	// Counts are scaled up by the sampling rate
+	top := h.top(1)
+	require.Len(t, top, 1)
+	require.InDelta(t, 10000, float64(top[0].Writes), 1000)
+}
@@ -22,10 +22,14 @@ package commitlog
 
 import (
 	"errors"
+	"fmt"
 	"io"
+	"time"
 
+	"github.com/m3db/m3db/src/dbnode/clock"
 	"github.com/m3db/m3db/src/dbnode/ts"
 	xlog "github.com/m3db/m3x/log"
+	"github.com/m3db/m3x/pool"
 	xtime "github.com/m3db/m3x/time"
 
 	"github.com/uber-go/tally"
@@ -38,28 +42,68 @@ var (
 )
 
 type iteratorMetrics struct {
-	readsErrors tally.Counter
+	readsErrors  tally.Counter
+	corruptFiles tally.Counter
+}
+
+type iteratorReadMetrics struct {
+	records          tally.Counter
+	bytes            tally.Counter
+	filesOpened      tally.Counter
+	decodeErrors     tally.Counter
+	fileReadDuration tally.Timer
+}
+
+func newIteratorReadMetrics(scope tally.Scope) iteratorReadMetrics {
+	return iteratorReadMetrics{
+		records:          scope.Counter("reads.records"),
+		bytes:            scope.Counter("reads.bytes"),
+		filesOpened:      scope.Counter("reads.files-opened"),
+		decodeErrors:     scope.Counter("reads.decode-errors"),
+		fileReadDuration: scope.Timer("reads.file-duration"),
+	}
 }
 
 type iterator struct {
-	opts       Options
-	scope      tally.Scope
-	metrics    iteratorMetrics
-	log        xlog.Logger
-	files      []File
-	reader     commitLogReader
-	read       iteratorRead
-	err        error
-	seriesPred SeriesFilterPredicate
-	setRead    bool
-	closed     bool
+	opts        Options
+	pool        pool.BytesPool
+	scope       tally.Scope
+	metrics     iteratorMetrics
+	readMetrics iteratorReadMetrics
+	nowFn       clock.NowFn
+	log         xlog.Logger
+	files       []File
+	parallel    *parallelFileReader
+	reader      entryReader
+	readerStart time.Time
+	read        iteratorRead
+	err         error
+	seriesPred  SeriesAnnotationFilterPredicate
+	valuePred   ValueFilterPredicate
+	codec       AnnotationCodec
+	readMode    ReadMode
+	corrupt     int
+	setRead     bool
+	closed      bool
+}
+
+// entryReader reads the entries of a single commit log file or stream
+type entryReader interface {
+	Read() (Series, ts.Datapoint, xtime.Unit, ts.Annotation, error)
+	SequenceNumber() uint64
+	AnnotationVersion() uint32
+	BytesRead() int64
+	Close() error
 }
 
 type iteratorRead struct {
-	series     Series
-	datapoint  ts.Datapoint
-	unit       xtime.Unit
-	annotation []byte
+	series            Series
+	datapoint         ts.Datapoint
+	unit              xtime.Unit
+	annotation        []byte
+	decodedAnnotation interface{}
+	sequenceNumber    uint64
+	annotationVersion uint32
 }
 
 // ReadAllPredicate can be passed as the ReadCommitLogPredicate for callers
@@ -68,63 +112,183 @@ func ReadAllPredicate() FileFilterPredicate {
 	return func(_ File) bool { return true }
 }
 
-// NewIterator creates a new commit log iterator
+// TimeRangeFilterPredicate returns a predicate that only reads the commitlogs
+// whose block, from the file start for the file duration, overlaps the time
+// range [start, end)
+func TimeRangeFilterPredicate(start, end time.Time) FileFilterPredicate {
+	return func(f File) bool {
+		return f.Start.Before(end) && f.Start.Add(f.Duration).After(start)
+	}
+}
+
+// NewIterator creates a new commit log iterator, which returns entries in the
+// order selected by the iterator options OrderBy field
 func NewIterator(iterOpts IteratorOpts) (Iterator, error) {
+	if iterOpts.OrderBy == GlobalTimestampOrder {
+		return newOrderedIterator(iterOpts)
+	}
+
 	opts := iterOpts.CommitLogOptions
 	iops := opts.InstrumentOptions()
+	if iterOpts.InstrumentOptions != nil {
+		iops = iterOpts.InstrumentOptions
+	}
 	iops = iops.SetMetricsScope(iops.MetricsScope().SubScope("iterator"))
 
 	files, err := Files(opts)
 	if err != nil {
 		return nil, err
 	}
+	if iterOpts.SealedOnly {
+		files = sealedFiles(files)
+	}
 	filteredFiles := filterFiles(opts, files, iterOpts.FileFilterPredicate)
 
+	scope := iops.MetricsScope()
+	readScope := tally.NoopScope
+	if iterOpts.InstrumentOptions != nil {
+		readScope = scope
+	}
+	iter := &iterator{
+		opts:  opts,
+		pool:  iterOpts.AnnotationBytesPool,
+		scope: scope,
+		metrics: iteratorMetrics{
+			readsErrors:  scope.Counter("reads.errors"),
+			corruptFiles: scope.Counter("reads.corrupt-files"),
+		},
+		readMetrics: newIteratorReadMetrics(readScope),
+		nowFn:       opts.ClockOptions().NowFn(),
+		log:         iops.Logger(),
+		files:       filteredFiles,
+		seriesPred:  combineSeriesPredicates(iterOpts.SeriesFilterPredicate, iterOpts.SeriesAnnotationFilterPredicate),
+		valuePred:   iterOpts.ValueFilterPredicate,
+		codec:       opts.AnnotationCodec(),
+		readMode:    iterOpts.ReadMode,
+	}
+	if iterOpts.Concurrency > 1 && len(filteredFiles) > 1 {
+		iter.parallel = newParallelFileReader(opts, iter.seriesPred, iter.pool,
+			filteredFiles, iterOpts.Concurrency)
+	}
+	return iter, nil
+}
+
+// NewReader creates a commit log iterator that returns the entries of a single
+// encoded commit log stream, such as one written by a writer created with
+// NewCommitLogWriter, the stream is not closed when the iterator is closed
+func NewReader(r io.Reader, opts Options) (Iterator, error) {
+	iops := opts.InstrumentOptions()
+	iops = iops.SetMetricsScope(iops.MetricsScope().SubScope("iterator"))
+
+	reader := newCommitLogReader(opts, combineSeriesPredicates(ReadAllSeriesPredicate(), nil), nil)
+	if _, _, _, err := reader.OpenStream(r); err != nil {
+		return nil, err
+	}
+
 	scope := iops.MetricsScope()
 	return &iterator{
 		opts:  opts,
 		scope: scope,
 		metrics: iteratorMetrics{
-			readsErrors: scope.Counter("reads.errors"),
+			readsErrors:  scope.Counter("reads.errors"),
+			corruptFiles: scope.Counter("reads.corrupt-files"),
 		},
-		log:        iops.Logger(),
-		files:      filteredFiles,
-		seriesPred: iterOpts.SeriesFilterPredicate,
+		readMetrics: newIteratorReadMetrics(tally.NoopScope),
+		nowFn:       opts.ClockOptions().NowFn(),
+		log:         iops.Logger(),
+		reader:      reader,
+		codec:       opts.AnnotationCodec(),
 	}, nil
 }
 
-func (i *iterator) Next() bool {
-	if i.hasError() || i.closed {
-		return false
+// ForEach iterates the commit logs selected by the iterator options and
+// calls fn with each entry in the order selected by the OrderBy field. The
+// annotation is only valid for the duration of the call if the iterator
+// options include an annotation bytes pool. Iteration stops at the first
+// error returned by fn, which is returned, otherwise the iterator error is
+// returned once all entries have been read.
+func ForEach(
+	iterOpts IteratorOpts,
+	fn func(Series, ts.Datapoint, xtime.Unit, ts.Annotation) error,
+) error {
+	iter, err := NewIterator(iterOpts)
+	if err != nil {
+		return err
 	}
-	if i.reader == nil {
-		if !i.nextReader() {
-			return false
+	defer iter.Close()
+
+	for iter.Next() {
+		if err := fn(iter.Current()); err != nil {
+			return err
 		}
 	}
-	var err error
-	i.read.series, i.read.datapoint, i.read.unit, i.read.annotation, err = i.reader.Read()
-	if err == io.EOF {
-		closeErr := i.closeAndResetReader()
-		if closeErr != nil {
-			i.err = closeErr
+	return iter.Err()
+}
+
+func (i *iterator) Next() bool {
+	for {
+		if i.hasError() || i.closed {
+			return false
 		}
-		// Try the next reader
-		return i.Next()
-	}
-	if err != nil {
-		// Try the next reader, this enables restoring with best effort from commit logs
-		i.metrics.readsErrors.Inc(1)
-		i.log.Errorf("commit log reader returned error, iterator moving to next file: %v", err)
-		i.err = err
-		closeErr := i.closeAndResetReader()
-		if closeErr != nil {
-			i.err = closeErr
+		if i.reader == nil {
+			if !i.nextReader() {
+				return false
+			}
+		}
+		i.releaseAnnotation()
+		var err error
+		i.read.series, i.read.datapoint, i.read.unit, i.read.annotation, err = i.reader.Read()
+		if err == io.EOF {
+			closeErr := i.closeAndResetReader()
+			if closeErr != nil {
+				i.err = closeErr
+			}
+			// Try the next reader
+			continue
+		}
+		if err != nil {
+			i.readMetrics.decodeErrors.Inc(1)
+		}
+		if err != nil && i.readMode == ReadModeSkipCorruptTail {
+			// Skip the rest of the file, the entry is most likely a partial
+			// record at the tail of a file that was not fully flushed
+			i.corrupt++
+			i.metrics.corruptFiles.Inc(1)
+			i.log.Warnf("commit log reader returned error, skipping rest of file: %v", err)
+			closeErr := i.closeAndResetReader()
+			if closeErr != nil {
+				i.err = closeErr
+			}
+			continue
 		}
-		return i.Next()
+		if err != nil {
+			// Try the next reader, this enables restoring with best effort from commit logs
+			i.metrics.readsErrors.Inc(1)
+			i.log.Errorf("commit log reader returned error, iterator moving to next file: %v", err)
+			i.err = err
+			closeErr := i.closeAndResetReader()
+			if closeErr != nil {
+				i.err = closeErr
+			}
+			continue
+		}
+		i.readMetrics.records.Inc(1)
+		if i.valuePred != nil && !i.valuePred(i.read.datapoint) {
+			// Skip datapoints the caller is not interested in
+			continue
+		}
+		i.read.sequenceNumber = i.reader.SequenceNumber()
+		i.read.annotationVersion = i.reader.AnnotationVersion()
+		i.read.decodedAnnotation, err = decodeAnnotation(i.codec, i.read.annotation)
+		if err != nil {
+			i.metrics.readsErrors.Inc(1)
+			i.readMetrics.decodeErrors.Inc(1)
+			i.err = err
+			return false
+		}
+		i.setRead = true
+		return true
 	}
-	i.setRead = true
-	return true
 }
 
 func (i *iterator) Current() (Series, ts.Datapoint, xtime.Unit, ts.Annotation) {
@@ -135,6 +299,31 @@ func (i *iterator) Current() (Series, ts.Datapoint, xtime.Unit, ts.Annotation) {
 	return read.series, read.datapoint, read.unit, read.annotation
 }
 
+func (i *iterator) DecodedAnnotation() interface{} {
+	if i.hasError() || i.closed || !i.setRead {
+		return nil
+	}
+	return i.read.decodedAnnotation
+}
+
+func (i *iterator) SequenceNumber() uint64 {
+	if i.hasError() || i.closed || !i.setRead {
+		return 0
+	}
+	return i.read.sequenceNumber
+}
+
+func (i *iterator) AnnotationVersion() uint32 {
+	if i.hasError() || i.closed || !i.setRead {
+		return 0
+	}
+	return i.read.annotationVersion
+}
+
+func (i *iterator) Corrupt() int {
+	return i.corrupt
+}
+
 func (i *iterator) Err() error {
 	return i.err
 }
@@ -145,9 +334,22 @@ func (i *iterator) Close() {
 		return
 	}
 	i.closed = true
+	i.releaseAnnotation()
+	if i.parallel != nil {
+		i.parallel.Close()
+	}
 	i.closeAndResetReader()
 }
 
+// releaseAnnotation returns the last read annotation to the annotation
+// bytes pool, if one is configured.
+func (i *iterator) releaseAnnotation() {
+	if i.pool != nil && i.read.annotation != nil {
+		i.pool.Put(i.read.annotation)
+	}
+	i.read.annotation = nil
+}
+
 func (i *iterator) hasError() bool {
 	return i.err != nil
 }
@@ -166,28 +368,65 @@ func (i *iterator) nextReader() bool {
 	file := i.files[0]
 	i.files = i.files[1:]
 
-	t, idx := file.Start, file.Index
-	reader := newCommitLogReader(i.opts, i.seriesPred)
-	start, duration, index, err := reader.Open(file.FilePath)
+	var reader entryReader
+	if i.parallel != nil {
+		reader, err = i.parallel.nextReader()
+	} else {
+		reader, err = openReader(i.opts, i.seriesPred, i.pool, file)
+	}
 	if err != nil {
 		i.err = err
 		return false
 	}
-	if !t.Equal(start) {
-		i.err = errStartDoesNotMatch
-		return false
+
+	i.readMetrics.filesOpened.Inc(1)
+	i.reader = reader
+	i.readerStart = i.nowFn()
+	return true
+}
+
+// decodeAnnotation decodes the annotation with the codec, returning nil if
+// there is no codec configured or no annotation to decode.
+func decodeAnnotation(codec AnnotationCodec, annotation ts.Annotation) (interface{}, error) {
+	if codec == nil || len(annotation) == 0 {
+		return nil, nil
 	}
-	if duration != i.opts.BlockSize() {
-		i.err = errDurationDoesNotMatch
-		return false
+	decoded, err := codec.Decode(annotation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode commit log annotation: %v", err)
 	}
-	if index != idx {
-		i.err = errIndexDoesNotMatch
-		return false
+	return decoded, nil
+}
+
+// openReader opens a commit log reader for the file and validates that the
+// file's log info matches its metadata.
+func openReader(
+	opts Options,
+	seriesPred SeriesAnnotationFilterPredicate,
+	annotationPool pool.BytesPool,
+	file File,
+) (commitLogReader, error) {
+	reader := newCommitLogReader(opts, seriesPred, annotationPool)
+	start, duration, index, err := reader.Open(file.FilePath)
+	if err != nil {
+		return nil, err
 	}
 
-	i.reader = reader
-	return true
+	var validateErr error
+	switch {
+	case !file.Start.Equal(start):
+		validateErr = errStartDoesNotMatch
+	case duration != opts.BlockSize():
+		validateErr = errDurationDoesNotMatch
+	case index != file.Index:
+		validateErr = errIndexDoesNotMatch
+	}
+	if validateErr != nil {
+		reader.Close()
+		return nil, validateErr
+	}
+
+	return reader, nil
 }
 
 func filterFiles(opts Options, files []File, predicate FileFilterPredicate) []File {
@@ -206,5 +445,10 @@ func (i *iterator) closeAndResetReader() error {
 	}
 	reader := i.reader
 	i.reader = nil
-	return reader.Close()
+	err := reader.Close()
+	i.readMetrics.bytes.Inc(reader.BytesRead())
+	if !i.readerStart.IsZero() {
+		i.readMetrics.fileReadDuration.Record(i.nowFn().Sub(i.readerStart))
+	}
+	return err
 }
@@ -0,0 +1,294 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package commitlog
+
+import (
+	"container/heap"
+	"io"
+
+	"github.com/m3db/m3db/src/dbnode/ts"
+	xerrors "github.com/m3db/m3x/errors"
+	xlog "github.com/m3db/m3x/log"
+	"github.com/m3db/m3x/pool"
+	xtime "github.com/m3db/m3x/time"
+)
+
+// mergedIterator performs a k-way merge across all commit log files ordered
+// by datapoint timestamp. Each file is read with a read concurrency of one so
+// that entries within a file are returned in the order they were written.
+type mergedIterator struct {
+	metrics   iteratorMetrics
+	log       xlog.Logger
+	pool      pool.BytesPool
+	entries   mergedIteratorEntries
+	last      *mergedIteratorEntry
+	read      iteratorRead
+	err       error
+	valuePred ValueFilterPredicate
+	codec     AnnotationCodec
+	readMode  ReadMode
+	corrupt   int
+	setRead   bool
+	closed    bool
+}
+
+type mergedIteratorEntry struct {
+	reader    commitLogReader
+	fileOrder int
+	read      iteratorRead
+}
+
+// NewMergedIterator creates a new commit log iterator that returns entries
+// from all commit log files in global timestamp order. Unlike the iterator
+// returned by NewIterator, which reads one file at a time with the configured
+// read concurrency and only guarantees ordering per series, this iterator
+// keeps every file open at once and decodes each file serially. Memory grows
+// linearly with the number of files, each open file has its own reader which
+// reads ahead up to a few thousand entries into its decoder queues and keeps
+// its decode buffers, about a megabyte per file with the default options, in
+// addition to the entry at the head of the merge. Throughput is lower than
+// the default iterator and open file descriptors grow with the number of
+// files. Entries are globally ordered provided each file's entries
+// were written in timestamp order, out of order writes within a file are
+// returned in the order they were written.
+func NewMergedIterator(iterOpts IteratorOpts) (Iterator, error) {
+	opts := iterOpts.CommitLogOptions
+	iops := opts.InstrumentOptions()
+	iops = iops.SetMetricsScope(iops.MetricsScope().SubScope("iterator"))
+
+	files, err := Files(opts)
+	if err != nil {
+		return nil, err
+	}
+	if iterOpts.SealedOnly {
+		files = sealedFiles(files)
+	}
+	filteredFiles := filterFiles(opts, files, iterOpts.FileFilterPredicate)
+
+	scope := iops.MetricsScope()
+	iter := &mergedIterator{
+		metrics: iteratorMetrics{
+			readsErrors:  scope.Counter("reads.errors"),
+			corruptFiles: scope.Counter("reads.corrupt-files"),
+		},
+		log:       iops.Logger(),
+		pool:      iterOpts.AnnotationBytesPool,
+		entries:   make(mergedIteratorEntries, 0, len(filteredFiles)),
+		valuePred: iterOpts.ValueFilterPredicate,
+		codec:     opts.AnnotationCodec(),
+		readMode:  iterOpts.ReadMode,
+	}
+
+	var (
+		readerOpts = opts.SetReadConcurrency(1)
+		seriesPred = combineSeriesPredicates(iterOpts.SeriesFilterPredicate,
+			iterOpts.SeriesAnnotationFilterPredicate)
+	)
+	for idx, file := range filteredFiles {
+		reader, err := openReader(readerOpts, seriesPred, iter.pool, file)
+		if err != nil {
+			iter.Close()
+			return nil, err
+		}
+
+		entry := &mergedIteratorEntry{reader: reader, fileOrder: idx}
+		if err := iter.advanceOrSkip(entry); err != nil {
+			iter.Close()
+			return nil, err
+		}
+	}
+
+	return iter, nil
+}
+
+func (i *mergedIterator) Next() bool {
+	for {
+		if i.err != nil || i.closed {
+			return false
+		}
+
+		if i.last != nil {
+			entry := i.last
+			i.last = nil
+			i.releaseAnnotation(entry)
+			if err := i.advanceOrSkip(entry); err != nil {
+				i.metrics.readsErrors.Inc(1)
+				i.log.Errorf("commit log reader returned error, merged iterator stopping: %v", err)
+				i.err = err
+				return false
+			}
+		}
+
+		if len(i.entries) == 0 {
+			return false
+		}
+
+		entry := heap.Pop(&i.entries).(*mergedIteratorEntry)
+		i.last = entry
+		if i.valuePred != nil && !i.valuePred(entry.read.datapoint) {
+			// Skip datapoints the caller is not interested in
+			continue
+		}
+
+		decoded, err := decodeAnnotation(i.codec, entry.read.annotation)
+		if err != nil {
+			i.metrics.readsErrors.Inc(1)
+			i.err = err
+			return false
+		}
+
+		i.read = entry.read
+		i.read.decodedAnnotation = decoded
+		i.setRead = true
+		return true
+	}
+}
+
+func (i *mergedIterator) Current() (Series, ts.Datapoint, xtime.Unit, ts.Annotation) {
+	read := i.read
+	if i.err != nil || i.closed || !i.setRead {
+		read = iteratorRead{}
+	}
+	return read.series, read.datapoint, read.unit, read.annotation
+}
+
+func (i *mergedIterator) DecodedAnnotation() interface{} {
+	if i.err != nil || i.closed || !i.setRead {
+		return nil
+	}
+	return i.read.decodedAnnotation
+}
+
+func (i *mergedIterator) SequenceNumber() uint64 {
+	if i.err != nil || i.closed || !i.setRead {
+		return 0
+	}
+	return i.read.sequenceNumber
+}
+
+func (i *mergedIterator) AnnotationVersion() uint32 {
+	if i.err != nil || i.closed || !i.setRead {
+		return 0
+	}
+	return i.read.annotationVersion
+}
+
+func (i *mergedIterator) Corrupt() int {
+	return i.corrupt
+}
+
+func (i *mergedIterator) Err() error {
+	return i.err
+}
+
+func (i *mergedIterator) Close() {
+	if i.closed {
+		return
+	}
+	i.closed = true
+
+	var multiErr xerrors.MultiError
+	if i.last != nil {
+		i.releaseAnnotation(i.last)
+		multiErr = multiErr.Add(i.last.reader.Close())
+		i.last = nil
+	}
+	for _, entry := range i.entries {
+		multiErr = multiErr.Add(entry.reader.Close())
+	}
+	i.entries = nil
+	if err := multiErr.FinalError(); err != nil {
+		i.log.Errorf("failed to close merged commit log iterator readers: %v", err)
+	}
+}
+
+// releaseAnnotation returns the entry's annotation to the annotation bytes
+// pool, if one is configured.
+func (i *mergedIterator) releaseAnnotation(entry *mergedIteratorEntry) {
+	if i.pool != nil && entry.read.annotation != nil {
+		i.pool.Put(entry.read.annotation)
+	}
+	entry.read.annotation = nil
+}
+
+// advanceOrSkip advances the entry, when reading with ReadModeSkipCorruptTail
+// a read error instead drops the rest of the entry's file.
+func (i *mergedIterator) advanceOrSkip(entry *mergedIteratorEntry) error {
+	err := i.advance(entry)
+	if err == nil || i.readMode != ReadModeSkipCorruptTail {
+		return err
+	}
+	i.corrupt++
+	i.metrics.corruptFiles.Inc(1)
+	i.log.Warnf("commit log reader returned error, skipping rest of file: %v", err)
+	return nil
+}
+
+// advance reads the next entry from the entry's reader and pushes it back
+// onto the heap, closing the reader if it has been exhausted.
+func (i *mergedIterator) advance(entry *mergedIteratorEntry) error {
+	series, datapoint, unit, annotation, err := entry.reader.Read()
+	if err == io.EOF {
+		return entry.reader.Close()
+	}
+	if err != nil {
+		entry.reader.Close()
+		return err
+	}
+
+	entry.read = iteratorRead{
+		series:            series,
+		datapoint:         datapoint,
+		unit:              unit,
+		annotation:        annotation,
+		sequenceNumber:    entry.reader.SequenceNumber(),
+		annotationVersion: entry.reader.AnnotationVersion(),
+	}
+	heap.Push(&i.entries, entry)
+	return nil
+}
+
+type mergedIteratorEntries []*mergedIteratorEntry
+
+func (e mergedIteratorEntries) Len() int { return len(e) }
+
+func (e mergedIteratorEntries) Less(i, j int) bool {
+	ti, tj := e[i].read.datapoint.Timestamp, e[j].read.datapoint.Timestamp
+	if ti.Equal(tj) {
+		return e[i].fileOrder < e[j].fileOrder
+	}
+	return ti.Before(tj)
+}
+
+func (e mergedIteratorEntries) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+
+func (e *mergedIteratorEntries) Push(x interface{}) {
+	*e = append(*e, x.(*mergedIteratorEntry))
+}
+
+func (e *mergedIteratorEntries) Pop() interface{} {
+	old := *e
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	*e = old[:n-1]
+	return entry
+}
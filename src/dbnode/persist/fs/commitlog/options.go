@@ -50,6 +50,23 @@ const (
 
 	// defaultReadConcurrency is the default read concurrency
 	defaultReadConcurrency = 4
+
+	// defaultValidFuture is the default of how far after the current time a
+	// write timestamp may be
+	defaultValidFuture = 24 * time.Hour
+
+	// defaultBacklogQueueFullPolicy is the default backlog queue full policy
+	defaultBacklogQueueFullPolicy = BacklogQueueFullReject
+
+	// defaultBacklogQueueFullTimeout is the default time a write waits for
+	// space in a full backlog queue with the block policy
+	defaultBacklogQueueFullTimeout = time.Second
+
+	// defaultFlushRetries is the default number of times a failed flush is retried
+	defaultFlushRetries = 3
+
+	// defaultFlushRetryBackoff is the default backoff before retrying a failed flush
+	defaultFlushRetryBackoff = 100 * time.Millisecond
 )
 
 var (
@@ -58,26 +75,44 @@ var (
 )
 
 var (
-	errFlushIntervalNonNegative       = errors.New("flush interval must be non-negative")
-	errBlockSizePositive              = errors.New("block size must be a positive duration")
-	errRetentionPeriodPositive        = errors.New("retention period must be a positive duration")
-	errRetentionGreaterEqualBlockSize = errors.New("retention period must be >= block size")
-	errReadConcurrencyPositive        = errors.New("read concurrency must be a positive integer")
+	errFlushIntervalNonNegative        = errors.New("flush interval must be non-negative")
+	errFsyncIntervalNonNegative        = errors.New("fsync interval must be non-negative")
+	errBlockSizePositive               = errors.New("block size must be a positive duration")
+	errRetentionPeriodPositive         = errors.New("retention period must be a positive duration")
+	errRetentionGreaterEqualBlockSize  = errors.New("retention period must be >= block size")
+	errReadConcurrencyPositive         = errors.New("read concurrency must be a positive integer")
+	errBacklogQueueFullTimeoutPositive = errors.New("backlog queue full timeout must be a positive duration")
+	errFlushRetriesNonNegative         = errors.New("flush retries must be non-negative")
+	errFlushRetryBackoffNonNegative    = errors.New("flush retry backoff must be non-negative")
+	errValidTimeWindowNonNegative      = errors.New("valid time window must be non-negative")
+	errHotSeriesSamplingRate           = errors.New("hot series sampling rate must be between 0 and 1")
 )
 
 type options struct {
-	clockOpts        clock.Options
-	instrumentOpts   instrument.Options
-	retentionPeriod  time.Duration
-	blockSize        time.Duration
-	fsOpts           fs.Options
-	strategy         Strategy
-	flushSize        int
-	flushInterval    time.Duration
-	backlogQueueSize int
-	bytesPool        pool.CheckedBytesPool
-	identPool        ident.Pool
-	readConcurrency  int
+	clockOpts          clock.Options
+	instrumentOpts     instrument.Options
+	retentionPeriod    time.Duration
+	blockSize          time.Duration
+	fsOpts             fs.Options
+	strategy           Strategy
+	flushSize          int
+	flushInterval      time.Duration
+	fsyncInterval      time.Duration
+	backlogQueueSize   int
+	backlogFullPolicy  BacklogQueueFullPolicy
+	backlogFullTimeout time.Duration
+	bytesPool          pool.CheckedBytesPool
+	identPool          ident.Pool
+	readConcurrency    int
+	annotationCodec    AnnotationCodec
+	annotationVersion  uint32
+	flushRetries       int
+	flushRetryBackoff  time.Duration
+	sequenceNumbers    bool
+	rotationCallback   RotationCallbackFn
+	validPast          time.Duration
+	validFuture        time.Duration
+	hotSeriesRate      float64
 }
 
 // NewOptions creates new commit log options
@@ -95,7 +130,12 @@ func NewOptions() Options {
 		bytesPool: pool.NewCheckedBytesPool(nil, nil, func(s []pool.Bucket) pool.BytesPool {
 			return pool.NewBytesPool(s, nil)
 		}),
-		readConcurrency: defaultReadConcurrency,
+		backlogFullPolicy:  defaultBacklogQueueFullPolicy,
+		backlogFullTimeout: defaultBacklogQueueFullTimeout,
+		readConcurrency:    defaultReadConcurrency,
+		validFuture:        defaultValidFuture,
+		flushRetries:       defaultFlushRetries,
+		flushRetryBackoff:  defaultFlushRetryBackoff,
 	}
 	o.bytesPool.Init()
 	o.identPool = ident.NewPool(o.bytesPool, ident.PoolOptions{})
@@ -106,6 +146,9 @@ func (o *options) Validate() error {
 	if o.FlushInterval() < 0 {
 		return errFlushIntervalNonNegative
 	}
+	if o.FsyncInterval() < 0 {
+		return errFsyncIntervalNonNegative
+	}
 	if o.BlockSize() <= 0 {
 		return errBlockSizePositive
 	}
@@ -118,6 +161,21 @@ func (o *options) Validate() error {
 	if o.ReadConcurrency() <= 0 {
 		return errReadConcurrencyPositive
 	}
+	if o.BacklogQueueFullTimeout() <= 0 {
+		return errBacklogQueueFullTimeoutPositive
+	}
+	if o.FlushRetries() < 0 {
+		return errFlushRetriesNonNegative
+	}
+	if o.FlushRetryBackoff() < 0 {
+		return errFlushRetryBackoffNonNegative
+	}
+	if past, future := o.ValidTimeWindow(); past < 0 || future < 0 {
+		return errValidTimeWindowNonNegative
+	}
+	if rate := o.HotSeriesSampling(); rate < 0 || rate > 1 {
+		return errHotSeriesSamplingRate
+	}
 	return nil
 }
 
@@ -201,6 +259,16 @@ func (o *options) FlushInterval() time.Duration {
 	return o.flushInterval
 }
 
+func (o *options) SetFsyncInterval(value time.Duration) Options {
+	opts := *o
+	opts.fsyncInterval = value
+	return &opts
+}
+
+func (o *options) FsyncInterval() time.Duration {
+	return o.fsyncInterval
+}
+
 func (o *options) SetBacklogQueueSize(value int) Options {
 	opts := *o
 	opts.backlogQueueSize = value
@@ -211,6 +279,26 @@ func (o *options) BacklogQueueSize() int {
 	return o.backlogQueueSize
 }
 
+func (o *options) SetBacklogQueueFullPolicy(value BacklogQueueFullPolicy) Options {
+	opts := *o
+	opts.backlogFullPolicy = value
+	return &opts
+}
+
+func (o *options) BacklogQueueFullPolicy() BacklogQueueFullPolicy {
+	return o.backlogFullPolicy
+}
+
+func (o *options) SetBacklogQueueFullTimeout(value time.Duration) Options {
+	opts := *o
+	opts.backlogFullTimeout = value
+	return &opts
+}
+
+func (o *options) BacklogQueueFullTimeout() time.Duration {
+	return o.backlogFullTimeout
+}
+
 func (o *options) SetBytesPool(value pool.CheckedBytesPool) Options {
 	opts := *o
 	opts.bytesPool = value
@@ -240,3 +328,84 @@ func (o *options) SetIdentifierPool(value ident.Pool) Options {
 func (o *options) IdentifierPool() ident.Pool {
 	return o.identPool
 }
+
+func (o *options) SetAnnotationCodec(value AnnotationCodec) Options {
+	opts := *o
+	opts.annotationCodec = value
+	return &opts
+}
+
+func (o *options) AnnotationCodec() AnnotationCodec {
+	return o.annotationCodec
+}
+
+func (o *options) SetAnnotationVersion(value uint32) Options {
+	opts := *o
+	opts.annotationVersion = value
+	return &opts
+}
+
+func (o *options) AnnotationVersion() uint32 {
+	return o.annotationVersion
+}
+
+func (o *options) SetFlushRetries(value int) Options {
+	opts := *o
+	opts.flushRetries = value
+	return &opts
+}
+
+func (o *options) FlushRetries() int {
+	return o.flushRetries
+}
+
+func (o *options) SetFlushRetryBackoff(value time.Duration) Options {
+	opts := *o
+	opts.flushRetryBackoff = value
+	return &opts
+}
+
+func (o *options) FlushRetryBackoff() time.Duration {
+	return o.flushRetryBackoff
+}
+
+func (o *options) SetSequenceNumbersEnabled(value bool) Options {
+	opts := *o
+	opts.sequenceNumbers = value
+	return &opts
+}
+
+func (o *options) SequenceNumbersEnabled() bool {
+	return o.sequenceNumbers
+}
+
+func (o *options) SetRotationCallback(value RotationCallbackFn) Options {
+	opts := *o
+	opts.rotationCallback = value
+	return &opts
+}
+
+func (o *options) RotationCallback() RotationCallbackFn {
+	return o.rotationCallback
+}
+
+func (o *options) SetValidTimeWindow(past, future time.Duration) Options {
+	opts := *o
+	opts.validPast = past
+	opts.validFuture = future
+	return &opts
+}
+
+func (o *options) ValidTimeWindow() (time.Duration, time.Duration) {
+	return o.validPast, o.validFuture
+}
+
+func (o *options) SetHotSeriesSampling(rate float64) Options {
+	opts := *o
+	opts.hotSeriesRate = rate
+	return &opts
+}
+
+func (o *options) HotSeriesSampling() float64 {
+	return o.hotSeriesRate
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package commitlog
+
+import (
+	"container/heap"
+	"errors"
+	"time"
+
+	"github.com/m3db/m3db/src/dbnode/ts"
+	xlog "github.com/m3db/m3x/log"
+	xtime "github.com/m3db/m3x/time"
+)
+
+const (
+	defaultOrderBufferSize = 65536
+)
+
+var (
+	// ErrOrderBufferExceeded is returned by an iterator reading in
+	// GlobalTimestampOrder when an entry is older than one already returned,
+	// meaning entries are further out of order than the order buffer allows
+	ErrOrderBufferExceeded = errors.New("commit log entries out of order beyond order buffer size")
+)
+
+// orderedIterator reorders the entries of a merged iterator into global
+// timestamp order. The merged iterator orders entries across files, the
+// ordered iterator additionally reorders out of order writes within a file
+// by buffering up to a fixed number of entries in a min heap.
+type orderedIterator struct {
+	metrics  iteratorMetrics
+	log      xlog.Logger
+	iter     Iterator
+	entries  orderedIteratorEntries
+	size     int
+	pushed   uint64
+	last     time.Time
+	read     iteratorRead
+	err      error
+	drained  bool
+	returned bool
+	setRead  bool
+	closed   bool
+}
+
+type orderedIteratorEntry struct {
+	read  iteratorRead
+	order uint64
+}
+
+func newOrderedIterator(iterOpts IteratorOpts) (Iterator, error) {
+	size := iterOpts.OrderBufferSize
+	if size <= 0 {
+		size = defaultOrderBufferSize
+	}
+
+	// Buffered entries outlive the next call to the merged iterator's Next
+	// so their annotations cannot be returned to a pool
+	iterOpts.AnnotationBytesPool = nil
+	iter, err := NewMergedIterator(iterOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	iops := iterOpts.CommitLogOptions.InstrumentOptions()
+	scope := iops.MetricsScope().SubScope("iterator")
+	return &orderedIterator{
+		metrics: iteratorMetrics{
+			readsErrors: scope.Counter("reads.errors"),
+		},
+		log:  iops.Logger(),
+		iter: iter,
+		size: size,
+	}, nil
+}
+
+func (i *orderedIterator) Next() bool {
+	if i.err != nil || i.closed {
+		return false
+	}
+
+	for !i.drained && len(i.entries) < i.size {
+		if !i.iter.Next() {
+			i.drained = true
+			if err := i.iter.Err(); err != nil {
+				i.err = err
+				return false
+			}
+			break
+		}
+
+		series, datapoint, unit, annotation := i.iter.Current()
+		if i.returned && datapoint.Timestamp.Before(i.last) {
+			i.metrics.readsErrors.Inc(1)
+			i.log.Errorf("commit log entry at %v read after entry at %v, ordered iterator stopping",
+				datapoint.Timestamp, i.last)
+			i.err = ErrOrderBufferExceeded
+			return false
+		}
+
+		heap.Push(&i.entries, orderedIteratorEntry{
+			read: iteratorRead{
+				series:            series,
+				datapoint:         datapoint,
+				unit:              unit,
+				annotation:        annotation,
+				decodedAnnotation: i.iter.DecodedAnnotation(),
+				sequenceNumber:    i.iter.SequenceNumber(),
+				annotationVersion: i.iter.AnnotationVersion(),
+			},
+			order: i.pushed,
+		})
+		i.pushed++
+	}
+
+	if len(i.entries) == 0 {
+		return false
+	}
+
+	entry := heap.Pop(&i.entries).(orderedIteratorEntry)
+	i.read = entry.read
+	i.last = entry.read.datapoint.Timestamp
+	i.returned = true
+	i.setRead = true
+	return true
+}
+
+func (i *orderedIterator) Current() (Series, ts.Datapoint, xtime.Unit, ts.Annotation) {
+	read := i.read
+	if i.err != nil || i.closed || !i.setRead {
+		read = iteratorRead{}
+	}
+	return read.series, read.datapoint, read.unit, read.annotation
+}
+
+func (i *orderedIterator) DecodedAnnotation() interface{} {
+	if i.err != nil || i.closed || !i.setRead {
+		return nil
+	}
+	return i.read.decodedAnnotation
+}
+
+func (i *orderedIterator) SequenceNumber() uint64 {
+	if i.err != nil || i.closed || !i.setRead {
+		return 0
+	}
+	return i.read.sequenceNumber
+}
+
+func (i *orderedIterator) AnnotationVersion() uint32 {
+	if i.err != nil || i.closed || !i.setRead {
+		return 0
+	}
+	return i.read.annotationVersion
+}
+
+func (i *orderedIterator) Corrupt() int {
+	return i.iter.Corrupt()
+}
+
+func (i *orderedIterator) Err() error {
+	return i.err
+}
+
+func (i *orderedIterator) Close() {
+	if i.closed {
+		return
+	}
+	i.closed = true
+	i.entries = nil
+	i.iter.Close()
+}
+
+type orderedIteratorEntries []orderedIteratorEntry
+
+func (e orderedIteratorEntries) Len() int { return len(e) }
+
+func (e orderedIteratorEntries) Less(i, j int) bool {
+	ti, tj := e[i].read.datapoint.Timestamp, e[j].read.datapoint.Timestamp
+	if ti.Equal(tj) {
+		return e[i].order < e[j].order
+	}
+	return ti.Before(tj)
+}
+
+func (e orderedIteratorEntries) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+
+func (e *orderedIteratorEntries) Push(x interface{}) {
+	*e = append(*e, x.(orderedIteratorEntry))
+}
+
+func (e *orderedIteratorEntries) Pop() interface{} {
+	old := *e
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = orderedIteratorEntry{}
+	*e = old[:n-1]
+	return entry
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package commitlog
+
+import (
+	"io"
+	"sync"
+
+	"github.com/m3db/m3db/src/dbnode/ts"
+	"github.com/m3db/m3x/pool"
+	xtime "github.com/m3db/m3x/time"
+)
+
+const (
+	// parallelReadBufferSize is the number of entries read ahead per file
+	// when reading files in parallel
+	parallelReadBufferSize = 4096
+)
+
+// parallelFileReader reads commit log files ahead of the iterator on a
+// bounded number of worker goroutines. Each file's entries are buffered on
+// their own channel and handed to the iterator in file order, so entries are
+// returned in exactly the order they would be reading one file at a time.
+// Memory is bounded by the read ahead buffer of each file being read.
+type parallelFileReader struct {
+	pool   pool.BytesPool
+	files  []*parallelFile
+	next   int
+	doneCh chan struct{}
+	wg     sync.WaitGroup
+	closed bool
+}
+
+type parallelFile struct {
+	file      File
+	openedCh  chan error
+	entries   chan parallelFileEntry
+	closeErr  error
+	bytesRead int64
+}
+
+type parallelFileEntry struct {
+	read iteratorRead
+	err  error
+}
+
+func newParallelFileReader(
+	opts Options,
+	seriesPred SeriesAnnotationFilterPredicate,
+	annotationPool pool.BytesPool,
+	files []File,
+	concurrency int,
+) *parallelFileReader {
+	r := &parallelFileReader{
+		pool:   annotationPool,
+		files:  make([]*parallelFile, 0, len(files)),
+		doneCh: make(chan struct{}),
+	}
+	for _, file := range files {
+		r.files = append(r.files, &parallelFile{
+			file:     file,
+			openedCh: make(chan error, 1),
+			entries:  make(chan parallelFileEntry, parallelReadBufferSize),
+		})
+	}
+	if concurrency > len(files) {
+		concurrency = len(files)
+	}
+
+	// Files are handed to the workers in order so the file the iterator is
+	// waiting on is always being read
+	work := make(chan *parallelFile)
+	go func() {
+		defer close(work)
+		for _, f := range r.files {
+			select {
+			case work <- f:
+			case <-r.doneCh:
+				return
+			}
+		}
+	}()
+
+	r.wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer r.wg.Done()
+			for f := range work {
+				r.readFile(opts, seriesPred, annotationPool, f)
+			}
+		}()
+	}
+
+	return r
+}
+
+func (r *parallelFileReader) readFile(
+	opts Options,
+	seriesPred SeriesAnnotationFilterPredicate,
+	annotationPool pool.BytesPool,
+	f *parallelFile,
+) {
+	defer close(f.entries)
+
+	reader, err := openReader(opts, seriesPred, annotationPool, f.file)
+	f.openedCh <- err
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		f.closeErr = reader.Close()
+		f.bytesRead = reader.BytesRead()
+	}()
+
+	for {
+		series, datapoint, unit, annotation, err := reader.Read()
+		if err == io.EOF {
+			return
+		}
+
+		entry := parallelFileEntry{err: err}
+		if err == nil {
+			entry.read = iteratorRead{
+				series:            series,
+				datapoint:         datapoint,
+				unit:              unit,
+				annotation:        annotation,
+				sequenceNumber:    reader.SequenceNumber(),
+				annotationVersion: reader.AnnotationVersion(),
+			}
+		}
+
+		select {
+		case f.entries <- entry:
+		case <-r.doneCh:
+			return
+		}
+		if err != nil {
+			// Stop reading the file at the first error as the iterator
+			// moves on to the next file once it sees it
+			return
+		}
+	}
+}
+
+// nextReader returns a reader for the entries of the next file, returning
+// the error opening the file if it could not be opened.
+func (r *parallelFileReader) nextReader() (entryReader, error) {
+	f := r.files[r.next]
+	r.next++
+	if err := <-f.openedCh; err != nil {
+		return nil, err
+	}
+	return &parallelFileEntryReader{pool: r.pool, file: f}, nil
+}
+
+// Close stops reading ahead and waits for the workers to exit, readers
+// returned by nextReader remain readable until they are closed.
+func (r *parallelFileReader) Close() {
+	if r.closed {
+		return
+	}
+	r.closed = true
+	close(r.doneCh)
+	r.wg.Wait()
+}
+
+// parallelFileEntryReader returns the entries of a file read ahead by a
+// parallel file reader.
+type parallelFileEntryReader struct {
+	pool              pool.BytesPool
+	file              *parallelFile
+	sequenceNumber    uint64
+	annotationVersion uint32
+}
+
+func (r *parallelFileEntryReader) Read() (Series, ts.Datapoint, xtime.Unit, ts.Annotation, error) {
+	entry, ok := <-r.file.entries
+	if !ok {
+		return Series{}, ts.Datapoint{}, xtime.Unit(0), ts.Annotation(nil), io.EOF
+	}
+	if entry.err != nil {
+		return Series{}, ts.Datapoint{}, xtime.Unit(0), ts.Annotation(nil), entry.err
+	}
+	r.sequenceNumber = entry.read.sequenceNumber
+	r.annotationVersion = entry.read.annotationVersion
+	read := entry.read
+	return read.series, read.datapoint, read.unit, read.annotation, nil
+}
+
+func (r *parallelFileEntryReader) SequenceNumber() uint64 {
+	return r.sequenceNumber
+}
+
+func (r *parallelFileEntryReader) AnnotationVersion() uint32 {
+	return r.annotationVersion
+}
+
+func (r *parallelFileEntryReader) BytesRead() int64 {
+	return r.file.bytesRead
+}
+
+// Close discards any entries left unread and returns the error closing the
+// underlying file reader.
+func (r *parallelFileEntryReader) Close() error {
+	for entry := range r.file.entries {
+		if r.pool != nil && entry.read.annotation != nil {
+			r.pool.Put(entry.read.annotation)
+		}
+	}
+	return r.file.closeErr
+}
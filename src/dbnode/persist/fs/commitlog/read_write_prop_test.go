@@ -157,7 +157,7 @@ func clCommandFunctor(basePath string, t *testing.T) *commands.ProtoCommands {
 			return ok
 		},
 		GenCommandFunc: func(state commands.State) gopter.Gen {
-			return gen.OneGenOf(genOpenCommand, genCloseCommand, genWriteBehindCommand)
+			return gen.OneGenOf(genOpenCommand, genCloseCommand, genSyncCommand, genWriteBehindCommand)
 		},
 	}
 }
@@ -228,6 +228,38 @@ var genCloseCommand = gen.Const(&commands.ProtoCommand{
 	},
 })
 
+var genSyncCommand = gen.Const(&commands.ProtoCommand{
+	Name: "Sync",
+	PreConditionFunc: func(state commands.State) bool {
+		return state.(*clState).open
+	},
+	RunFunc: func(q commands.SystemUnderTest) commands.Result {
+		s := q.(*clState)
+		return s.cLog.Sync()
+	},
+	NextStateFunc: func(state commands.State) commands.State {
+		return state
+	},
+	PostConditionFunc: func(state commands.State, result commands.Result) *gopter.PropResult {
+		if result != nil {
+			return &gopter.PropResult{
+				Status: gopter.PropError,
+				Error:  result.(error),
+			}
+		}
+		// Writes must be readable after a sync without closing the commit log
+		s := state.(*clState)
+		err := s.writesArePresent(s.pendingWrites...)
+		if err != nil {
+			return &gopter.PropResult{
+				Status: gopter.PropError,
+				Error:  err.(error),
+			}
+		}
+		return &gopter.PropResult{Status: gopter.PropTrue}
+	},
+})
+
 var genWriteBehindCommand = genWrite().
 	Map(func(w generatedWrite) commands.Command {
 		return &commands.ProtoCommand{
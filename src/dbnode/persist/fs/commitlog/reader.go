@@ -25,6 +25,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"io"
+	"io/ioutil"
 	"os"
 	"sync"
 	"sync/atomic"
@@ -59,6 +60,20 @@ func ReadAllSeriesPredicate() SeriesFilterPredicate {
 	return func(id ident.ID, namespace ident.ID) bool { return true }
 }
 
+// combineSeriesPredicates returns a predicate that passes series which pass
+// both predicates, either of which may be nil to pass all series.
+func combineSeriesPredicates(
+	seriesPred SeriesFilterPredicate,
+	annotationPred SeriesAnnotationFilterPredicate,
+) SeriesAnnotationFilterPredicate {
+	return func(id ident.ID, namespace ident.ID, annotation ts.Annotation) bool {
+		if seriesPred != nil && !seriesPred(id, namespace) {
+			return false
+		}
+		return annotationPred == nil || annotationPred(id, namespace, annotation)
+	}
+}
+
 type seriesMetadata struct {
 	Series
 	passedPredicate bool
@@ -68,19 +83,37 @@ type commitLogReader interface {
 	// Open opens the commit log for reading
 	Open(filePath string) (time.Time, time.Duration, int64, error)
 
+	// OpenStream opens an encoded commit log stream for reading, the stream
+	// is not closed when the reader is closed
+	OpenStream(r io.Reader) (time.Time, time.Duration, int64, error)
+
 	// Read returns the next id and data pair or error, will return io.EOF at end of volume
 	Read() (Series, ts.Datapoint, xtime.Unit, ts.Annotation, error)
 
+	// SequenceNumber returns the sequence number of the entry last returned
+	// by Read, or zero if the entry was written without one
+	SequenceNumber() uint64
+
+	// AnnotationVersion returns the annotation schema version of the entry
+	// last returned by Read, or zero if the entry was written without one
+	AnnotationVersion() uint32
+
+	// BytesRead returns the number of bytes read from the commit log, it is
+	// only safe to call once the reader has returned io.EOF or been closed
+	BytesRead() int64
+
 	// Close the reader
 	Close() error
 }
 
 type readResponse struct {
-	series     Series
-	datapoint  ts.Datapoint
-	unit       xtime.Unit
-	annotation ts.Annotation
-	resultErr  error
+	series            Series
+	datapoint         ts.Datapoint
+	unit              xtime.Unit
+	annotation        ts.Annotation
+	sequenceNumber    uint64
+	annotationVersion uint32
+	resultErr         error
 }
 
 type decoderArg struct {
@@ -112,12 +145,19 @@ type reader struct {
 	shutdownCh           chan error
 	metadata             readerMetadata
 	nextIndex            int64
+	sequenceNumber       uint64
+	annotationVersion    uint32
 	hasBeenOpened        bool
 	bgWorkersInitialized int64
-	seriesPredicate      SeriesFilterPredicate
+	seriesPredicate      SeriesAnnotationFilterPredicate
+	annotationPool       pool.BytesPool
 }
 
-func newCommitLogReader(opts Options, seriesPredicate SeriesFilterPredicate) commitLogReader {
+func newCommitLogReader(
+	opts Options,
+	seriesPredicate SeriesAnnotationFilterPredicate,
+	annotationPool pool.BytesPool,
+) commitLogReader {
 	decodingOpts := opts.FilesystemOptions().DecodingOptions()
 	cancelCtx, cancelFunc := context.WithCancel(context.Background())
 
@@ -152,6 +192,7 @@ func newCommitLogReader(opts Options, seriesPredicate SeriesFilterPredicate) com
 		metadata:          readerMetadata{},
 		nextIndex:         0,
 		seriesPredicate:   seriesPredicate,
+		annotationPool:    annotationPool,
 	}
 	return reader
 }
@@ -168,6 +209,20 @@ func (r *reader) Open(filePath string) (time.Time, time.Duration, int64, error)
 		return timeZero, 0, 0, err
 	}
 
+	return r.open(fd)
+}
+
+func (r *reader) OpenStream(stream io.Reader) (time.Time, time.Duration, int64, error) {
+	// Commitlog reader does not currently support being reused
+	if r.hasBeenOpened {
+		return timeZero, 0, 0, errCommitLogReaderIsNotReusable
+	}
+	r.hasBeenOpened = true
+
+	return r.open(ioutil.NopCloser(stream))
+}
+
+func (r *reader) open(fd io.ReadCloser) (time.Time, time.Duration, int64, error) {
 	r.chunkReader.reset(fd)
 	info, err := r.readInfo()
 	if err != nil {
@@ -205,9 +260,23 @@ func (r *reader) Read() (
 		return Series{}, ts.Datapoint{}, xtime.Unit(0), ts.Annotation(nil), io.EOF
 	}
 	r.nextIndex++
+	r.sequenceNumber = rr.sequenceNumber
+	r.annotationVersion = rr.annotationVersion
 	return rr.series, rr.datapoint, rr.unit, rr.annotation, rr.resultErr
 }
 
+func (r *reader) SequenceNumber() uint64 {
+	return r.sequenceNumber
+}
+
+func (r *reader) AnnotationVersion() uint32 {
+	return r.annotationVersion
+}
+
+func (r *reader) BytesRead() int64 {
+	return r.chunkReader.bytesRead
+}
+
 func (r *reader) startBackgroundWorkers() error {
 	// Make sure background workers are never setup more than once
 	set := atomic.CompareAndSwapInt64(&r.bgWorkersInitialized, 0, 1)
@@ -365,8 +434,14 @@ func (r *reader) decoderLoop(inBuf <-chan decoderArg, outBuf chan<- readResponse
 		response.unit = xtime.Unit(byte(entry.Unit))
 		// Copy annotation to prevent reference to pooled byte slice
 		if len(entry.Annotation) > 0 {
-			response.annotation = append([]byte(nil), entry.Annotation...)
+			var annotation []byte
+			if r.annotationPool != nil {
+				annotation = r.annotationPool.Get(len(entry.Annotation))[:0]
+			}
+			response.annotation = append(annotation, entry.Annotation...)
 		}
+		response.sequenceNumber = entry.SequenceNumber
+		response.annotationVersion = entry.AnnotationVersion
 		r.handleDecoderLoopIterationEnd(arg, outBuf, response, nil)
 	}
 
@@ -449,7 +524,7 @@ func (r *reader) decodeAndHandleMetadata(
 
 	metadataLookup[entry.Index] = seriesMetadata{
 		Series:          metadata,
-		passedPredicate: r.seriesPredicate(metadata.ID, metadata.Namespace),
+		passedPredicate: r.seriesPredicate(metadata.ID, metadata.Namespace, entry.Annotation),
 	}
 
 	namespace.DecRef()
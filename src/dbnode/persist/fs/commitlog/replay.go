@@ -0,0 +1,78 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package commitlog
+
+import (
+	"github.com/m3db/m3db/src/dbnode/ts"
+	xtime "github.com/m3db/m3x/time"
+)
+
+// ReplayFn is called with each commit log entry read during a replay.
+type ReplayFn func(
+	series Series,
+	datapoint ts.Datapoint,
+	unit xtime.Unit,
+	annotation ts.Annotation,
+) error
+
+// Replay iterates the commit logs selected by the iterator options and calls
+// fn with each entry in turn. It stops at and returns the first error returned
+// by fn, otherwise it returns any error encountered while iterating. The
+// number of entries replayed and failed replays are reported under the
+// "replay" metrics scope.
+func Replay(iterOpts IteratorOpts, fn ReplayFn) error {
+	var (
+		opts    = iterOpts.CommitLogOptions
+		iops    = opts.InstrumentOptions()
+		scope   = iops.MetricsScope().SubScope("replay")
+		entries = scope.Counter("entries")
+		failed  = scope.Counter("errors")
+		nowFn   = opts.ClockOptions().NowFn()
+		start   = nowFn()
+		n       int64
+	)
+
+	iter, err := NewIterator(iterOpts)
+	if err != nil {
+		failed.Inc(1)
+		return err
+	}
+	defer iter.Close()
+
+	for iter.Next() {
+		series, datapoint, unit, annotation := iter.Current()
+		if err := fn(series, datapoint, unit, annotation); err != nil {
+			failed.Inc(1)
+			return err
+		}
+		entries.Inc(1)
+		n++
+	}
+	if err := iter.Err(); err != nil {
+		failed.Inc(1)
+		return err
+	}
+
+	took := nowFn().Sub(start)
+	scope.Timer("duration").Record(took)
+	iops.Logger().Infof("replayed %d commit log entries in %v", n, took)
+	return nil
+}
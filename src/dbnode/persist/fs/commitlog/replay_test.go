@@ -0,0 +1,113 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package commitlog
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/m3db/m3db/src/dbnode/ts"
+	xtime "github.com/m3db/m3x/time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayCallsFnForEachEntry(t *testing.T) {
+	opts, scope := newTestOptions(t, overrides{
+		strategy: StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+
+	writes := []testWrite{
+		{testSeries(0, "foo.bar", testTags1, 127), time.Now(), 1, xtime.Millisecond, []byte("a"), nil},
+		{testSeries(1, "foo.baz", testTags2, 150), time.Now(), 2, xtime.Millisecond, nil, nil},
+		{testSeries(2, "foo.qux", testTags3, 291), time.Now(), 3, xtime.Millisecond, nil, nil},
+	}
+
+	// Call write sync
+	writeCommitLogs(t, scope, commitLog, writes).Wait()
+
+	// Close the commit log and consequently flush
+	require.NoError(t, commitLog.Close())
+
+	replayed := make(map[string]float64)
+	err := Replay(IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+	}, func(series Series, dp ts.Datapoint, unit xtime.Unit, annotation ts.Annotation) error {
+		replayed[series.ID.String()] = dp.Value
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]float64{
+		"foo.bar": 1,
+		"foo.baz": 2,
+		"foo.qux": 3,
+	}, replayed)
+
+	entries, ok := snapshotCounterValue(scope, "replay.entries")
+	require.True(t, ok)
+	require.Equal(t, int64(3), entries.Value())
+}
+
+func TestReplayStopsOnFirstFnError(t *testing.T) {
+	opts, scope := newTestOptions(t, overrides{
+		strategy: StrategyWriteWait,
+	})
+	defer cleanup(t, opts)
+
+	commitLog := newTestCommitLog(t, opts)
+
+	writes := []testWrite{
+		{testSeries(0, "foo.bar", testTags1, 127), time.Now(), 1, xtime.Millisecond, nil, nil},
+		{testSeries(1, "foo.baz", testTags2, 150), time.Now(), 2, xtime.Millisecond, nil, nil},
+	}
+
+	// Call write sync
+	writeCommitLogs(t, scope, commitLog, writes).Wait()
+
+	// Close the commit log and consequently flush
+	require.NoError(t, commitLog.Close())
+
+	var (
+		calls     int
+		errReplay = errors.New("replay failed")
+	)
+	err := Replay(IteratorOpts{
+		CommitLogOptions:      opts,
+		FileFilterPredicate:   ReadAllPredicate(),
+		SeriesFilterPredicate: ReadAllSeriesPredicate(),
+	}, func(Series, ts.Datapoint, xtime.Unit, ts.Annotation) error {
+		calls++
+		return errReplay
+	})
+	require.Equal(t, errReplay, err)
+	require.Equal(t, 1, calls)
+
+	failed, ok := snapshotCounterValue(scope, "replay.errors")
+	require.True(t, ok)
+	require.Equal(t, int64(1), failed.Value())
+}
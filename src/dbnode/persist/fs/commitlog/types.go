@@ -21,6 +21,7 @@
 package commitlog
 
 import (
+	stdcontext "context"
 	"time"
 
 	"github.com/m3db/m3db/src/dbnode/clock"
@@ -39,7 +40,8 @@ type Strategy int
 const (
 	// StrategyWriteWait describes the strategy that waits
 	// for the buffered commit log chunk that contains a write to flush
-	// before acknowledging a write
+	// and be fsync'd before acknowledging a write, an error flushing or
+	// syncing the chunk is returned to every write it contains
 	StrategyWriteWait Strategy = iota
 
 	// StrategyWriteBehind describes the strategy that does not wait
@@ -48,9 +50,25 @@ const (
 	StrategyWriteBehind
 )
 
+// BacklogQueueFullPolicy describes how writes are handled when the
+// backlog queue is full
+type BacklogQueueFullPolicy int
+
+const (
+	// BacklogQueueFullReject describes the policy that rejects writes with
+	// ErrCommitLogQueueFull when the backlog queue is full
+	BacklogQueueFullReject BacklogQueueFullPolicy = iota
+
+	// BacklogQueueFullBlock describes the policy that blocks writes until
+	// there is space in the backlog queue, writes that wait longer than the
+	// backlog queue full timeout are rejected with ErrCommitLogQueueFull
+	BacklogQueueFullBlock
+)
+
 // CommitLog provides a synchronized commit log
 type CommitLog interface {
-	// Open the commit log
+	// Open the commit log, returns ErrCommitLogAlreadyOpen if it is already
+	// open and ErrCommitLogClosed if it has been closed
 	Open() error
 
 	// Write will write an entry in the commit log for a given series
@@ -62,18 +80,94 @@ type CommitLog interface {
 		annotation ts.Annotation,
 	) error
 
-	// Close the commit log
+	// WriteBatch will write a batch of entries in the commit log, enqueueing
+	// them under a single acquisition of the commit log lock. Writes fail
+	// independently, if any fail a BatchWriteErrors is returned describing
+	// each failed write and the rest of the batch is still written
+	WriteBatch(
+		ctx context.Context,
+		writes []BatchWrite,
+	) error
+
+	// Quiesce stops the commit log accepting new writes, which will return
+	// ErrCommitLogClosing, and waits for all enqueued writes to be written,
+	// flushed and synced to disk. The commit log should be closed after
+	// quiescing. Returns ErrCommitLogNotOpen if the commit log has not been
+	// opened.
+	Quiesce(ctx stdcontext.Context) error
+
+	// Sync flushes all writes enqueued before it is called and fsyncs the
+	// active file, returning once they are durable on disk. Unlike Close
+	// the commit log keeps accepting writes, returns ErrCommitLogClosed if
+	// the commit log is closed, ErrCommitLogClosing if it is quiescing and
+	// ErrCommitLogNotOpen if it has not been opened
+	Sync() error
+
+	// ActiveFile returns the path of the file the commit log is currently
+	// writing to, or an empty string if the commit log is not open
+	ActiveFile() string
+
+	// TopSeries returns up to n of the most written series, most written
+	// first, estimated from the writes sampled since the commit log was
+	// created. It returns nil if hot series sampling is disabled
+	TopSeries(n int) []SeriesWriteStat
+
+	// Close the commit log, returns ErrCommitLogClosed if it is already
+	// closed and ErrCommitLogNotOpen if it has not been opened
 	Close() error
 }
 
+// RotationCallbackFn is called with the path of the file that was sealed and
+// the path of the new file when the commit log rotates to a new file
+type RotationCallbackFn func(oldPath, newPath string)
+
+// BatchWrite is a single entry in a batch written with WriteBatch
+type BatchWrite struct {
+	Series     Series
+	Datapoint  ts.Datapoint
+	Unit       xtime.Unit
+	Annotation ts.Annotation
+}
+
+// BatchWriteError describes a write in a batch that failed
+type BatchWriteError struct {
+	// Index is the index of the write in the batch
+	Index int
+
+	// Err is the reason the write failed
+	Err error
+}
+
+// BatchWriteErrors is returned by WriteBatch when writes in the batch fail
+type BatchWriteErrors []BatchWriteError
+
 // Iterator provides an iterator for commit logs
 type Iterator interface {
 	// Next returns whether the iterator has the next value
 	Next() bool
 
-	// Current returns the current commit log entry
+	// Current returns the current commit log entry, the annotation is only
+	// valid until the next call to Next or Close if the iterator was created
+	// with an annotation bytes pool
 	Current() (Series, ts.Datapoint, xtime.Unit, ts.Annotation)
 
+	// DecodedAnnotation returns the current entry's annotation decoded with
+	// the configured annotation codec, or nil if no codec is configured
+	DecodedAnnotation() interface{}
+
+	// SequenceNumber returns the current entry's commit log wide sequence
+	// number, or zero if the entry was written without sequence numbers
+	SequenceNumber() uint64
+
+	// AnnotationVersion returns the annotation schema version the current
+	// entry was written with, or zero if it was written without one
+	AnnotationVersion() uint32
+
+	// Corrupt returns the number of files whose remaining entries were
+	// skipped because an entry could not be read, this is only non-zero when
+	// reading with ReadModeSkipCorruptTail
+	Corrupt() int
+
 	// Err returns an error if an error occurred
 	Err() error
 
@@ -81,13 +175,83 @@ type Iterator interface {
 	Close()
 }
 
-// IteratorOpts is a struct that contains coptions for the Iterator
+// Writer writes commit log entries to a single encoded stream
+type Writer interface {
+	// Write writes an entry for the series, the series metadata is only
+	// written with the first entry for each series unique index and later
+	// entries reference it by the unique index
+	Write(
+		series Series,
+		datapoint ts.Datapoint,
+		unit xtime.Unit,
+		annotation ts.Annotation,
+	) error
+
+	// Flush writes any buffered entries to the underlying stream
+	Flush() error
+
+	// Close flushes any buffered entries, the underlying stream is left open
+	Close() error
+}
+
+// IteratorOpts is a struct that contains coptions for the Iterator, if
+// SealedOnly is set then the most recent commit log file, which may still be
+// actively written to, is skipped. OrderBy selects the order entries are
+// returned in, with GlobalTimestampOrder at most OrderBufferSize entries are
+// buffered to reorder them, or a default if it is not set. ReadMode selects
+// how entries that cannot be read are handled. If AnnotationBytesPool is set
+// annotations are allocated from it and returned to it once the iterator
+// moves past them, it is not used with GlobalTimestampOrder. If Concurrency
+// is greater than one up to that many files are read ahead in parallel, each
+// with the configured read concurrency, entries are still returned in file
+// order so the ordering is the same as reading one file at a time. It is
+// not used with GlobalTimestampOrder. If InstrumentOptions is set the
+// iterator reports the records, bytes and files it reads, the decode errors
+// it encounters and how long each file takes to read, otherwise these
+// metrics are not reported. They are not reported with GlobalTimestampOrder.
 type IteratorOpts struct {
-	CommitLogOptions      Options
-	FileFilterPredicate   FileFilterPredicate
-	SeriesFilterPredicate SeriesFilterPredicate
+	CommitLogOptions                Options
+	FileFilterPredicate             FileFilterPredicate
+	SeriesFilterPredicate           SeriesFilterPredicate
+	SeriesAnnotationFilterPredicate SeriesAnnotationFilterPredicate
+	ValueFilterPredicate            ValueFilterPredicate
+	SealedOnly                      bool
+	OrderBy                         IteratorOrder
+	OrderBufferSize                 int
+	ReadMode                        ReadMode
+	AnnotationBytesPool             pool.BytesPool
+	Concurrency                     int
+	InstrumentOptions               instrument.Options
 }
 
+// IteratorOrder describes the order a commit log iterator returns entries in
+type IteratorOrder int
+
+const (
+	// PerSeriesOrder returns entries in the order they are read from disk, which
+	// only guarantees entries within a series are in the order they were written
+	PerSeriesOrder IteratorOrder = iota
+
+	// GlobalTimestampOrder returns entries across all series in timestamp order,
+	// the iterator fails with ErrOrderBufferExceeded if an entry arrives too
+	// late to be reordered within the order buffer
+	GlobalTimestampOrder
+)
+
+// ReadMode describes how a commit log iterator handles entries that cannot
+// be read
+type ReadMode int
+
+const (
+	// ReadModeStrict fails the iteration at the first entry that cannot be read
+	ReadModeStrict ReadMode = iota
+
+	// ReadModeSkipCorruptTail stops reading a file at the first entry that
+	// cannot be read, such as a partial record left by a process killed during
+	// a flush, and continues with the next file
+	ReadModeSkipCorruptTail
+)
+
 // Series describes a series in the commit log
 type Series struct {
 	// UniqueIndex is the unique index assigned to this series
@@ -153,18 +317,55 @@ type Options interface {
 	// Strategy returns the strategy
 	Strategy() Strategy
 
-	// SetFlushInterval sets the flush interval
+	// SetFlushInterval sets the flush interval, the commit log is flushed
+	// at least this often while it is open. A zero interval flushes after
+	// every write, negative intervals are invalid.
 	SetFlushInterval(value time.Duration) Options
 
 	// FlushInterval returns the flush interval
 	FlushInterval() time.Duration
 
+	// SetFsyncInterval sets the fsync interval, the active commit log file
+	// is flushed and fsync'd at least this often while it is open. A zero
+	// interval, the default, disables periodic fsyncs and negative intervals
+	// are invalid.
+	//
+	// Flushing only writes buffered entries to the OS page cache, which
+	// survives a process crash but not a machine crash. With
+	// StrategyWriteWait every flushed chunk is also fsync'd before writes
+	// are acknowledged so acknowledged writes survive a machine crash and
+	// the fsync interval adds nothing. With StrategyWriteBehind writes are
+	// acknowledged before they are flushed, the flush interval bounds the
+	// writes lost on a process crash and the fsync interval bounds the
+	// writes lost on a machine crash, without it they are only fsync'd when
+	// the file is rotated or closed.
+	SetFsyncInterval(value time.Duration) Options
+
+	// FsyncInterval returns the fsync interval
+	FsyncInterval() time.Duration
+
 	// SetBacklogQueueSize sets the backlog queue size
 	SetBacklogQueueSize(value int) Options
 
 	// BacklogQueueSize returns the backlog queue size
 	BacklogQueueSize() int
 
+	// SetBacklogQueueFullPolicy sets the policy for writes when the backlog
+	// queue is full
+	SetBacklogQueueFullPolicy(value BacklogQueueFullPolicy) Options
+
+	// BacklogQueueFullPolicy returns the policy for writes when the backlog
+	// queue is full
+	BacklogQueueFullPolicy() BacklogQueueFullPolicy
+
+	// SetBacklogQueueFullTimeout sets how long a write waits for space in a
+	// full backlog queue with the block policy before it is rejected
+	SetBacklogQueueFullTimeout(value time.Duration) Options
+
+	// BacklogQueueFullTimeout returns how long a write waits for space in a
+	// full backlog queue with the block policy before it is rejected
+	BacklogQueueFullTimeout() time.Duration
+
 	// SetBytesPool sets the checked bytes pool
 	SetBytesPool(value pool.CheckedBytesPool) Options
 
@@ -182,6 +383,86 @@ type Options interface {
 
 	// IdentifierPool returns the IdentifierPool to use for pooling identifiers.
 	IdentifierPool() ident.Pool
+
+	// SetAnnotationCodec sets the codec used to decode annotations when
+	// reading, by default annotations are treated as opaque bytes.
+	SetAnnotationCodec(value AnnotationCodec) Options
+
+	// AnnotationCodec returns the codec used to decode annotations when
+	// reading, by default annotations are treated as opaque bytes.
+	AnnotationCodec() AnnotationCodec
+
+	// SetAnnotationVersion sets the annotation schema version written with
+	// each entry, readers can dispatch on it to decode annotations written
+	// with older schemas, entries written before versions were recorded
+	// read back as version zero.
+	SetAnnotationVersion(value uint32) Options
+
+	// AnnotationVersion returns the annotation schema version written with
+	// each entry.
+	AnnotationVersion() uint32
+
+	// SetFlushRetries sets the number of times a failed write of a chunk to
+	// disk is retried before the error is reported, failed fsyncs are never
+	// retried
+	SetFlushRetries(value int) Options
+
+	// FlushRetries returns the number of times a failed write of a chunk to
+	// disk is retried before the error is reported
+	FlushRetries() int
+
+	// SetFlushRetryBackoff sets the backoff before the first flush retry,
+	// the backoff doubles with each subsequent retry
+	SetFlushRetryBackoff(value time.Duration) Options
+
+	// FlushRetryBackoff returns the backoff before the first flush retry,
+	// the backoff doubles with each subsequent retry
+	FlushRetryBackoff() time.Duration
+
+	// SetSequenceNumbersEnabled sets whether each entry is assigned a sequence
+	// number that increases monotonically across all commit log files
+	SetSequenceNumbersEnabled(value bool) Options
+
+	// SequenceNumbersEnabled returns whether each entry is assigned a sequence
+	// number that increases monotonically across all commit log files
+	SequenceNumbersEnabled() bool
+
+	// SetRotationCallback sets the callback invoked when the commit log
+	// rotates to a new file, it is called for each rotation in order from a
+	// single goroutine so that it does not block writes or flushes unless
+	// rotations back up, and Close waits for queued calls to return
+	SetRotationCallback(value RotationCallbackFn) Options
+
+	// RotationCallback returns the callback invoked when the commit log
+	// rotates to a new file
+	RotationCallback() RotationCallbackFn
+
+	// SetValidTimeWindow sets how far before and after the current time a
+	// write timestamp may be, writes outside the window are rejected with a
+	// TimestampOutsideWindowError. A zero duration leaves that side of the
+	// window unbounded, by default the past is unbounded and the future is
+	// bounded to a day ahead.
+	SetValidTimeWindow(past, future time.Duration) Options
+
+	// ValidTimeWindow returns how far before and after the current time a
+	// write timestamp may be
+	ValidTimeWindow() (past, future time.Duration)
+
+	// SetHotSeriesSampling sets the fraction of writes sampled to track the
+	// most written series, reported by TopSeries. A bounded number of series
+	// are tracked regardless of cardinality, zero disables sampling and is
+	// the default.
+	SetHotSeriesSampling(rate float64) Options
+
+	// HotSeriesSampling returns the fraction of writes sampled to track the
+	// most written series
+	HotSeriesSampling() float64
+}
+
+// AnnotationCodec decodes commit log annotations into typed values.
+type AnnotationCodec interface {
+	// Decode decodes an annotation, returning an error if it is invalid.
+	Decode(annotation ts.Annotation) (interface{}, error)
 }
 
 // FileFilterPredicate is a predicate that allows the caller to determine
@@ -193,3 +474,16 @@ type FileFilterPredicate func(f File) bool
 // reader level to prevent having to run the same function for every datapoint for a
 // given series.
 type SeriesFilterPredicate func(id ident.ID, namespace ident.ID) bool
+
+// SeriesAnnotationFilterPredicate is a predicate like SeriesFilterPredicate that
+// also receives the annotation of the first datapoint for the series in each
+// commit log file, the datapoint that carries the series metadata. It is
+// optional and is combined with the SeriesFilterPredicate, a series must pass
+// both to be returned.
+type SeriesAnnotationFilterPredicate func(id ident.ID, namespace ident.ID, annotation ts.Annotation) bool
+
+// ValueFilterPredicate is a predicate that determines whether a decoded datapoint
+// should be returned from the commit log iterator. Unlike the file and series
+// predicates it is evaluated for every datapoint after it has been decoded.
+// It is optional, a nil predicate returns all datapoints.
+type ValueFilterPredicate func(dp ts.Datapoint) bool
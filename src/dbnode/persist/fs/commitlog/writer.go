@@ -24,6 +24,7 @@ import (
 	"bufio"
 	"encoding/binary"
 	"errors"
+	"io"
 	"os"
 	"time"
 
@@ -37,6 +38,8 @@ import (
 	"github.com/m3db/m3db/src/dbnode/ts"
 	"github.com/m3db/m3x/ident"
 	xtime "github.com/m3db/m3x/time"
+
+	"github.com/uber-go/tally"
 )
 
 const (
@@ -56,6 +59,7 @@ const (
 
 var (
 	errCommitLogWriterAlreadyOpen = errors.New("commit log writer already open")
+	errCommitLogWriterClosed      = errors.New("commit log writer is closed")
 	errTagEncoderDataNotAvailable = errors.New("tag iterator data not available")
 
 	endianness = binary.LittleEndian
@@ -65,17 +69,29 @@ type commitLogWriter interface {
 	// Open opens the commit log for writing data
 	Open(start time.Time, duration time.Duration) error
 
-	// Write will write an entry in the commit log for a given series
+	// Write will write an entry in the commit log for a given series, the
+	// series metadata is only written with the first entry for the series in
+	// each file and later entries reference it by the series unique index,
+	// a zero sequence number means the entry is written without one
 	Write(
 		series Series,
 		datapoint ts.Datapoint,
 		unit xtime.Unit,
 		annotation ts.Annotation,
+		sequenceNumber uint64,
+		annotationVersion uint32,
 	) error
 
 	// Flush will flush the contents to the disk, useful when first testing if first commit log is writable
 	Flush() error
 
+	// Sync will flush the contents to the disk and fsync the file
+	Sync() error
+
+	// FilePath returns the path of the file being written to, or an empty
+	// string if the writer is not open
+	FilePath() string
+
 	// Close the reader
 	Close() error
 }
@@ -84,6 +100,7 @@ type flushFn func(err error)
 
 type writer struct {
 	filePathPrefix     string
+	filePath           string
 	newFileMode        os.FileMode
 	newDirectoryMode   os.FileMode
 	nowFn              clock.NowFn
@@ -104,6 +121,10 @@ func newCommitLogWriter(
 	flushFn flushFn,
 	opts Options,
 ) commitLogWriter {
+	return newWriter(flushFn, opts)
+}
+
+func newWriter(flushFn flushFn, opts Options) *writer {
 	shouldFsync := opts.Strategy() == StrategyWriteWait
 
 	return &writer{
@@ -111,7 +132,7 @@ func newCommitLogWriter(
 		newFileMode:        opts.FilesystemOptions().NewFileMode(),
 		newDirectoryMode:   opts.FilesystemOptions().NewDirectoryMode(),
 		nowFn:              opts.ClockOptions().NowFn(),
-		chunkWriter:        newChunkWriter(flushFn, shouldFsync),
+		chunkWriter:        newChunkWriter(flushFn, shouldFsync, opts),
 		chunkReserveHeader: make([]byte, chunkHeaderLen),
 		buffer:             bufio.NewWriterSize(nil, opts.FlushSize()),
 		sizeBuffer:         make([]byte, binary.MaxVarintLen64),
@@ -134,6 +155,28 @@ func (w *writer) Open(start time.Time, duration time.Duration) error {
 	}
 
 	filePath, index := fs.NextCommitLogsFile(w.filePathPrefix, start)
+	fd, err := fs.OpenWritable(filePath, w.newFileMode)
+	if err != nil {
+		return err
+	}
+
+	if err := w.open(fd, start, duration, index); err != nil {
+		return err
+	}
+
+	w.filePath = filePath
+	return nil
+}
+
+// open writes the log info to the chunk file and prepares the writer for
+// writing entries to it, the chunk file is closed if the log info cannot
+// be written.
+func (w *writer) open(
+	fd chunkFile,
+	start time.Time,
+	duration time.Duration,
+	index int,
+) error {
 	logInfo := schema.LogInfo{
 		Start:    start.UnixNano(),
 		Duration: int64(duration),
@@ -141,10 +184,7 @@ func (w *writer) Open(start time.Time, duration time.Duration) error {
 	}
 	w.logEncoder.Reset()
 	if err := w.logEncoder.EncodeLogInfo(logInfo); err != nil {
-		return err
-	}
-	fd, err := fs.OpenWritable(filePath, w.newFileMode)
-	if err != nil {
+		fd.Close()
 		return err
 	}
 
@@ -169,6 +209,8 @@ func (w *writer) Write(
 	datapoint ts.Datapoint,
 	unit xtime.Unit,
 	annotation ts.Annotation,
+	sequenceNumber uint64,
+	annotationVersion uint32,
 ) error {
 	var logEntry schema.LogEntry
 	logEntry.Create = w.nowFn().UnixNano()
@@ -215,6 +257,8 @@ func (w *writer) Write(
 	logEntry.Value = datapoint.Value
 	logEntry.Unit = uint32(unit)
 	logEntry.Annotation = annotation
+	logEntry.SequenceNumber = sequenceNumber
+	logEntry.AnnotationVersion = annotationVersion
 	w.logEncoder.Reset()
 	if err := w.logEncoder.EncodeLogEntry(logEntry); err != nil {
 		return err
@@ -234,6 +278,21 @@ func (w *writer) Flush() error {
 	return w.buffer.Flush()
 }
 
+func (w *writer) FilePath() string {
+	return w.filePath
+}
+
+func (w *writer) Sync() error {
+	if !w.isOpen() {
+		return nil
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return w.chunkWriter.fd.Sync()
+}
+
 func (w *writer) Close() error {
 	if !w.isOpen() {
 		return nil
@@ -247,6 +306,7 @@ func (w *writer) Close() error {
 	}
 
 	w.chunkWriter.fd = nil
+	w.filePath = ""
 	w.start = timeZero
 	w.duration = 0
 	w.seen.ClearAll()
@@ -274,18 +334,109 @@ func (w *writer) write(data []byte) error {
 	return err
 }
 
+type streamWriter struct {
+	writer            *writer
+	annotationVersion uint32
+	closed            bool
+}
+
+// NewCommitLogWriter returns a writer that encodes commit log entries to w in
+// the same format as a commit log file, starting with the log info for the
+// current block, without rotating files. The stream can be read back with
+// NewReader.
+func NewCommitLogWriter(w io.Writer, opts Options) (Writer, error) {
+	writer := newWriter(func(error) {}, opts)
+	start := opts.ClockOptions().NowFn()().Truncate(opts.BlockSize())
+	if err := writer.open(streamFile{Writer: w}, start, opts.BlockSize(), 0); err != nil {
+		return nil, err
+	}
+	return &streamWriter{
+		writer:            writer,
+		annotationVersion: opts.AnnotationVersion(),
+	}, nil
+}
+
+func (w *streamWriter) Write(
+	series Series,
+	datapoint ts.Datapoint,
+	unit xtime.Unit,
+	annotation ts.Annotation,
+) error {
+	if w.closed {
+		return errCommitLogWriterClosed
+	}
+	return w.writer.Write(series, datapoint, unit, annotation, 0, w.annotationVersion)
+}
+
+func (w *streamWriter) Flush() error {
+	if w.closed {
+		return errCommitLogWriterClosed
+	}
+	return w.writer.Flush()
+}
+
+func (w *streamWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.writer.Close()
+}
+
+// streamFile adapts an io.Writer to a chunkFile, it syncs the writer if it
+// supports syncing and leaves closing the writer to its owner
+type streamFile struct {
+	io.Writer
+}
+
+func (f streamFile) Sync() error {
+	if s, ok := f.Writer.(interface {
+		Sync() error
+	}); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+func (f streamFile) Close() error {
+	return nil
+}
+
+// chunkFile is the file that chunks are written to
+type chunkFile interface {
+	io.Writer
+
+	Sync() error
+	Close() error
+}
+
 type chunkWriter struct {
-	fd      *os.File
-	flushFn flushFn
-	buff    []byte
-	fsync   bool
+	fd           chunkFile
+	flushFn      flushFn
+	buff         []byte
+	fsync        bool
+	retries      int
+	retryBackoff time.Duration
+	retried      tally.Counter
+	flushBytes   tally.Counter
+	flushLatency tally.Timer
+	nowFn        clock.NowFn
+	sleepFn      func(time.Duration)
 }
 
-func newChunkWriter(flushFn flushFn, fsync bool) *chunkWriter {
+func newChunkWriter(flushFn flushFn, fsync bool, opts Options) *chunkWriter {
+	scope := opts.InstrumentOptions().MetricsScope().SubScope("commitlog")
 	return &chunkWriter{
-		flushFn: flushFn,
-		buff:    make([]byte, chunkHeaderLen),
-		fsync:   fsync,
+		flushFn:      flushFn,
+		buff:         make([]byte, chunkHeaderLen),
+		fsync:        fsync,
+		retries:      opts.FlushRetries(),
+		retryBackoff: opts.FlushRetryBackoff(),
+		retried:      scope.Counter("writes.flush-retries"),
+		flushBytes:   scope.Counter("writes.flush-bytes"),
+		flushLatency: scope.Timer("writes.flush-latency"),
+		nowFn:        opts.ClockOptions().NowFn(),
+		sleepFn:      time.Sleep,
 	}
 }
 
@@ -317,19 +468,46 @@ func (w *chunkWriter) Write(p []byte) (int, error) {
 	// Combine buffers to reduce to a single syscall
 	w.buff = append(w.buff[:chunkHeaderLen], p...)
 
-	// Write contents to file descriptor
-	n, err := w.fd.Write(w.buff)
+	// Write contents to file descriptor, retrying failed writes so that a
+	// transient disk error does not drop the chunk
+	start := w.nowFn()
+	var n int
+	err := w.withRetries(func() error {
+		written, err := w.fd.Write(w.buff[n:])
+		n += written
+		return err
+	})
 	if err != nil {
 		w.flushFn(err)
 		return n, err
 	}
 
-	// Fsync if required to
+	// Fsync if required to, a failed fsync is never retried since the
+	// kernel may have already dropped the dirty pages it failed to write
+	// back so a later successful fsync would not mean the chunk is durable
 	if w.fsync {
 		err = w.fd.Sync()
 	}
+	w.flushBytes.Inc(int64(n))
+	w.flushLatency.Record(w.nowFn().Sub(start))
 
 	// Fire flush callback
 	w.flushFn(err)
 	return n, err
 }
+
+// withRetries calls fn until it succeeds or the configured number of retries
+// is exhausted, doubling the backoff between each attempt.
+func (w *chunkWriter) withRetries(fn func() error) error {
+	backoff := w.retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= w.retries {
+			return err
+		}
+
+		w.retried.Inc(1)
+		w.sleepFn(backoff)
+		backoff *= 2
+	}
+}
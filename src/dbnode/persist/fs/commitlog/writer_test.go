@@ -0,0 +1,187 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package commitlog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/m3db/m3db/src/dbnode/ts"
+	"github.com/m3db/m3x/instrument"
+	xtime "github.com/m3db/m3x/time"
+
+	mclock "github.com/facebookgo/clock"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+)
+
+type flakyChunkFile struct {
+	bytes.Buffer
+	writeFailures int
+	syncFailures  int
+}
+
+func (f *flakyChunkFile) Write(p []byte) (int, error) {
+	if f.writeFailures > 0 {
+		f.writeFailures--
+		// Simulate a partial write before the error
+		n, _ := f.Buffer.Write(p[:1])
+		return n, errors.New("transient write error")
+	}
+	return f.Buffer.Write(p)
+}
+
+func (f *flakyChunkFile) Sync() error {
+	if f.syncFailures > 0 {
+		f.syncFailures--
+		return errors.New("transient sync error")
+	}
+	return nil
+}
+
+func (f *flakyChunkFile) Close() error {
+	return nil
+}
+
+func newTestChunkWriter(
+	file chunkFile,
+	retries int,
+) (*chunkWriter, *[]error, *[]time.Duration, tally.TestScope) {
+	scope := tally.NewTestScope("", nil)
+	opts := NewOptions().
+		SetInstrumentOptions(instrument.NewOptions().SetMetricsScope(scope)).
+		SetFlushRetries(retries).
+		SetFlushRetryBackoff(time.Millisecond)
+
+	var (
+		flushErrs []error
+		sleeps    []time.Duration
+	)
+	w := newChunkWriter(func(err error) {
+		flushErrs = append(flushErrs, err)
+	}, true, opts)
+	w.fd = file
+	w.sleepFn = func(d time.Duration) {
+		sleeps = append(sleeps, d)
+	}
+	return w, &flushErrs, &sleeps, scope
+}
+
+func TestChunkWriterRetriesTransientErrors(t *testing.T) {
+	file := &flakyChunkFile{writeFailures: 2}
+	w, flushErrs, sleeps, scope := newTestChunkWriter(file, 3)
+
+	data := []byte("some data")
+	_, err := w.Write(data)
+	require.NoError(t, err)
+
+	// The chunk is written once in full despite the partial writes
+	require.Equal(t, chunkHeaderLen+len(data), file.Len())
+	require.True(t, bytes.HasSuffix(file.Bytes(), data))
+
+	require.Equal(t, []error{nil}, *flushErrs)
+	require.Equal(t, []time.Duration{
+		time.Millisecond, 2 * time.Millisecond,
+	}, *sleeps)
+
+	retried, ok := snapshotCounterValue(scope, "commitlog.writes.flush-retries")
+	require.True(t, ok)
+	require.Equal(t, int64(2), retried.Value())
+}
+
+func TestChunkWriterDoesNotRetryFsync(t *testing.T) {
+	file := &flakyChunkFile{syncFailures: 1}
+	w, flushErrs, sleeps, _ := newTestChunkWriter(file, 3)
+
+	_, err := w.Write([]byte("some data"))
+	require.Error(t, err)
+
+	require.Equal(t, []error{err}, *flushErrs)
+	require.Empty(t, *sleeps)
+}
+
+func TestChunkWriterReportsErrorAfterRetries(t *testing.T) {
+	file := &flakyChunkFile{writeFailures: 3}
+	w, flushErrs, sleeps, _ := newTestChunkWriter(file, 2)
+
+	_, err := w.Write([]byte("some data"))
+	require.Error(t, err)
+
+	require.Len(t, *flushErrs, 1)
+	require.Equal(t, err, (*flushErrs)[0])
+	require.Len(t, *sleeps, 2)
+}
+
+func TestCommitLogWriterStreamRoundTrip(t *testing.T) {
+	clock := mclock.NewMock()
+	opts, _ := newTestOptions(t, overrides{clock: clock})
+	defer cleanup(t, opts)
+
+	var buf bytes.Buffer
+	w, err := NewCommitLogWriter(&buf, opts)
+	require.NoError(t, err)
+
+	start := clock.Now()
+	writes := []testWrite{
+		{testSeries(0, "foo.a", testTags1, 127), start, 1, xtime.Millisecond, []byte{1, 2, 3}, nil},
+		{testSeries(1, "foo.b", testTags2, 150), start.Add(time.Second), 2, xtime.Second, nil, nil},
+		{testSeries(0, "foo.a", testTags1, 127), start.Add(2 * time.Second), 3.5, xtime.Millisecond, nil, nil},
+	}
+	for _, write := range writes {
+		require.NoError(t, w.Write(write.series, ts.Datapoint{
+			Timestamp: write.t,
+			Value:     write.v,
+		}, write.u, write.a))
+	}
+	require.NoError(t, w.Close())
+	require.Error(t, w.Write(writes[0].series, ts.Datapoint{}, xtime.Second, nil))
+
+	iter, err := NewReader(&buf, opts)
+	require.NoError(t, err)
+	defer iter.Close()
+
+	// Entries are only ordered within a series, so match them up by series
+	remaining := make(map[uint64][]testWrite)
+	for _, write := range writes {
+		idx := write.series.UniqueIndex
+		remaining[idx] = append(remaining[idx], write)
+	}
+	read := 0
+	for iter.Next() {
+		series, datapoint, unit, annotation := iter.Current()
+		expected := remaining[series.UniqueIndex]
+		require.NotEmpty(t, expected)
+		expected[0].assert(t, series, datapoint, unit, annotation)
+		remaining[series.UniqueIndex] = expected[1:]
+		read++
+	}
+	require.NoError(t, iter.Err())
+	require.Equal(t, len(writes), read)
+}
+
+func TestNewReaderInvalidStream(t *testing.T) {
+	opts, _ := newTestOptions(t, overrides{})
+	defer cleanup(t, opts)
+
+	_, err := NewReader(bytes.NewBufferString("not a commit log"), opts)
+	require.Error(t, err)
+}
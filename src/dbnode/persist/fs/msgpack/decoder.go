@@ -157,6 +157,7 @@ func (dec *Decoder) DecodeLogEntry() (schema.LogEntry, error) {
 type DecodeLogEntryRemainingToken struct {
 	numFieldsToSkip1 int
 	numFieldsToSkip2 int
+	numFields        int
 }
 
 // DecodeLogEntryUniqueIndex decodes a log entry as much as is required to return
@@ -168,7 +169,7 @@ func (dec *Decoder) DecodeLogEntryUniqueIndex() (DecodeLogEntryRemainingToken, u
 	}
 
 	_, numFieldsToSkip1 := dec.decodeRootObject(logEntryVersion, logEntryType)
-	numFieldsToSkip2, _, ok := dec.checkNumFieldsFor(logEntryType, checkNumFieldsOptions{})
+	numFieldsToSkip2, actual, ok := dec.checkNumFieldsFor(logEntryType, dec.logEntryNumFieldsOptions())
 	if !ok {
 		return emptyLogEntryRemainingToken, 0, errorUnableToDetermineNumFieldsToSkip
 	}
@@ -177,6 +178,7 @@ func (dec *Decoder) DecodeLogEntryUniqueIndex() (DecodeLogEntryRemainingToken, u
 	token := DecodeLogEntryRemainingToken{
 		numFieldsToSkip1: numFieldsToSkip1,
 		numFieldsToSkip2: numFieldsToSkip2,
+		numFields:        actual,
 	}
 	return token, idx, nil
 }
@@ -196,6 +198,12 @@ func (dec *Decoder) DecodeLogEntryRemaining(token DecodeLogEntryRemainingToken,
 	logEntry.Value = dec.decodeFloat64()
 	logEntry.Unit = uint32(dec.decodeVarUint())
 	logEntry.Annotation, _, _ = dec.decodeBytes()
+	if !dec.legacy.decodeLegacyV1LogEntry && token.numFields >= 8 {
+		logEntry.SequenceNumber = dec.decodeVarUint()
+	}
+	if !dec.legacy.decodeLegacyV1LogEntry && token.numFields >= 9 {
+		logEntry.AnnotationVersion = uint32(dec.decodeVarUint())
+	}
 
 	dec.skip(token.numFieldsToSkip1)
 	if dec.err != nil {
@@ -360,8 +368,19 @@ func (dec *Decoder) decodeLogInfo() schema.LogInfo {
 	return logInfo
 }
 
+func (dec *Decoder) logEntryNumFieldsOptions() checkNumFieldsOptions {
+	var opts checkNumFieldsOptions
+	if dec.legacy.decodeLegacyV1LogEntry {
+		// v1 had 7 fields
+		opts.override = true
+		opts.numExpectedMinFields = 7
+		opts.numExpectedCurrFields = 7
+	}
+	return opts
+}
+
 func (dec *Decoder) decodeLogEntry() schema.LogEntry {
-	numFieldsToSkip, _, ok := dec.checkNumFieldsFor(logEntryType, checkNumFieldsOptions{})
+	numFieldsToSkip, actual, ok := dec.checkNumFieldsFor(logEntryType, dec.logEntryNumFieldsOptions())
 	if !ok {
 		return emptyLogEntry
 	}
@@ -373,6 +392,12 @@ func (dec *Decoder) decodeLogEntry() schema.LogEntry {
 	logEntry.Value = dec.decodeFloat64()
 	logEntry.Unit = uint32(dec.decodeVarUint())
 	logEntry.Annotation, _, _ = dec.decodeBytes()
+	if !dec.legacy.decodeLegacyV1LogEntry && actual >= 8 {
+		logEntry.SequenceNumber = dec.decodeVarUint()
+	}
+	if !dec.legacy.decodeLegacyV1LogEntry && actual >= 9 {
+		logEntry.AnnotationVersion = uint32(dec.decodeVarUint())
+	}
 	dec.skip(numFieldsToSkip)
 	if dec.err != nil {
 		return emptyLogEntry
@@ -56,15 +56,19 @@ type Encoder struct {
 type legacyEncodingOptions struct {
 	encodeLegacyV1IndexInfo  bool
 	encodeLegacyV1IndexEntry bool
+	encodeLegacyV1LogEntry   bool
 	decodeLegacyV1IndexInfo  bool
 	decodeLegacyV1IndexEntry bool
+	decodeLegacyV1LogEntry   bool
 }
 
 var defaultlegacyEncodingOptions = legacyEncodingOptions{
 	encodeLegacyV1IndexInfo:  false,
 	encodeLegacyV1IndexEntry: false,
+	encodeLegacyV1LogEntry:   false,
 	decodeLegacyV1IndexInfo:  false,
 	decodeLegacyV1IndexEntry: false,
+	decodeLegacyV1LogEntry:   false,
 }
 
 // NewEncoder creates a new encoder
@@ -156,7 +160,11 @@ func (enc *Encoder) EncodeLogEntry(entry schema.LogEntry) error {
 		return enc.err
 	}
 	enc.encodeRootObject(logEntryVersion, logEntryType)
-	enc.encodeLogEntry(entry)
+	if enc.legacy.encodeLegacyV1LogEntry {
+		enc.encodeLogEntryV1(entry)
+	} else {
+		enc.encodeLogEntryV2(entry)
+	}
 	return enc.err
 }
 
@@ -242,7 +250,21 @@ func (enc *Encoder) encodeLogInfo(info schema.LogInfo) {
 	enc.encodeVarintFn(info.Index)
 }
 
-func (enc *Encoder) encodeLogEntry(entry schema.LogEntry) {
+// We only keep this method around for the sake of testing
+// backwards-compatbility
+func (enc *Encoder) encodeLogEntryV1(entry schema.LogEntry) {
+	// Manually encode num fields for testing purposes
+	enc.encodeArrayLenFn(7) // v1 had 7 fields
+	enc.encodeVarUintFn(entry.Index)
+	enc.encodeVarintFn(entry.Create)
+	enc.encodeBytesFn(entry.Metadata)
+	enc.encodeVarintFn(entry.Timestamp)
+	enc.encodeFloat64Fn(entry.Value)
+	enc.encodeVarUintFn(uint64(entry.Unit))
+	enc.encodeBytesFn(entry.Annotation)
+}
+
+func (enc *Encoder) encodeLogEntryV2(entry schema.LogEntry) {
 	enc.encodeNumObjectFieldsForFn(logEntryType)
 	// Encode the index first because the commitlog reader needs this information first
 	// to distribute the rest of the decoding to a group of workers.
@@ -253,6 +275,8 @@ func (enc *Encoder) encodeLogEntry(entry schema.LogEntry) {
 	enc.encodeFloat64Fn(entry.Value)
 	enc.encodeVarUintFn(uint64(entry.Unit))
 	enc.encodeBytesFn(entry.Annotation)
+	enc.encodeVarUintFn(entry.SequenceNumber)
+	enc.encodeVarUintFn(uint64(entry.AnnotationVersion))
 }
 
 func (enc *Encoder) encodeLogMetadata(metadata schema.LogMetadata) {
@@ -69,13 +69,15 @@ var (
 	}
 
 	testLogEntry = schema.LogEntry{
-		Create:     time.Now().UnixNano(),
-		Index:      9345,
-		Metadata:   []byte("testMetadata"),
-		Timestamp:  time.Now().Add(time.Minute).UnixNano(),
-		Value:      903.234,
-		Unit:       9,
-		Annotation: []byte("testAnnotation"),
+		Create:            time.Now().UnixNano(),
+		Index:             9345,
+		Metadata:          []byte("testMetadata"),
+		Timestamp:         time.Now().Add(time.Minute).UnixNano(),
+		Value:             903.234,
+		Unit:              9,
+		Annotation:        []byte("testAnnotation"),
+		SequenceNumber:    77123,
+		AnnotationVersion: 2,
 	}
 
 	testLogMetadata = schema.LogMetadata{
@@ -258,6 +260,82 @@ func TestLogEntryRoundtrip(t *testing.T) {
 	require.Equal(t, testLogEntry, res)
 }
 
+// Make sure the new decoding code can handle the old file format
+func TestLogEntryRoundTripBackwardsCompatibilityV1(t *testing.T) {
+	var (
+		opts = legacyEncodingOptions{encodeLegacyV1LogEntry: true}
+		enc  = newEncoder(opts)
+		dec  = newDecoder(opts, nil)
+	)
+
+	// Set the default values on the fields that did not exist in V1
+	// and then restore them at the end of the test - This is required
+	// because the new decoder won't try and read the new fields from
+	// the old file format
+	currSequenceNumber := testLogEntry.SequenceNumber
+	currAnnotationVersion := testLogEntry.AnnotationVersion
+	testLogEntry.SequenceNumber = 0
+	testLogEntry.AnnotationVersion = 0
+	defer func() {
+		testLogEntry.SequenceNumber = currSequenceNumber
+		testLogEntry.AnnotationVersion = currAnnotationVersion
+	}()
+
+	enc.EncodeLogEntry(testLogEntry)
+	buf := enc.Bytes()
+
+	dec.Reset(NewDecoderStream(buf))
+	res, err := dec.DecodeLogEntry()
+	require.NoError(t, err)
+	require.Equal(t, testLogEntry, res)
+
+	dec.Reset(NewDecoderStream(buf))
+	token, idx, err := dec.DecodeLogEntryUniqueIndex()
+	require.NoError(t, err)
+	res, err = dec.DecodeLogEntryRemaining(token, idx)
+	require.NoError(t, err)
+	require.Equal(t, testLogEntry, res)
+}
+
+// Make sure the old decoder code can handle the new file format
+func TestLogEntryRoundTripForwardsCompatibilityV2(t *testing.T) {
+	var (
+		opts = legacyEncodingOptions{decodeLegacyV1LogEntry: true}
+		enc  = newEncoder(opts)
+		dec  = newDecoder(opts, nil)
+	)
+
+	// Set the default values on the fields that did not exist in V1
+	// and then restore them at the end of the test - This is required
+	// because the old decoder won't read the new fields
+	currSequenceNumber := testLogEntry.SequenceNumber
+	currAnnotationVersion := testLogEntry.AnnotationVersion
+
+	enc.EncodeLogEntry(testLogEntry)
+	buf := enc.Bytes()
+
+	// Make sure to zero them before we compare, but after we have
+	// encoded the data
+	testLogEntry.SequenceNumber = 0
+	testLogEntry.AnnotationVersion = 0
+	defer func() {
+		testLogEntry.SequenceNumber = currSequenceNumber
+		testLogEntry.AnnotationVersion = currAnnotationVersion
+	}()
+
+	dec.Reset(NewDecoderStream(buf))
+	res, err := dec.DecodeLogEntry()
+	require.NoError(t, err)
+	require.Equal(t, testLogEntry, res)
+
+	dec.Reset(NewDecoderStream(buf))
+	token, idx, err := dec.DecodeLogEntryUniqueIndex()
+	require.NoError(t, err)
+	res, err = dec.DecodeLogEntryRemaining(token, idx)
+	require.NoError(t, err)
+	require.Equal(t, testLogEntry, res)
+}
+
 func BenchmarkLogEntryDecoder(b *testing.B) {
 	// Copy so we don't mutate global state
 	logEntry := testLogEntry
@@ -52,6 +52,19 @@ const (
 	logMetadataVersion  = 1
 )
 
+const (
+	// CommitLogInfoVersion is the current version of the commit log info
+	// header encoded at the start of each commit log file.
+	CommitLogInfoVersion = logInfoVersion
+
+	// CommitLogEntryVersion is the current version of encoded commit log entries.
+	CommitLogEntryVersion = logEntryVersion
+
+	// CommitLogMetadataVersion is the current version of encoded commit log
+	// series metadata.
+	CommitLogMetadataVersion = logMetadataVersion
+)
+
 type objectType int
 
 // nolint: varcheck, unused
@@ -105,7 +118,7 @@ const (
 	currNumIndexEntryFields           = 6
 	currNumIndexSummaryFields         = 3
 	currNumLogInfoFields              = 3
-	currNumLogEntryFields             = 7
+	currNumLogEntryFields             = 9
 	currNumLogMetadataFields          = 3
 )
 
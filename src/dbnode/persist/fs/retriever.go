@@ -568,6 +568,11 @@ func (req *retrieveRequest) Reset(segment ts.Segment) {
 	req.resultWg.Done()
 }
 
+func (req *retrieveRequest) ResetAndFinalizePrevious(segment ts.Segment) {
+	req.reader.ResetAndFinalizePrevious(segment)
+	req.resultWg.Done()
+}
+
 func (req *retrieveRequest) ResetWindowed(segment ts.Segment, start time.Time, blockSize time.Duration) {
 	req.Reset(segment)
 	req.start = start
@@ -78,13 +78,15 @@ type LogInfo struct {
 
 // LogEntry stores per-entry data in a commit log
 type LogEntry struct {
-	Index      uint64
-	Create     int64
-	Metadata   []byte
-	Timestamp  int64
-	Value      float64
-	Unit       uint32
-	Annotation []byte
+	Index             uint64
+	Create            int64
+	Metadata          []byte
+	Timestamp         int64
+	Value             float64
+	Unit              uint32
+	Annotation        []byte
+	SequenceNumber    uint64
+	AnnotationVersion uint32
 }
 
 // LogMetadata stores metadata information about a commit log
@@ -191,6 +191,10 @@ func (r *dbMergedBlockReader) Reset(_ ts.Segment) {
 	panic(fmt.Errorf("merged block reader not available for re-use"))
 }
 
+func (r *dbMergedBlockReader) ResetAndFinalizePrevious(_ ts.Segment) {
+	panic(fmt.Errorf("merged block reader not available for re-use"))
+}
+
 func (r *dbMergedBlockReader) ResetWindowed(_ ts.Segment, _, _ time.Time) {
 	panic(fmt.Errorf("merged block reader not available for re-use"))
 }
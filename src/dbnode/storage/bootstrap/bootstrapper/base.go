@@ -173,7 +173,9 @@ func (b baseBootstrapper) runBootstrapStep(
 	unfulfilled := totalRanges.Copy()
 	unfulfilled.Subtract(fulfilledRanges)
 
-	step.mergeResults(unfulfilled)
+	if err := step.mergeResults(unfulfilled); err != nil {
+		return err
+	}
 
 	unattemptedNextRanges := currRanges.Copy()
 	unattemptedNextRanges.Subtract(currStatus.fulfilled)
@@ -195,7 +197,9 @@ func (b baseBootstrapper) runBootstrapStep(
 
 		unfulfilledFinal := unfulfilled.Copy()
 		unfulfilledFinal.Subtract(nextStatus.fulfilled)
-		step.mergeResults(unfulfilledFinal)
+		if err := step.mergeResults(unfulfilledFinal); err != nil {
+			return err
+		}
 	}
 
 	return nil
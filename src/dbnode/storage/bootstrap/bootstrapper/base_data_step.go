@@ -107,7 +107,7 @@ func (s *bootstrapData) runNextStep(
 
 func (s *bootstrapData) mergeResults(
 	totalUnfulfilled result.ShardTimeRanges,
-) {
+) error {
 	if s.mergedResult == nil {
 		s.mergedResult = result.NewDataBootstrapResult()
 	}
@@ -122,6 +122,7 @@ func (s *bootstrapData) mergeResults(
 		s.nextResult = nil
 	}
 	s.mergedResult.SetUnfulfilled(totalUnfulfilled)
+	return nil
 }
 
 func (s *bootstrapData) result() result.DataBootstrapResult {
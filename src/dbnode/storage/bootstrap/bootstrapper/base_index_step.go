@@ -40,6 +40,7 @@ type bootstrapIndex struct {
 	currResult   result.IndexBootstrapResult
 	nextResult   result.IndexBootstrapResult
 	mergedResult result.IndexBootstrapResult
+	discarded    result.ShardTimeRanges
 }
 
 func newBootstrapIndexStep(
@@ -113,21 +114,35 @@ func (s *bootstrapIndex) runNextStep(
 
 func (s *bootstrapIndex) mergeResults(
 	totalUnfulfilled result.ShardTimeRanges,
-) {
+) error {
 	if s.mergedResult == nil {
 		s.mergedResult = result.NewIndexBootstrapResult()
 	}
+	if s.discarded == nil {
+		s.discarded = result.ShardTimeRanges{}
+	}
 	if s.currResult != nil {
 		// Merge the curr results in
 		s.mergedResult.IndexResults().AddResults(s.currResult.IndexResults())
 		s.currResult = nil
 	}
 	if s.nextResult != nil {
-		// Merge the next results in
-		s.mergedResult.IndexResults().AddResults(s.nextResult.IndexResults())
+		// Merge the next results in, the curr results take precedence as
+		// the first result when resolving overlapping index blocks
+		discarded, err := s.mergedResult.IndexResults().AddResultsWithPrecedence(
+			s.nextResult.IndexResults(), s.opts.IndexMergePrecedence())
 		s.nextResult = nil
+		if err != nil {
+			return err
+		}
+		s.discarded.AddRanges(discarded)
 	}
-	s.mergedResult.SetUnfulfilled(totalUnfulfilled)
+
+	// Ranges fulfilled only by discarded index blocks are unfulfilled
+	unfulfilled := totalUnfulfilled.Copy()
+	unfulfilled.AddRanges(s.discarded)
+	s.mergedResult.SetUnfulfilled(unfulfilled)
+	return nil
 }
 
 func (s *bootstrapIndex) result() result.IndexBootstrapResult {
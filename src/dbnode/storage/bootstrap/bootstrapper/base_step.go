@@ -29,7 +29,7 @@ type bootstrapStep interface {
 	prepare(totalRanges result.ShardTimeRanges) bootstrapStepPreparedResult
 	runCurrStep(targetRanges result.ShardTimeRanges) (bootstrapStepStatus, error)
 	runNextStep(targetRanges result.ShardTimeRanges) (bootstrapStepStatus, error)
-	mergeResults(totalUnfulfilled result.ShardTimeRanges)
+	mergeResults(totalUnfulfilled result.ShardTimeRanges) error
 }
 
 type bootstrapStepPreparedResult struct {
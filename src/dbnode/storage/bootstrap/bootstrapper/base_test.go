@@ -503,3 +503,72 @@ func TestBaseBootstrapperIndexHalfCurrentHalfNext(t *testing.T) {
 	assert.True(t, segSecond == second.Segments()[0])
 	assert.Equal(t, secondHalf, map[uint32]xtime.Ranges(second.Fulfilled()))
 }
+
+func TestBaseBootstrapperIndexMergePrecedence(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	source, next, base := testBaseBootstrapper(t, ctrl)
+	testNs := testNsMetadata(t)
+
+	targetRanges := map[uint32]xtime.Ranges{
+		testShard: xtime.NewRanges(xtime.Range{
+			Start: testTargetStart,
+			End:   testTargetStart.Add(2 * time.Hour),
+		}),
+	}
+	firstHalf := map[uint32]xtime.Ranges{
+		testShard: xtime.NewRanges(xtime.Range{
+			Start: testTargetStart,
+			End:   testTargetStart.Add(1 * time.Hour),
+		}),
+	}
+	secondHalf := map[uint32]xtime.Ranges{
+		testShard: xtime.NewRanges(xtime.Range{
+			Start: testTargetStart.Add(1 * time.Hour),
+			End:   testTargetStart.Add(2 * time.Hour),
+		}),
+	}
+
+	segFirst, err := mem.NewSegment(0, mem.NewOptions())
+	require.NoError(t, err)
+
+	// The next result overlaps the current result for the same index block
+	// so it is discarded and its segment closed
+	segSecond := segment.NewMockSegment(ctrl)
+	segSecond.EXPECT().Close().Return(nil)
+
+	currResult := result.NewIndexBootstrapResult()
+	currResult.Add(result.NewIndexBlock(testTargetStart,
+		[]segment.Segment{segFirst}, firstHalf), secondHalf)
+	nextResult := result.NewIndexBootstrapResult()
+	nextResult.Add(result.NewIndexBlock(testTargetStart,
+		[]segment.Segment{segSecond}, targetRanges), nil)
+
+	runOpts := testDefaultRunOpts.
+		SetIndexMergePrecedence(result.IndexMergePreferFirst)
+
+	source.EXPECT().Can(bootstrap.BootstrapParallel).Return(false)
+	source.EXPECT().
+		AvailableIndex(testNs, shardTimeRangesMatcher{targetRanges}).
+		Return(targetRanges)
+	source.EXPECT().
+		ReadIndex(testNs, shardTimeRangesMatcher{targetRanges}, runOpts).
+		Return(currResult, nil)
+
+	next.EXPECT().
+		BootstrapIndex(testNs, shardTimeRangesMatcher{secondHalf}, runOpts).
+		Return(nextResult, nil)
+
+	res, err := base.BootstrapIndex(testNs, targetRanges, runOpts)
+	require.NoError(t, err)
+
+	// The ranges only fulfilled by the discarded block are unfulfilled
+	assert.True(t, result.ShardTimeRanges(secondHalf).Equal(res.Unfulfilled()))
+	require.Equal(t, 1, len(res.IndexResults()))
+
+	block, ok := res.IndexResults()[xtime.ToUnixNano(testTargetStart)]
+	require.True(t, ok)
+	require.Equal(t, 1, len(block.Segments()))
+	assert.True(t, segFirst == block.Segments()[0])
+	assert.Equal(t, firstHalf, map[uint32]xtime.Ranges(block.Fulfilled()))
+}
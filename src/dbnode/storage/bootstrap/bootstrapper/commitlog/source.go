@@ -232,6 +232,7 @@ func (s *commitLogSource) ReadData(
 			CommitLogOptions:      s.opts.CommitLogOptions(),
 			FileFilterPredicate:   readCommitLogPred,
 			SeriesFilterPredicate: readSeriesPredicate,
+			InstrumentOptions:     s.opts.CommitLogOptions().InstrumentOptions(),
 		}
 	)
 
@@ -1314,6 +1315,7 @@ func (s *commitLogSource) ReadIndex(
 			CommitLogOptions:      s.opts.CommitLogOptions(),
 			FileFilterPredicate:   readCommitLogPredicate,
 			SeriesFilterPredicate: readSeriesPredicate,
+			InstrumentOptions:     s.opts.CommitLogOptions().InstrumentOptions(),
 		}
 	)
 
@@ -700,6 +700,22 @@ func (i *testCommitLogIterator) Current() (commitlog.Series, ts.Datapoint, xtime
 	return v.s, ts.Datapoint{Timestamp: v.t, Value: v.v}, v.u, v.a
 }
 
+func (i *testCommitLogIterator) DecodedAnnotation() interface{} {
+	return nil
+}
+
+func (i *testCommitLogIterator) SequenceNumber() uint64 {
+	return 0
+}
+
+func (i *testCommitLogIterator) AnnotationVersion() uint32 {
+	return 0
+}
+
+func (i *testCommitLogIterator) Corrupt() int {
+	return 0
+}
+
 func (i *testCommitLogIterator) Err() error {
 	return i.err
 }
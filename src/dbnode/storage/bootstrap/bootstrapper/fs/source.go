@@ -919,15 +919,20 @@ func (s *fileSystemSource) read(
 		return newRunResult(), nil
 	}
 
-	setOrMergeResult := func(newResult *runResult) {
+	setOrMergeResult := func(newResult *runResult) error {
 		if newResult == nil {
-			return
+			return nil
 		}
 		if res == nil {
 			res = newResult
-		} else {
-			res = res.mergedResult(newResult)
+			return nil
+		}
+		merged, err := res.mergedResult(newResult)
+		if err != nil {
+			return err
 		}
+		res = merged
+		return nil
 	}
 
 	if run == bootstrapDataRunType {
@@ -982,7 +987,9 @@ func (s *fileSystemSource) read(
 			shardsTimeRanges = shardsTimeRanges.Copy()
 			shardsTimeRanges.Subtract(r.fulfilled)
 			// Set or merge result
-			setOrMergeResult(r.result)
+			if err := setOrMergeResult(r.result); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -997,7 +1004,9 @@ func (s *fileSystemSource) read(
 		readerPool, blockRetriever, readersCh)
 
 	// Merge any existing results if necessary
-	setOrMergeResult(bootstrapFromDataReadersResult)
+	if err := setOrMergeResult(bootstrapFromDataReadersResult); err != nil {
+		return nil, err
+	}
 
 	return res, nil
 }
@@ -1145,8 +1154,19 @@ func (s *fileSystemSource) bootstrapFromIndexPersistedBlocks(
 			segmentsFulfilled)
 		// NB(r): Don't need to call MarkFulfilled on the IndexResults here
 		// as we've already passed the ranges fulfilled to the block that
-		// we place in the IndexResuts with the call to Add(...)
-		res.result.index.Add(indexBlock, nil)
+		// we place in the IndexResuts with the call to AddChecked(...)
+		if err := res.result.index.IndexResults().AddChecked(indexBlock); err != nil {
+			s.log.WithFields(
+				xlog.NewField("namespace", ns.ID().String()),
+				xlog.NewField("error", err.Error()),
+				xlog.NewField("blockStart", indexBlockStart.String()),
+				xlog.NewField("volumeIndex", infoFile.ID.VolumeIndex),
+			).Error("unable to add segments from index fileset")
+			for _, seg := range segments {
+				seg.Close()
+			}
+			continue
+		}
 		res.fulfilled.AddRanges(segmentsFulfilled)
 	}
 
@@ -1270,11 +1290,16 @@ func (r *runResult) getOrAddIndexSegment(
 	return indexBlockSegment, err
 }
 
-func (r *runResult) mergedResult(other *runResult) *runResult {
+func (r *runResult) mergedResult(other *runResult) (*runResult, error) {
+	index, err := result.MergedIndexBootstrapResult(r.index, other.index,
+		result.IndexMergeAppend)
+	if err != nil {
+		return nil, err
+	}
 	return &runResult{
 		data:  result.MergedDataBootstrapResult(r.data, other.data),
-		index: result.MergedIndexBootstrapResult(r.index, other.index),
-	}
+		index: index,
+	}, nil
 }
 
 type shardTimeRangesTimeWindowGroup struct {
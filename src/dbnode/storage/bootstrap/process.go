@@ -177,7 +177,11 @@ func (b bootstrapProcess) bootstrapIndex(
 			return nil, err
 		}
 
-		bootstrapResult = result.MergedIndexBootstrapResult(bootstrapResult, res)
+		bootstrapResult, err = result.MergedIndexBootstrapResult(bootstrapResult,
+			res, result.IndexMergeAppend)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return bootstrapResult, nil
@@ -302,5 +306,8 @@ func (b bootstrapProcess) newRunOptions() RunOptions {
 	return NewRunOptions().
 		SetCacheSeriesMetadata(
 			b.processOpts.CacheSeriesMetadata(),
+		).
+		SetIndexMergePrecedence(
+			b.processOpts.IndexMergePrecedence(),
 		)
 }
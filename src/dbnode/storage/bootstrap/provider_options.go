@@ -20,20 +20,30 @@
 
 package bootstrap
 
+import (
+	"github.com/m3db/m3db/src/dbnode/storage/bootstrap/result"
+)
+
 const (
 	// defaultCacheSeriesMetadata declares that by default bootstrap providers should
 	// cache series metadata between runs.
 	defaultCacheSeriesMetadata = true
+
+	// defaultIndexMergePrecedence declares that by default index results from
+	// bootstrappers are appended to each other regardless of overlap.
+	defaultIndexMergePrecedence = result.IndexMergeAppend
 )
 
 type processOptions struct {
-	cacheSeriesMetadata bool
+	cacheSeriesMetadata  bool
+	indexMergePrecedence result.IndexMergePrecedence
 }
 
 // NewProcessOptions creates new bootstrap run options
 func NewProcessOptions() ProcessOptions {
 	return &processOptions{
-		cacheSeriesMetadata: defaultCacheSeriesMetadata,
+		cacheSeriesMetadata:  defaultCacheSeriesMetadata,
+		indexMergePrecedence: defaultIndexMergePrecedence,
 	}
 }
 
@@ -46,3 +56,13 @@ func (o *processOptions) SetCacheSeriesMetadata(value bool) ProcessOptions {
 func (o *processOptions) CacheSeriesMetadata() bool {
 	return o.cacheSeriesMetadata
 }
+
+func (o *processOptions) SetIndexMergePrecedence(value result.IndexMergePrecedence) ProcessOptions {
+	opts := *o
+	opts.indexMergePrecedence = value
+	return &opts
+}
+
+func (o *processOptions) IndexMergePrecedence() result.IndexMergePrecedence {
+	return o.indexMergePrecedence
+}
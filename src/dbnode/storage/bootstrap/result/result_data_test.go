@@ -21,6 +21,7 @@
 package result
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
@@ -415,6 +416,33 @@ func TestShardTimeRangesSubtract(t *testing.T) {
 	}))
 }
 
+func TestShardTimeRangesEqual(t *testing.T) {
+	start := time.Now().Truncate(testBlockSize)
+
+	newRanges := func(secondEnd time.Time) ShardTimeRanges {
+		return ShardTimeRanges{
+			0: xtime.NewRanges(xtime.Range{
+				Start: start,
+				End:   start.Add(testBlockSize),
+			}).AddRange(xtime.Range{
+				Start: start.Add(2 * testBlockSize),
+				End:   secondEnd,
+			}),
+		}
+	}
+
+	str := newRanges(start.Add(3 * testBlockSize))
+	assert.True(t, str.Equal(newRanges(start.Add(3*testBlockSize))))
+	assert.True(t, str.Equal(str.Copy()))
+
+	// Only the last range differs
+	assert.False(t, str.Equal(newRanges(start.Add(4*testBlockSize))))
+
+	// Different shards
+	assert.False(t, str.Equal(ShardTimeRanges{1: str[0]}))
+	assert.False(t, str.Equal(ShardTimeRanges{}))
+}
+
 func TestShardTimeRangesMinMax(t *testing.T) {
 
 	start := time.Now().Truncate(testBlockSize)
@@ -488,3 +516,37 @@ func TestShardTimeRangesSummaryString(t *testing.T) {
 
 	assert.Equal(t, expected, str.SummaryString())
 }
+
+func TestShardTimeRangesJSON(t *testing.T) {
+	start := time.Unix(1472824800, 0).UTC()
+
+	str := ShardTimeRanges{
+		0: xtime.NewRanges(xtime.Range{
+			Start: start,
+			End:   start.Add(testBlockSize),
+		}).AddRange(xtime.Range{
+			Start: start.Add(2 * testBlockSize),
+			End:   start.Add(4 * testBlockSize),
+		}),
+		1: xtime.NewRanges(xtime.Range{
+			Start: start,
+			End:   start.Add(2 * testBlockSize),
+		}),
+	}
+
+	data, err := json.Marshal(str)
+	require.NoError(t, err)
+
+	expected := `{` +
+		`"0":[{"start":"2016-09-02T14:00:00Z","end":"2016-09-02T16:00:00Z"},` +
+		`{"start":"2016-09-02T18:00:00Z","end":"2016-09-02T22:00:00Z"}],` +
+		`"1":[{"start":"2016-09-02T14:00:00Z","end":"2016-09-02T18:00:00Z"}]` +
+		`}`
+	assert.Equal(t, expected, string(data))
+
+	var parsed ShardTimeRanges
+	require.NoError(t, json.Unmarshal(data, &parsed))
+	assert.True(t, str.Equal(parsed))
+
+	require.Error(t, json.Unmarshal([]byte(`{"0":"foo"}`), &parsed))
+}
@@ -21,20 +21,44 @@
 package result
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/m3db/m3db/src/dbnode/persist"
 	"github.com/m3db/m3db/src/dbnode/storage/namespace"
+	"github.com/m3db/m3db/src/m3ninx/doc"
+	"github.com/m3db/m3db/src/m3ninx/index"
 	"github.com/m3db/m3db/src/m3ninx/index/segment"
 	"github.com/m3db/m3db/src/m3ninx/index/segment/mem"
+	xerrors "github.com/m3db/m3x/errors"
 	xtime "github.com/m3db/m3x/time"
 )
 
+// ErrIndexBlockStartZero is returned when adding an index block with a zero
+// block start to index results.
+var ErrIndexBlockStartZero = errors.New("index block start is zero")
+
 // NewDefaultMutableSegmentAllocator returns a default mutable segment
 // allocator.
 func NewDefaultMutableSegmentAllocator() MutableSegmentAllocator {
+	return NewMutableSegmentAllocator(0, mem.NewOptions())
+}
+
+// NewMutableSegmentAllocator returns a mutable segment allocator that
+// allocates memory segments with the given options, sized to initially hold
+// the given number of documents if it is positive or the options' initial
+// capacity otherwise.
+func NewMutableSegmentAllocator(
+	initialDocs int,
+	opts mem.Options,
+) MutableSegmentAllocator {
+	if initialDocs > 0 {
+		opts = opts.SetInitialCapacity(initialDocs)
+	}
 	return func() (segment.MutableSegment, error) {
-		return mem.NewSegment(0, mem.NewOptions())
+		return mem.NewSegment(0, opts)
 	}
 }
 
@@ -69,10 +93,18 @@ func (r *indexBootstrapResult) Add(block IndexBlock, unfulfilled ShardTimeRanges
 }
 
 // Add will add an index block to the collection, merging if one already
-// exists.
+// exists. Blocks with a zero block start are dropped, use AddChecked to
+// find out whether the block was added.
 func (r IndexResults) Add(block IndexBlock) {
+	r.AddChecked(block)
+}
+
+// AddChecked will add an index block to the collection, merging if one
+// already exists, returning ErrIndexBlockStartZero without adding the block
+// if its block start is zero.
+func (r IndexResults) AddChecked(block IndexBlock) error {
 	if block.BlockStart().IsZero() {
-		return
+		return ErrIndexBlockStartZero
 	}
 
 	// Merge results
@@ -80,9 +112,10 @@ func (r IndexResults) Add(block IndexBlock) {
 	existing, ok := r[blockStart]
 	if !ok {
 		r[blockStart] = block
-		return
+		return nil
 	}
 	r[blockStart] = existing.Merged(block)
+	return nil
 }
 
 // AddResults will add another set of index results to the collection, merging
@@ -93,7 +126,9 @@ func (r IndexResults) AddResults(other IndexResults) {
 	}
 }
 
-// GetOrAddSegment get or create a new mutable segment.
+// GetOrAddSegment get or create a new mutable segment. If results merged
+// from several bootstrappers left more than one mutable segment for the block
+// they are merged into the first so that there is one per block start.
 func (r IndexResults) GetOrAddSegment(
 	t time.Time,
 	idxopts namespace.IndexOptions,
@@ -106,11 +141,26 @@ func (r IndexResults) GetOrAddSegment(
 	blockStart := t.Truncate(idxopts.BlockSize())
 	blockStartNanos := xtime.ToUnixNano(blockStart)
 
+	// NB: The block is only added to the results once it has a mutable
+	// segment, so a failed allocation does not leave an empty block behind
+	// and a retry allocates again.
 	block, exists := r[blockStartNanos]
 	if !exists {
 		block = NewIndexBlock(blockStart, nil, nil)
+	}
+
+	block, merged, err := block.consolidated()
+	if err != nil {
+		return nil, err
+	}
+	if exists {
 		r[blockStartNanos] = block
 	}
+	if merged > 1 {
+		opts.InstrumentOptions().Logger().Infof(
+			"merged %d mutable index segments for block start %v",
+			merged, blockStart)
+	}
 	for _, seg := range block.Segments() {
 		if mutable, ok := seg.(segment.MutableSegment); ok {
 			return mutable, nil
@@ -128,6 +178,113 @@ func (r IndexResults) GetOrAddSegment(
 	return mutable, nil
 }
 
+// consolidated returns the index block with duplicate segments removed and
+// its mutable segments merged into the first of them, along with the number
+// of mutable segments that were merged. Merged segments are closed.
+func (b IndexBlock) consolidated() (IndexBlock, int, error) {
+	distinct, mutables := distinctSegments(b.segments)
+	if len(mutables) < 2 {
+		r := b
+		r.segments = distinct
+		return r, len(mutables), nil
+	}
+
+	if _, err := mergeMutableSegments(mutables); err != nil {
+		return b, 0, err
+	}
+
+	// Keep the first mutable segment, the rest were merged into it
+	segments := make([]segment.Segment, 0, len(distinct)-len(mutables)+1)
+	for _, seg := range distinct {
+		if _, ok := seg.(segment.MutableSegment); ok && seg != mutables[0] {
+			continue
+		}
+		segments = append(segments, seg)
+	}
+
+	r := b
+	r.segments = segments
+	return r, len(mutables), nil
+}
+
+// distinctSegments returns the segments with duplicates removed, in the order
+// they were first seen, along with the distinct mutable segments among them.
+func distinctSegments(
+	segments []segment.Segment,
+) ([]segment.Segment, []segment.MutableSegment) {
+	var (
+		seen     = make(map[segment.Segment]struct{}, len(segments))
+		distinct = make([]segment.Segment, 0, len(segments))
+		mutables []segment.MutableSegment
+	)
+	for _, seg := range segments {
+		if _, ok := seen[seg]; ok {
+			continue
+		}
+		seen[seg] = struct{}{}
+		distinct = append(distinct, seg)
+
+		if mutable, ok := seg.(segment.MutableSegment); ok {
+			mutables = append(mutables, mutable)
+		}
+	}
+	return distinct, mutables
+}
+
+// mergeMutableSegments inserts the documents of every mutable segment into
+// the first and closes the rest, returning the first.
+func mergeMutableSegments(
+	mutables []segment.MutableSegment,
+) (segment.MutableSegment, error) {
+	target := mutables[0]
+	for _, mutable := range mutables[1:] {
+		if err := insertSegmentDocs(target, mutable); err != nil {
+			return nil, err
+		}
+		if err := mutable.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return target, nil
+}
+
+// insertSegmentDocs inserts all the documents in the source segment into the
+// target, documents already in the target are skipped.
+func insertSegmentDocs(target segment.MutableSegment, source segment.Segment) error {
+	reader, err := source.Reader()
+	if err != nil {
+		return err
+	}
+
+	iter, err := reader.AllDocs()
+	if err != nil {
+		reader.Close()
+		return err
+	}
+
+	var docs []doc.Document
+	for iter.Next() {
+		docs = append(docs, iter.Current())
+	}
+
+	var multiErr xerrors.MultiError
+	multiErr = multiErr.Add(iter.Err())
+	multiErr = multiErr.Add(iter.Close())
+	multiErr = multiErr.Add(reader.Close())
+	if err := multiErr.FinalError(); err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+
+	err = target.InsertBatch(index.NewBatch(docs, index.AllowPartialUpdates()))
+	if partialErr, ok := err.(*index.BatchPartialError); ok {
+		err = partialErr.FilterDuplicateIDErrors()
+	}
+	return err
+}
+
 // MarkFulfilled will mark an index block as fulfilled, either partially or
 // wholly as specified by the shard time ranges passed.
 func (r IndexResults) MarkFulfilled(
@@ -163,15 +320,304 @@ func (r IndexResults) MarkFulfilled(
 	return nil
 }
 
-// MergedIndexBootstrapResult returns a merged result of two bootstrap results.
-// It is a mutating function that mutates the larger result by adding the
-// smaller result to it and then finally returns the mutated result.
-func MergedIndexBootstrapResult(i, j IndexBootstrapResult) IndexBootstrapResult {
+// MarkFulfilledRanges will mark the index blocks spanned by the shard time
+// ranges passed as fulfilled, splitting the ranges at index block boundaries
+// and marking each block with the part of the ranges that falls within it.
+func (r IndexResults) MarkFulfilledRanges(
+	fulfilled ShardTimeRanges,
+	idxopts namespace.IndexOptions,
+) error {
+	var (
+		blockSize = idxopts.BlockSize()
+		byBlock   = make(map[xtime.UnixNano]ShardTimeRanges)
+	)
+	for shard, ranges := range fulfilled {
+		it := ranges.Iter()
+		for it.Next() {
+			tr := it.Value()
+			blockStart := tr.Start.Truncate(blockSize)
+			if blockStart.IsZero() {
+				return fmt.Errorf("fulfilled range %s does not fall within a valid index block",
+					tr.String())
+			}
+
+			for ; blockStart.Before(tr.End); blockStart = blockStart.Add(blockSize) {
+				blockRange := xtime.Range{
+					Start: blockStart,
+					End:   blockStart.Add(blockSize),
+				}
+				if tr.Start.After(blockRange.Start) {
+					blockRange.Start = tr.Start
+				}
+				if tr.End.Before(blockRange.End) {
+					blockRange.End = tr.End
+				}
+
+				blockStartNanos := xtime.ToUnixNano(blockStart)
+				blockFulfilled, ok := byBlock[blockStartNanos]
+				if !ok {
+					blockFulfilled = make(ShardTimeRanges)
+					byBlock[blockStartNanos] = blockFulfilled
+				}
+				blockFulfilled.AddRanges(ShardTimeRanges{
+					shard: xtime.NewRanges(blockRange),
+				})
+			}
+		}
+	}
+
+	for blockStart, blockFulfilled := range byBlock {
+		if err := r.MarkFulfilled(blockStart.ToTime(), blockFulfilled, idxopts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveBlock removes the index block for the given block start, returning
+// the removed block and whether it existed. The caller takes ownership of the
+// removed block's segments.
+func (r IndexResults) RemoveBlock(blockStart time.Time) (IndexBlock, bool) {
+	blockStartNanos := xtime.ToUnixNano(blockStart)
+	block, ok := r[blockStartNanos]
+	if !ok {
+		return IndexBlock{}, false
+	}
+	delete(r, blockStartNanos)
+	return block, true
+}
+
+// FlushBlock persists the index block for the given block start as a single
+// sealed segment, holding the documents of all of the block's segments, with
+// the given index persist function. If evict is set the block is then removed
+// and its segments closed, releasing the memory it held, otherwise the block
+// is left as it was. It is a no-op if there is no block for the block start.
+func (r IndexResults) FlushBlock(
+	blockStart time.Time,
+	persistFn persist.IndexFn,
+	opts Options,
+	evict bool,
+) error {
+	blockStartNanos := xtime.ToUnixNano(blockStart)
+	block, ok := r[blockStartNanos]
+	if !ok {
+		return nil
+	}
+
+	merged, err := opts.IndexMutableSegmentAllocator()()
+	if err != nil {
+		return err
+	}
+
+	distinct, _ := distinctSegments(block.segments)
+	for _, seg := range distinct {
+		if err := insertSegmentDocs(merged, seg); err != nil {
+			merged.Close()
+			return err
+		}
+	}
+
+	if _, err := merged.Seal(); err != nil {
+		merged.Close()
+		return err
+	}
+	if err := persistFn(merged); err != nil {
+		merged.Close()
+		return err
+	}
+	if err := merged.Close(); err != nil {
+		return err
+	}
+
+	if !evict {
+		return nil
+	}
+
+	delete(r, blockStartNanos)
+	var multiErr xerrors.MultiError
+	for _, seg := range distinct {
+		multiErr = multiErr.Add(seg.Close())
+	}
+	return multiErr.FinalError()
+}
+
+// EvictFulfilled removes the index blocks whose fulfilled ranges cover the
+// entire index block range for every shard they have fulfilled, returning the
+// removed blocks. The caller takes ownership of the removed blocks' segments,
+// this allows bootstrapped blocks to be flushed and released incrementally.
+func (r IndexResults) EvictFulfilled(idxopts namespace.IndexOptions) []IndexBlock {
+	var evicted []IndexBlock
+	for blockStart, block := range r {
+		if !block.fulfilledWholeBlock(idxopts.BlockSize()) {
+			continue
+		}
+		delete(r, blockStart)
+		evicted = append(evicted, block)
+	}
+	return evicted
+}
+
+// NumSegments returns the number of distinct segments held across all the
+// index blocks, both mutable and immutable.
+func (r IndexResults) NumSegments() int {
+	var n int
+	r.forEachSegment(func(segment.Segment) {
+		n++
+	})
+	return n
+}
+
+// NumDocuments returns the number of documents held across all the index
+// blocks, summing the size of every distinct mutable and immutable segment.
+// A document indexed in more than one segment is counted once per segment,
+// segment sizes are document counts so this is the closest proxy for the
+// memory held by the index results that segments expose.
+func (r IndexResults) NumDocuments() int64 {
+	var n int64
+	r.forEachSegment(func(seg segment.Segment) {
+		n += seg.Size()
+	})
+	return n
+}
+
+// forEachSegment calls fn once for every distinct segment in the index blocks,
+// the same segment may be held by more than one block after merging results.
+func (r IndexResults) forEachSegment(fn func(seg segment.Segment)) {
+	seen := make(map[segment.Segment]struct{})
+	for _, block := range r {
+		for _, seg := range block.Segments() {
+			if _, ok := seen[seg]; ok {
+				continue
+			}
+			seen[seg] = struct{}{}
+			fn(seg)
+		}
+	}
+}
+
+// AddResultsWithPrecedence will add another set of index results to the
+// collection using the given precedence to resolve index blocks that have
+// overlapping fulfilled ranges. Segments cannot be split by shard or time so
+// the block that loses is discarded as a whole and its segments are closed,
+// the returned shard time ranges are the ranges that the discarded blocks had
+// fulfilled which are not fulfilled by the blocks that were kept and should
+// be treated as unfulfilled by the caller.
+func (r IndexResults) AddResultsWithPrecedence(
+	other IndexResults,
+	precedence IndexMergePrecedence,
+) (ShardTimeRanges, error) {
+	var (
+		discarded = ShardTimeRanges{}
+		multiErr  xerrors.MultiError
+	)
+	for blockStart, block := range other {
+		existing, ok := r[blockStart]
+		if !ok || precedence == IndexMergeAppend || !existing.overlaps(block) {
+			r.Add(block)
+			continue
+		}
+
+		kept, dropped := existing, block
+		if precedence == IndexMergePreferSecond {
+			kept, dropped = block, existing
+		}
+
+		lost := dropped.fulfilled.Copy()
+		lost.Subtract(kept.fulfilled)
+		discarded.AddRanges(lost)
+
+		for _, seg := range dropped.segments {
+			if err := seg.Close(); err != nil {
+				multiErr = multiErr.Add(err)
+			}
+		}
+
+		r[blockStart] = kept
+	}
+	return discarded, multiErr.FinalError()
+}
+
+// ConcurrentIndexResults is a collection of index results that is safe to
+// add to and get or add segments from concurrently.
+type ConcurrentIndexResults struct {
+	sync.Mutex
+	results IndexResults
+}
+
+// NewConcurrentIndexResults returns a new concurrent index results.
+func NewConcurrentIndexResults() *ConcurrentIndexResults {
+	return &ConcurrentIndexResults{results: make(IndexResults)}
+}
+
+// Add will add an index block to the collection, see IndexResults.Add.
+func (r *ConcurrentIndexResults) Add(block IndexBlock) {
+	r.Lock()
+	r.results.Add(block)
+	r.Unlock()
+}
+
+// AddResults will add another set of index results to the collection, see
+// IndexResults.AddResults.
+func (r *ConcurrentIndexResults) AddResults(other IndexResults) {
+	r.Lock()
+	r.results.AddResults(other)
+	r.Unlock()
+}
+
+// GetOrAddSegment get or create a new mutable segment, see
+// IndexResults.GetOrAddSegment.
+func (r *ConcurrentIndexResults) GetOrAddSegment(
+	t time.Time,
+	idxopts namespace.IndexOptions,
+	opts Options,
+) (segment.MutableSegment, error) {
+	r.Lock()
+	defer r.Unlock()
+	return r.results.GetOrAddSegment(t, idxopts, opts)
+}
+
+// MarkFulfilled will mark an index block as fulfilled, see
+// IndexResults.MarkFulfilled.
+func (r *ConcurrentIndexResults) MarkFulfilled(
+	t time.Time,
+	fulfilled ShardTimeRanges,
+	idxopts namespace.IndexOptions,
+) error {
+	r.Lock()
+	defer r.Unlock()
+	return r.results.MarkFulfilled(t, fulfilled, idxopts)
+}
+
+// IndexResults returns the underlying index results, they must not be used
+// concurrently with any of the other methods.
+func (r *ConcurrentIndexResults) IndexResults() IndexResults {
+	r.Lock()
+	defer r.Unlock()
+	return r.results
+}
+
+// MergedIndexBootstrapResult returns a merged result of two bootstrap results
+// using the given precedence to resolve index blocks with overlapping fulfilled
+// ranges, see AddResultsWithPrecedence. It is a mutating function that mutates
+// one of the results and then finally returns the mutated result. With
+// IndexMergeAppend the larger result is mutated by adding the smaller result
+// to it, otherwise the first result is mutated.
+func MergedIndexBootstrapResult(
+	i, j IndexBootstrapResult,
+	precedence IndexMergePrecedence,
+) (IndexBootstrapResult, error) {
 	if i == nil {
-		return j
+		return j, nil
 	}
 	if j == nil {
-		return i
+		return i, nil
+	}
+	if precedence != IndexMergeAppend {
+		discarded, err := i.IndexResults().AddResultsWithPrecedence(
+			j.IndexResults(), precedence)
+		i.Unfulfilled().AddRanges(j.Unfulfilled())
+		i.Unfulfilled().AddRanges(discarded)
+		return i, err
 	}
 	sizeI, sizeJ := 0, 0
 	for _, ir := range i.IndexResults() {
@@ -180,14 +626,30 @@ func MergedIndexBootstrapResult(i, j IndexBootstrapResult) IndexBootstrapResult
 	for _, ir := range j.IndexResults() {
 		sizeJ += len(ir.Segments())
 	}
-	if sizeI >= sizeJ {
-		i.IndexResults().AddResults(j.IndexResults())
-		i.Unfulfilled().AddRanges(j.Unfulfilled())
-		return i
+	if sizeI < sizeJ {
+		i, j = j, i
 	}
-	j.IndexResults().AddResults(i.IndexResults())
-	j.Unfulfilled().AddRanges(i.Unfulfilled())
-	return j
+	results := i.IndexResults()
+	results.AddResults(j.IndexResults())
+	i.Unfulfilled().AddRanges(j.Unfulfilled())
+
+	// Appending can leave a block with the same segment twice or with a
+	// mutable segment from each result, consolidate the blocks that were
+	// appended to so that superseded segments are closed
+	var multiErr xerrors.MultiError
+	for blockStart := range j.IndexResults() {
+		block, ok := results[blockStart]
+		if !ok {
+			continue
+		}
+		consolidated, _, err := block.consolidated()
+		if err != nil {
+			multiErr = multiErr.Add(err)
+			continue
+		}
+		results[blockStart] = consolidated
+	}
+	return i, multiErr.FinalError()
 }
 
 // NewIndexBlock returns a new bootstrap index block result.
@@ -223,7 +685,7 @@ func (b IndexBlock) Fulfilled() ShardTimeRanges {
 
 // Merged returns a new merged index block, currently it just appends the
 // list of segments from the other index block and the caller merges
-// as they see necessary.
+// as they see necessary, see MergedAndCompact.
 func (b IndexBlock) Merged(other IndexBlock) IndexBlock {
 	r := b
 	if len(other.segments) > 0 {
@@ -235,3 +697,79 @@ func (b IndexBlock) Merged(other IndexBlock) IndexBlock {
 	}
 	return r
 }
+
+// MergedAndCompact returns a new merged index block like Merged, but rather
+// than leaving the caller to merge segments it merges all the mutable segments
+// of both blocks into a single mutable segment allocated with alloc, closing
+// the segments merged. Immutable segments are left as they are and duplicate
+// segments are removed whether or not any were merged. It also
+// returns the number of mutable segments that were collapsed into one, which
+// is zero if there were not at least two to merge.
+func (b IndexBlock) MergedAndCompact(
+	other IndexBlock,
+	alloc MutableSegmentAllocator,
+) (IndexBlock, int, error) {
+	merged := b.Merged(other)
+
+	distinct, mutables := distinctSegments(merged.segments)
+	if len(mutables) < 2 {
+		merged.segments = distinct
+		return merged, 0, nil
+	}
+
+	compacted, err := alloc()
+	if err != nil {
+		return b, 0, err
+	}
+	toMerge := make([]segment.MutableSegment, 0, len(mutables)+1)
+	toMerge = append(append(toMerge, compacted), mutables...)
+	if _, err := mergeMutableSegments(toMerge); err != nil {
+		compacted.Close()
+		return b, 0, err
+	}
+
+	segments := make([]segment.Segment, 0, len(distinct)-len(mutables)+1)
+	for _, seg := range distinct {
+		if _, ok := seg.(segment.MutableSegment); !ok {
+			segments = append(segments, seg)
+		}
+	}
+	merged.segments = append(segments, compacted)
+	return merged, len(mutables), nil
+}
+
+// fulfilledWholeBlock returns whether this index block has fulfilled at least
+// one shard and has fulfilled the entire block range for each of its shards.
+func (b IndexBlock) fulfilledWholeBlock(blockSize time.Duration) bool {
+	if b.fulfilled.IsEmpty() {
+		return false
+	}
+	blockRange := xtime.NewRanges(xtime.Range{
+		Start: b.blockStart,
+		End:   b.blockStart.Add(blockSize),
+	})
+	for _, ranges := range b.fulfilled {
+		if !blockRange.RemoveRanges(ranges).IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+// overlaps returns whether this index block has fulfilled any of the ranges
+// that the other index block has fulfilled.
+func (b IndexBlock) overlaps(other IndexBlock) bool {
+	for shard, otherRanges := range other.fulfilled {
+		ranges, ok := b.fulfilled[shard]
+		if !ok {
+			continue
+		}
+		it := otherRanges.Iter()
+		for it.Next() {
+			if ranges.Overlaps(it.Value()) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -21,11 +21,16 @@
 package result
 
 import (
+	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/m3db/m3db/src/dbnode/storage/namespace"
+	"github.com/m3db/m3db/src/m3ninx/doc"
 	"github.com/m3db/m3db/src/m3ninx/index/segment"
+	"github.com/m3db/m3db/src/m3ninx/index/segment/mem"
 	xtime "github.com/m3db/m3x/time"
 
 	"github.com/golang/mock/gomock"
@@ -70,6 +75,361 @@ func TestIndexResultGetOrAddSegment(t *testing.T) {
 	require.Equal(t, 2, allocated)
 }
 
+func TestIndexResultGetOrAddSegmentRetriesFailedAllocation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var (
+		created   = segment.NewMockMutableSegment(ctrl)
+		allocErr  = errors.New("allocate failed")
+		allocated = 0
+	)
+	opts := NewOptions().
+		SetIndexMutableSegmentAllocator(func() (segment.MutableSegment, error) {
+			allocated++
+			if allocated == 1 {
+				return nil, allocErr
+			}
+			return created, nil
+		})
+
+	blockSize := time.Hour
+	idxOpts := namespace.NewIndexOptions().SetBlockSize(blockSize)
+	blockStart := time.Now().Truncate(blockSize)
+
+	results := IndexResults{}
+	_, err := results.GetOrAddSegment(blockStart, idxOpts, opts)
+	require.Equal(t, allocErr, err)
+	require.Equal(t, 0, len(results))
+
+	seg, err := results.GetOrAddSegment(blockStart, idxOpts, opts)
+	require.NoError(t, err)
+	require.True(t, seg == created)
+	require.Equal(t, 1, len(results))
+	require.Equal(t, []segment.Segment{created},
+		results[xtime.ToUnixNano(blockStart)].Segments())
+	require.Equal(t, 2, allocated)
+}
+
+func newTestMemSegment(t *testing.T, ids ...string) segment.MutableSegment {
+	seg, err := mem.NewSegment(0, mem.NewOptions())
+	require.NoError(t, err)
+	for _, id := range ids {
+		_, err := seg.Insert(doc.Document{
+			ID:     []byte(id),
+			Fields: []doc.Field{{Name: []byte("name"), Value: []byte(id)}},
+		})
+		require.NoError(t, err)
+	}
+	return seg
+}
+
+func TestIndexResultAddCheckedZeroBlockStart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	results := make(IndexResults)
+	err := results.AddChecked(NewIndexBlock(time.Time{},
+		[]segment.Segment{segment.NewMockSegment(ctrl)}, nil))
+	require.Equal(t, ErrIndexBlockStartZero, err)
+	require.Equal(t, 0, len(results))
+
+	start := time.Now().Truncate(testBlockSize)
+	require.NoError(t, results.AddChecked(NewIndexBlock(start,
+		[]segment.Segment{segment.NewMockSegment(ctrl)}, nil)))
+	require.Equal(t, 1, len(results))
+}
+
+func TestIndexResultGetOrAddSegmentMergesMutableSegments(t *testing.T) {
+	blockSize := time.Hour
+	idxOpts := namespace.NewIndexOptions().SetBlockSize(blockSize)
+	blockStart := time.Now().Truncate(blockSize)
+
+	first := newTestMemSegment(t, "foo", "bar")
+	second := newTestMemSegment(t, "bar", "baz")
+	results := IndexResults{}
+	results.Add(NewIndexBlock(blockStart, []segment.Segment{first}, nil))
+	results.Add(NewIndexBlock(blockStart, []segment.Segment{second}, nil))
+
+	seg, err := results.GetOrAddSegment(blockStart, idxOpts, NewOptions())
+	require.NoError(t, err)
+	require.True(t, seg == first)
+	require.Equal(t, int64(3), seg.Size())
+
+	block := results[xtime.ToUnixNano(blockStart)]
+	require.Equal(t, []segment.Segment{first}, block.Segments())
+	for _, id := range []string{"foo", "bar", "baz"} {
+		contains, err := seg.ContainsID([]byte(id))
+		require.NoError(t, err)
+		require.True(t, contains, id)
+	}
+
+	// The merged segment was closed
+	require.Equal(t, int64(0), second.Size())
+}
+
+func TestIndexResultFlushBlock(t *testing.T) {
+	blockSize := time.Hour
+	blockStart := time.Now().Truncate(blockSize)
+
+	first := newTestMemSegment(t, "foo", "bar")
+	second := newTestMemSegment(t, "bar", "baz")
+	results := IndexResults{}
+	results.Add(NewIndexBlock(blockStart, []segment.Segment{first, second}, nil))
+
+	var persisted int
+	persistFn := func(seg segment.MutableSegment) error {
+		persisted++
+		require.True(t, seg.IsSealed())
+		require.Equal(t, int64(3), seg.Size())
+		for _, id := range []string{"foo", "bar", "baz"} {
+			contains, err := seg.ContainsID([]byte(id))
+			require.NoError(t, err)
+			require.True(t, contains, id)
+		}
+		return nil
+	}
+
+	// Flushing a block start without a block does nothing
+	require.NoError(t, results.FlushBlock(blockStart.Add(blockSize), persistFn, NewOptions(), true))
+	require.Equal(t, 0, persisted)
+
+	// Without evicting the block and its segments are left as they were
+	require.NoError(t, results.FlushBlock(blockStart, persistFn, NewOptions(), false))
+	require.Equal(t, 1, persisted)
+	require.Equal(t, 1, len(results))
+	require.Equal(t, int64(2), first.Size())
+	require.Equal(t, int64(2), second.Size())
+
+	require.NoError(t, results.FlushBlock(blockStart, persistFn, NewOptions(), true))
+	require.Equal(t, 2, persisted)
+	require.Equal(t, 0, len(results))
+	require.Equal(t, int64(0), first.Size())
+	require.Equal(t, int64(0), second.Size())
+}
+
+func TestIndexResultFlushBlockPersistError(t *testing.T) {
+	blockStart := time.Now().Truncate(time.Hour)
+
+	seg := newTestMemSegment(t, "foo")
+	results := IndexResults{}
+	results.Add(NewIndexBlock(blockStart, []segment.Segment{seg}, nil))
+
+	persistErr := errors.New("persist failed")
+	err := results.FlushBlock(blockStart, func(segment.MutableSegment) error {
+		return persistErr
+	}, NewOptions(), true)
+	require.Equal(t, persistErr, err)
+
+	// The block is kept so that it can be retried
+	require.Equal(t, 1, len(results))
+	require.Equal(t, int64(1), seg.Size())
+}
+
+func TestIndexResultRemoveBlock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Now().Truncate(testBlockSize)
+	seg := segment.NewMockSegment(ctrl)
+
+	results := IndexResults{}
+	results.Add(NewIndexBlock(start, []segment.Segment{seg}, nil))
+
+	block, ok := results.RemoveBlock(start)
+	require.True(t, ok)
+	require.Equal(t, []segment.Segment{seg}, block.Segments())
+	require.Equal(t, 0, len(results))
+
+	_, ok = results.RemoveBlock(start)
+	require.False(t, ok)
+}
+
+func TestIndexResultEvictFulfilled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	idxOpts := namespace.NewIndexOptions().SetBlockSize(testBlockSize)
+	start := time.Now().Truncate(testBlockSize)
+	times := []time.Time{start, start.Add(testBlockSize), start.Add(2 * testBlockSize)}
+
+	results := IndexResults{}
+	// Wholly fulfilled for every shard
+	results.Add(NewIndexBlock(times[0], []segment.Segment{segment.NewMockSegment(ctrl)},
+		NewShardTimeRanges(times[0], times[1], 1, 2)))
+	// Only partially fulfilled
+	results.Add(NewIndexBlock(times[1], []segment.Segment{segment.NewMockSegment(ctrl)},
+		NewShardTimeRanges(times[1], times[1].Add(testBlockSize/2), 1)))
+	// Not fulfilled at all
+	results.Add(NewIndexBlock(times[2], []segment.Segment{segment.NewMockSegment(ctrl)}, nil))
+
+	evicted := results.EvictFulfilled(idxOpts)
+	require.Equal(t, 1, len(evicted))
+	require.True(t, evicted[0].BlockStart().Equal(times[0]))
+
+	require.Equal(t, 2, len(results))
+	_, ok := results[xtime.ToUnixNano(times[0])]
+	require.False(t, ok)
+}
+
+func TestIndexResultNumSegmentsAndDocuments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Now().Truncate(testBlockSize)
+
+	immutable := segment.NewMockSegment(ctrl)
+	immutable.EXPECT().Size().Return(int64(3)).AnyTimes()
+	mutable := segment.NewMockMutableSegment(ctrl)
+	mutable.EXPECT().Size().Return(int64(5)).AnyTimes()
+	shared := segment.NewMockSegment(ctrl)
+	shared.EXPECT().Size().Return(int64(7)).AnyTimes()
+
+	results := IndexResults{}
+	require.Equal(t, 0, results.NumSegments())
+	require.Equal(t, int64(0), results.NumDocuments())
+
+	results.Add(NewIndexBlock(start, []segment.Segment{immutable, shared}, nil))
+	results.Add(NewIndexBlock(start, []segment.Segment{mutable}, nil))
+	results.Add(NewIndexBlock(start.Add(testBlockSize), []segment.Segment{shared}, nil))
+
+	// The shared segment is only counted once
+	require.Equal(t, 3, results.NumSegments())
+	require.Equal(t, int64(15), results.NumDocuments())
+}
+
+func TestConcurrentIndexResultsGetOrAddSegment(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	created := segment.NewMockMutableSegment(ctrl)
+	var allocated int64
+	opts := NewOptions().
+		SetIndexMutableSegmentAllocator(func() (segment.MutableSegment, error) {
+			atomic.AddInt64(&allocated, 1)
+			return created, nil
+		})
+
+	idxOpts := namespace.NewIndexOptions().SetBlockSize(testBlockSize)
+	start := time.Now().Truncate(testBlockSize)
+	results := NewConcurrentIndexResults()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			blockTime := start.Add(time.Duration(i) * time.Minute)
+			seg, err := results.GetOrAddSegment(blockTime, idxOpts, opts)
+			assert.NoError(t, err)
+			assert.True(t, seg == created)
+			assert.NoError(t, results.MarkFulfilled(blockTime,
+				NewShardTimeRanges(blockTime, blockTime.Add(time.Minute), uint32(i)), idxOpts))
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int64(1), atomic.LoadInt64(&allocated))
+	require.Equal(t, 1, len(results.IndexResults()))
+	block := results.IndexResults()[xtime.ToUnixNano(start)]
+	require.Equal(t, 16, len(block.Fulfilled()))
+}
+
+func TestIndexBlockMergedAndCompact(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Now().Truncate(testBlockSize)
+	tr0 := NewShardTimeRanges(start, start.Add(testBlockSize), 1)
+	tr1 := NewShardTimeRanges(start, start.Add(testBlockSize), 2)
+
+	immutable := segment.NewMockSegment(ctrl)
+	mutables := []segment.MutableSegment{
+		newTestMemSegment(t, "foo"),
+		newTestMemSegment(t, "bar"),
+		newTestMemSegment(t, "bar", "baz"),
+	}
+
+	first := NewIndexBlock(start, []segment.Segment{mutables[0], immutable}, tr0)
+	second := NewIndexBlock(start, []segment.Segment{mutables[1], mutables[2]}, tr1)
+
+	merged, collapsed, err := first.MergedAndCompact(second, NewDefaultMutableSegmentAllocator())
+	require.NoError(t, err)
+	require.Equal(t, 3, collapsed)
+
+	segments := merged.Segments()
+	require.Equal(t, 2, len(segments))
+	require.True(t, segments[0] == immutable)
+	compacted, ok := segments[1].(segment.MutableSegment)
+	require.True(t, ok)
+	require.Equal(t, int64(3), compacted.Size())
+	for _, mutable := range mutables {
+		require.Equal(t, int64(0), mutable.Size())
+	}
+
+	expectedFulfilled := tr0.Copy()
+	expectedFulfilled.AddRanges(tr1)
+	require.True(t, expectedFulfilled.Equal(merged.Fulfilled()))
+
+	// A single mutable segment is left as it is
+	single := newTestMemSegment(t, "foo")
+	merged, collapsed, err = NewIndexBlock(start, []segment.Segment{single}, nil).
+		MergedAndCompact(NewIndexBlock(start, []segment.Segment{immutable}, nil),
+			NewDefaultMutableSegmentAllocator())
+	require.NoError(t, err)
+	require.Equal(t, 0, collapsed)
+	require.Equal(t, []segment.Segment{single, immutable}, merged.Segments())
+
+	// Duplicate segments are removed even if there is nothing to merge
+	merged, collapsed, err = NewIndexBlock(start, []segment.Segment{single, immutable}, nil).
+		MergedAndCompact(NewIndexBlock(start, []segment.Segment{single, immutable}, nil),
+			NewDefaultMutableSegmentAllocator())
+	require.NoError(t, err)
+	require.Equal(t, 0, collapsed)
+	require.Equal(t, []segment.Segment{single, immutable}, merged.Segments())
+}
+
+type closeTrackingSegment struct {
+	segment.MutableSegment
+	closed   bool
+	closeErr error
+}
+
+func (s *closeTrackingSegment) Close() error {
+	s.closed = true
+	if err := s.MutableSegment.Close(); err != nil {
+		return err
+	}
+	return s.closeErr
+}
+
+func TestIndexBlockMergedAndCompactClosesCompactedOnError(t *testing.T) {
+	start := time.Now().Truncate(testBlockSize)
+
+	failing := &closeTrackingSegment{
+		MutableSegment: newTestMemSegment(t, "bar"),
+		closeErr:       errors.New("close failed"),
+	}
+	first := NewIndexBlock(start, []segment.Segment{newTestMemSegment(t, "foo")}, nil)
+	second := NewIndexBlock(start, []segment.Segment{failing}, nil)
+
+	var compacted *closeTrackingSegment
+	alloc := func() (segment.MutableSegment, error) {
+		seg, err := NewDefaultMutableSegmentAllocator()()
+		if err != nil {
+			return nil, err
+		}
+		compacted = &closeTrackingSegment{MutableSegment: seg}
+		return compacted, nil
+	}
+
+	_, _, err := first.MergedAndCompact(second, alloc)
+	require.Error(t, err)
+	require.NotNil(t, compacted)
+	require.True(t, compacted.closed)
+}
+
 func TestIndexResultMergeMergesExistingSegments(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -98,7 +458,8 @@ func TestIndexResultMergeMergesExistingSegments(t *testing.T) {
 	second.Add(NewIndexBlock(times[0], []segment.Segment{segments[4]}, tr0), nil)
 	second.Add(NewIndexBlock(times[1], []segment.Segment{segments[5]}, tr1), nil)
 
-	merged := MergedIndexBootstrapResult(first, second)
+	merged, err := MergedIndexBootstrapResult(first, second, IndexMergeAppend)
+	require.NoError(t, err)
 
 	expected := NewIndexBootstrapResult()
 	expected.Add(NewIndexBlock(times[0], []segment.Segment{segments[0], segments[1], segments[4]}, tr0), nil)
@@ -107,6 +468,143 @@ func TestIndexResultMergeMergesExistingSegments(t *testing.T) {
 	assert.True(t, segmentsInResultsSame(expected.IndexResults(), merged.IndexResults()))
 }
 
+func TestIndexResultMergeClosesSupersededSegments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Now().Truncate(testBlockSize)
+	tr := NewShardTimeRanges(start, start.Add(testBlockSize), 1)
+
+	// Both results share an immutable segment and each has a mutable one
+	shared := segment.NewMockSegment(ctrl)
+	firstMutable := newTestMemSegment(t, "foo")
+	secondMutable := newTestMemSegment(t, "bar")
+
+	first := NewIndexBootstrapResult()
+	first.Add(NewIndexBlock(start, []segment.Segment{firstMutable, shared}, tr), nil)
+	second := NewIndexBootstrapResult()
+	second.Add(NewIndexBlock(start, []segment.Segment{secondMutable, shared}, tr), nil)
+
+	merged, err := MergedIndexBootstrapResult(first, second, IndexMergeAppend)
+	require.NoError(t, err)
+
+	// The shared segment is kept once and is not closed, the second mutable
+	// segment is merged into the first and closed
+	block := merged.IndexResults()[xtime.ToUnixNano(start)]
+	require.Equal(t, []segment.Segment{firstMutable, shared}, block.Segments())
+	require.Equal(t, int64(2), firstMutable.Size())
+	require.Equal(t, int64(0), secondMutable.Size())
+}
+
+func TestIndexResultMergeWithPrecedence(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	start := time.Now().Truncate(testBlockSize)
+	times := []time.Time{start, start.Add(testBlockSize), start.Add(2 * testBlockSize)}
+	tr0 := NewShardTimeRanges(times[0], times[1], 1, 2, 3)
+	tr0Partial := NewShardTimeRanges(times[0], times[1], 1)
+	tr0Other := NewShardTimeRanges(times[0], times[1], 4)
+	tr1 := NewShardTimeRanges(times[1], times[2], 1, 2, 3)
+	tr2 := NewShardTimeRanges(times[2], times[2].Add(testBlockSize), 1, 2, 3)
+
+	// Returns a peers result with a block fulfilling shards 1-3 and a commit
+	// log result whose first block partially overlaps it, fulfilling shard 1
+	// which peers also fulfilled and shard 4 which it did not.
+	newResults := func() (IndexBootstrapResult, IndexBootstrapResult, []*segment.MockSegment) {
+		segments := []*segment.MockSegment{
+			segment.NewMockSegment(ctrl),
+			segment.NewMockSegment(ctrl),
+			segment.NewMockSegment(ctrl),
+			segment.NewMockSegment(ctrl),
+		}
+
+		peers := NewIndexBootstrapResult()
+		peers.Add(NewIndexBlock(times[0], []segment.Segment{segments[0]}, tr0), nil)
+
+		commitLog := NewIndexBootstrapResult()
+		commitLog.Add(NewIndexBlock(times[0], []segment.Segment{segments[1]}, tr0Partial), nil)
+		commitLog.Add(NewIndexBlock(times[0], []segment.Segment{segments[2]}, tr0Other), nil)
+		commitLog.Add(NewIndexBlock(times[1], []segment.Segment{segments[3]}, tr1), tr2)
+		return peers, commitLog, segments
+	}
+
+	assertMerged := func(
+		merged IndexBootstrapResult,
+		segments []*segment.MockSegment,
+	) {
+		expected := NewIndexBootstrapResult()
+		expected.Add(NewIndexBlock(times[0], []segment.Segment{segments[0]}, tr0), nil)
+		expected.Add(NewIndexBlock(times[1], []segment.Segment{segments[3]}, tr1), nil)
+		assert.True(t, segmentsInResultsSame(expected.IndexResults(), merged.IndexResults()))
+
+		results := merged.IndexResults()
+		assert.True(t, tr0.Equal(results[xtime.ToUnixNano(times[0])].Fulfilled()))
+		assert.True(t, tr1.Equal(results[xtime.ToUnixNano(times[1])].Fulfilled()))
+
+		// Shard 4 was only fulfilled by the discarded block so it is now
+		// unfulfilled along with the commit log result's own unfulfilled ranges
+		expectedUnfulfilled := tr2.Copy()
+		expectedUnfulfilled.AddRanges(tr0Other)
+		assert.True(t, expectedUnfulfilled.Equal(merged.Unfulfilled()),
+			"expected unfulfilled %s, actual %s",
+			expectedUnfulfilled.String(), merged.Unfulfilled().String())
+	}
+
+	// Overlapping block from the second result is discarded and closed
+	peers, commitLog, segments := newResults()
+	segments[1].EXPECT().Close().Return(nil)
+	segments[2].EXPECT().Close().Return(nil)
+	merged, err := MergedIndexBootstrapResult(peers, commitLog, IndexMergePreferFirst)
+	require.NoError(t, err)
+	assertMerged(merged, segments)
+
+	// Overlapping block from the first result is discarded and closed
+	peers, commitLog, segments = newResults()
+	segments[1].EXPECT().Close().Return(nil)
+	segments[2].EXPECT().Close().Return(nil)
+	merged, err = MergedIndexBootstrapResult(commitLog, peers, IndexMergePreferSecond)
+	require.NoError(t, err)
+	assertMerged(merged, segments)
+
+	// Errors closing discarded segments are returned
+	peers, commitLog, segments = newResults()
+	segments[1].EXPECT().Close().Return(errors.New("an error"))
+	segments[2].EXPECT().Close().Return(nil)
+	_, err = MergedIndexBootstrapResult(peers, commitLog, IndexMergePreferFirst)
+	require.Error(t, err)
+
+	// Blocks that do not overlap are appended regardless of precedence
+	disjoint := NewIndexBootstrapResult()
+	disjointSegment := segment.NewMockSegment(ctrl)
+	disjoint.Add(NewIndexBlock(times[0], []segment.Segment{disjointSegment}, tr0Other), nil)
+	peers, _, segments = newResults()
+	merged, err = MergedIndexBootstrapResult(peers, disjoint, IndexMergePreferFirst)
+	require.NoError(t, err)
+	block := merged.IndexResults()[xtime.ToUnixNano(times[0])]
+	assert.Equal(t, []segment.Segment{segments[0], disjointSegment}, block.Segments())
+	expectedFulfilled := tr0.Copy()
+	expectedFulfilled.AddRanges(tr0Other)
+	assert.True(t, expectedFulfilled.Equal(block.Fulfilled()))
+	assert.True(t, merged.Unfulfilled().IsEmpty())
+
+	// Default precedence appends all segments
+	peers, commitLog, _ = newResults()
+	merged, err = MergedIndexBootstrapResult(peers, commitLog, IndexMergeAppend)
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(merged.IndexResults()[xtime.ToUnixNano(times[0])].Segments()))
+	assert.True(t, tr2.Equal(merged.Unfulfilled()))
+
+	// Precedence given to a nil result returns the other result unchanged
+	peers, _, _ = newResults()
+	merged, err = MergedIndexBootstrapResult(nil, peers, IndexMergePreferFirst)
+	require.NoError(t, err)
+	assert.True(t, merged == peers)
+	merged, err = MergedIndexBootstrapResult(peers, nil, IndexMergePreferSecond)
+	require.NoError(t, err)
+	assert.True(t, merged == peers)
+}
+
 func TestIndexResultSetUnfulfilled(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -181,6 +679,38 @@ func TestIndexResulsMarkFulfilled(t *testing.T) {
 	require.Equal(t, nextFulfilledRange, blk.fulfilled)
 }
 
+func TestIndexResultsMarkFulfilledRanges(t *testing.T) {
+	iopts := namespace.NewIndexOptions().SetBlockSize(time.Hour * 2)
+	t0 := time.Now().Truncate(2 * time.Hour)
+	tn := func(i int) time.Time {
+		return t0.Add(time.Duration(i) * time.Hour)
+	}
+	results := make(IndexResults)
+
+	// A range spanning three blocks for one shard and one within a block for another
+	fulfilled := NewShardTimeRanges(tn(1), tn(5), 1)
+	fulfilled.AddRanges(NewShardTimeRanges(tn(2), tn(3), 2))
+	require.NoError(t, results.MarkFulfilledRanges(fulfilled, iopts))
+	require.Equal(t, 3, len(results))
+
+	expected := map[time.Time]ShardTimeRanges{
+		tn(0): NewShardTimeRanges(tn(1), tn(2), 1),
+		tn(2): NewShardTimeRanges(tn(2), tn(4), 1),
+		tn(4): NewShardTimeRanges(tn(4), tn(5), 1),
+	}
+	expected[tn(2)].AddRanges(NewShardTimeRanges(tn(2), tn(3), 2))
+	for blockStart, expectedFulfilled := range expected {
+		blk, ok := results[xtime.ToUnixNano(blockStart)]
+		require.True(t, ok)
+		require.True(t, expectedFulfilled.Equal(blk.Fulfilled()),
+			"expected %s, actual %s", expectedFulfilled.String(), blk.Fulfilled().String())
+	}
+
+	// Ranges that fall in no valid block are rejected
+	require.Error(t, results.MarkFulfilledRanges(
+		NewShardTimeRanges(time.Time{}, tn(1), 1), iopts))
+}
+
 func segmentsInResultsSame(a, b IndexResults) bool {
 	if len(a) != len(b) {
 		return false
@@ -22,6 +22,7 @@ package result
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"time"
@@ -64,7 +65,7 @@ func (r ShardTimeRanges) Equal(other ShardTimeRanges) bool {
 		}
 		it := ranges.Iter()
 		otherIt := otherRanges.Iter()
-		if it.Next() && otherIt.Next() {
+		for it.Next() && otherIt.Next() {
 			value := it.Value()
 			otherValue := otherIt.Value()
 			if !value.Start.Equal(otherValue.Start) ||
@@ -199,6 +200,46 @@ func (r ShardTimeRanges) SummaryString() string {
 	return r.summarize(rangesDuration)
 }
 
+type timeRangeJSON struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// MarshalJSON returns the shard time ranges as a JSON object keyed by shard
+// with the list of time ranges for each shard, ordered by start time.
+func (r ShardTimeRanges) MarshalJSON() ([]byte, error) {
+	values := make(map[uint32][]timeRangeJSON, len(r))
+	for shard, ranges := range r {
+		shardValues := make([]timeRangeJSON, 0, ranges.Len())
+		it := ranges.Iter()
+		for it.Next() {
+			curr := it.Value()
+			shardValues = append(shardValues, timeRangeJSON{Start: curr.Start, End: curr.End})
+		}
+		values[shard] = shardValues
+	}
+	return json.Marshal(values)
+}
+
+// UnmarshalJSON sets the shard time ranges from the JSON representation
+// returned by MarshalJSON, replacing any existing ranges.
+func (r *ShardTimeRanges) UnmarshalJSON(data []byte) error {
+	var values map[uint32][]timeRangeJSON
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	result := make(ShardTimeRanges, len(values))
+	for shard, shardValues := range values {
+		var ranges xtime.Ranges
+		for _, v := range shardValues {
+			ranges = ranges.AddRange(xtime.Range{Start: v.Start, End: v.End})
+		}
+		result[shard] = ranges
+	}
+	*r = result
+	return nil
+}
+
 type shardTimeRanges struct {
 	shard uint32
 	value xtime.Ranges
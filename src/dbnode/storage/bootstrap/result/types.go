@@ -72,6 +72,26 @@ type IndexBlock struct {
 	fulfilled  ShardTimeRanges
 }
 
+// IndexMergePrecedence determines which index bootstrap result is treated
+// as authoritative when merging results with overlapping fulfilled ranges.
+type IndexMergePrecedence int
+
+const (
+	// IndexMergeAppend appends the segments of both results for every index
+	// block regardless of overlap, this is the default merge behavior.
+	IndexMergeAppend IndexMergePrecedence = iota
+
+	// IndexMergePreferFirst treats the first result as authoritative, index
+	// blocks from the second result whose fulfilled ranges overlap with the
+	// first result's block are discarded and their segments closed.
+	IndexMergePreferFirst
+
+	// IndexMergePreferSecond treats the second result as authoritative, index
+	// blocks from the first result whose fulfilled ranges overlap with the
+	// second result's block are discarded and their segments closed.
+	IndexMergePreferSecond
+)
+
 // MutableSegmentAllocator allocates a new MutableSegment type when
 // creating a bootstrap result to return to the index.
 type MutableSegmentAllocator func() (segment.MutableSegment, error)
@@ -20,6 +20,10 @@
 
 package bootstrap
 
+import (
+	"github.com/m3db/m3db/src/dbnode/storage/bootstrap/result"
+)
+
 const (
 	// defaultIncremental declares the intent to by default not perform an
 	// incremental bootstrap.
@@ -27,15 +31,17 @@ const (
 )
 
 type runOptions struct {
-	incremental         bool
-	cacheSeriesMetadata bool
+	incremental          bool
+	cacheSeriesMetadata  bool
+	indexMergePrecedence result.IndexMergePrecedence
 }
 
 // NewRunOptions creates new bootstrap run options
 func NewRunOptions() RunOptions {
 	return &runOptions{
-		incremental:         defaultIncremental,
-		cacheSeriesMetadata: defaultCacheSeriesMetadata,
+		incremental:          defaultIncremental,
+		cacheSeriesMetadata:  defaultCacheSeriesMetadata,
+		indexMergePrecedence: defaultIndexMergePrecedence,
 	}
 }
 
@@ -58,3 +64,13 @@ func (o *runOptions) SetCacheSeriesMetadata(value bool) RunOptions {
 func (o *runOptions) CacheSeriesMetadata() bool {
 	return o.cacheSeriesMetadata
 }
+
+func (o *runOptions) SetIndexMergePrecedence(value result.IndexMergePrecedence) RunOptions {
+	opts := *o
+	opts.indexMergePrecedence = value
+	return &opts
+}
+
+func (o *runOptions) IndexMergePrecedence() result.IndexMergePrecedence {
+	return o.indexMergePrecedence
+}
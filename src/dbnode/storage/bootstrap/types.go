@@ -75,6 +75,14 @@ type ProcessOptions interface {
 	// CacheSeriesMetadata returns whether bootstrappers created by this
 	// provider should cache series metadata between runs.
 	CacheSeriesMetadata() bool
+
+	// SetIndexMergePrecedence sets the precedence used when merging index
+	// results from a bootstrapper with the results of the next bootstrapper.
+	SetIndexMergePrecedence(value result.IndexMergePrecedence) ProcessOptions
+
+	// IndexMergePrecedence returns the precedence used when merging index
+	// results from a bootstrapper with the results of the next bootstrapper.
+	IndexMergePrecedence() result.IndexMergePrecedence
 }
 
 // RunOptions is a set of options for a bootstrap run.
@@ -94,6 +102,16 @@ type RunOptions interface {
 	// CacheSeriesMetadata returns whether bootstrappers created by this
 	// provider should cache series metadata between runs.
 	CacheSeriesMetadata() bool
+
+	// SetIndexMergePrecedence sets the precedence used when merging index
+	// results from a bootstrapper with the results of the next bootstrapper,
+	// the first result is the current bootstrapper's and the second result
+	// is the next bootstrapper's.
+	SetIndexMergePrecedence(value result.IndexMergePrecedence) RunOptions
+
+	// IndexMergePrecedence returns the precedence used when merging index
+	// results from a bootstrapper with the results of the next bootstrapper.
+	IndexMergePrecedence() result.IndexMergePrecedence
 }
 
 // BootstrapperProvider constructs a bootstrapper.
@@ -22,6 +22,7 @@ package storage
 
 import (
 	"bytes"
+	stdcontext "context"
 	"errors"
 	"fmt"
 	"sync"
@@ -441,7 +442,11 @@ func (d *db) terminateWithLock() error {
 	// our reference to the namespaces to nil.
 	d.namespaces.Reallocate()
 
-	// Finally close the commit log
+	// Finally quiesce the commit log so all pending writes are synced to disk
+	// and then close it
+	if err := d.commitLog.Quiesce(stdcontext.Background()); err != nil {
+		return err
+	}
 	return d.commitLog.Close()
 }
 
@@ -22,8 +22,6 @@ package index
 
 import (
 	"github.com/m3db/m3db/src/dbnode/storage/bootstrap/result"
-	"github.com/m3db/m3db/src/m3ninx/index/segment"
-	"github.com/m3db/m3db/src/m3ninx/index/segment/mem"
 )
 
 // NewBootstrapResultMutableSegmentAllocator returns a default mutable segment
@@ -31,7 +29,5 @@ import (
 func NewBootstrapResultMutableSegmentAllocator(
 	opts Options,
 ) result.MutableSegmentAllocator {
-	return func() (segment.MutableSegment, error) {
-		return mem.NewSegment(0, opts.MemSegmentOptions())
-	}
+	return result.NewMutableSegmentAllocator(0, opts.MemSegmentOptions())
 }
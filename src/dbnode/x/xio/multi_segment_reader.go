@@ -0,0 +1,120 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xio
+
+import (
+	"io"
+
+	"github.com/m3db/m3db/src/dbnode/ts"
+	"github.com/m3db/m3x/checked"
+)
+
+type multiSegmentReader struct {
+	segments []ts.Segment
+	readers  []segmentReader
+	ri       int
+}
+
+// NewMultiSegmentReader creates a new segment reader that reads the given
+// segments in order as a single continuous stream.
+func NewMultiSegmentReader(segments []ts.Segment) SegmentReader {
+	r := &multiSegmentReader{}
+	r.resetSegments(segments)
+	return r
+}
+
+func (r *multiSegmentReader) resetSegments(segments []ts.Segment) {
+	r.segments = append(r.segments[:0], segments...)
+	if cap(r.readers) < len(segments) {
+		r.readers = make([]segmentReader, len(segments))
+	}
+	r.readers = r.readers[:len(segments)]
+	for i, segment := range segments {
+		r.readers[i].Reset(segment)
+	}
+	r.ri = 0
+}
+
+func (r *multiSegmentReader) Clone() (SegmentReader, error) {
+	segments := make([]ts.Segment, 0, len(r.segments))
+	for _, segment := range r.segments {
+		segments = append(segments, cloneSegment(segment))
+	}
+	return NewMultiSegmentReader(segments), nil
+}
+
+func (r *multiSegmentReader) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	n := 0
+	for n < len(b) && r.ri < len(r.readers) {
+		nRead, err := r.readers[r.ri].Read(b[n:])
+		n += nRead
+		if err == io.EOF {
+			r.ri++
+			continue
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// Segment returns a single segment holding a copy of the bytes of all the
+// segments read, the returned segment is owned by the caller.
+func (r *multiSegmentReader) Segment() (ts.Segment, error) {
+	var size int
+	for i := range r.segments {
+		size += r.segments[i].Len()
+	}
+	data := make([]byte, 0, size)
+	for i := range r.readers {
+		data = append(data, r.readers[i].head...)
+		data = append(data, r.readers[i].tail...)
+	}
+	return ts.NewSegment(checked.NewBytes(data, nil), nil, ts.FinalizeNone), nil
+}
+
+func (r *multiSegmentReader) Reset(segment ts.Segment) {
+	r.resetSegments([]ts.Segment{segment})
+}
+
+func (r *multiSegmentReader) ResetAndFinalizePrevious(segment ts.Segment) {
+	for i := range r.readers {
+		r.readers[i].Finalize()
+	}
+	r.resetSegments([]ts.Segment{segment})
+}
+
+func (r *multiSegmentReader) Finalize() {
+	// Finalize each of the segments
+	for i := range r.readers {
+		r.readers[i].Finalize()
+	}
+	r.segments = r.segments[:0]
+	r.readers = r.readers[:0]
+	r.ri = 0
+}
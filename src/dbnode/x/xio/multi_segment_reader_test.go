@@ -0,0 +1,104 @@
+// Copyright (c) 2016 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package xio
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/m3db/m3db/src/dbnode/ts"
+	"github.com/m3db/m3x/checked"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMultiSegments() ([]ts.Segment, []byte) {
+	checkd := func(d []byte) checked.Bytes { return checked.NewBytes(d, nil) }
+	segments := []ts.Segment{
+		ts.NewSegment(checkd([]byte{0x1, 0x2}), checkd([]byte{0x3}), ts.FinalizeNone),
+		ts.NewSegment(nil, nil, ts.FinalizeNone),
+		ts.NewSegment(checkd([]byte{0x4}), nil, ts.FinalizeNone),
+		ts.NewSegment(checkd([]byte{0x5, 0x6, 0x7}), checkd([]byte{0x8, 0x9}), ts.FinalizeNone),
+	}
+	expected := []byte{0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8, 0x9}
+	return segments, expected
+}
+
+func TestMultiSegmentReader(t *testing.T) {
+	segments, expected := newTestMultiSegments()
+	r := NewMultiSegmentReader(segments)
+
+	// Read in chunks smaller than and spanning segments
+	var read []byte
+	var b [2]byte
+	for {
+		n, err := r.Read(b[:])
+		read = append(read, b[:n]...)
+		if err == io.EOF {
+			require.Equal(t, 0, n)
+			break
+		}
+		require.NoError(t, err)
+	}
+	require.Equal(t, expected, read)
+
+	seg, err := r.Segment()
+	require.NoError(t, err)
+	require.Equal(t, expected, seg.Head.Bytes())
+	require.Nil(t, seg.Tail)
+
+	clone, err := r.Clone()
+	require.NoError(t, err)
+	cloned, err := ioutil.ReadAll(clone)
+	require.NoError(t, err)
+	require.Equal(t, expected, cloned)
+}
+
+func TestMultiSegmentReaderReset(t *testing.T) {
+	segments, expected := newTestMultiSegments()
+	r := NewMultiSegmentReader(segments)
+	read, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, expected, read)
+
+	head := []byte{0xa, 0xb}
+	r.Reset(ts.NewSegment(checked.NewBytes(head, nil), nil, ts.FinalizeNone))
+	read, err = ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, head, read)
+}
+
+func TestMultiSegmentReaderFinalize(t *testing.T) {
+	var finalized []checked.Bytes
+	opts := checked.NewBytesOptions().SetFinalizer(checked.BytesFinalizerFn(func(b checked.Bytes) {
+		finalized = append(finalized, b)
+	}))
+	first := checked.NewBytes([]byte{0x1}, opts)
+	second := checked.NewBytes([]byte{0x2}, opts)
+	r := NewMultiSegmentReader([]ts.Segment{
+		ts.NewSegment(first, nil, ts.FinalizeHead),
+		ts.NewSegment(second, nil, ts.FinalizeHead),
+	})
+
+	r.Finalize()
+	require.Equal(t, []checked.Bytes{first, second}, finalized)
+}
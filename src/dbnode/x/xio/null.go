@@ -26,8 +26,9 @@ import (
 
 type nullSegmentReader struct{}
 
-func (r nullSegmentReader) Read([]byte) (n int, err error) { return 0, nil }
-func (r nullSegmentReader) Segment() (ts.Segment, error)   { return ts.Segment{}, nil }
-func (r nullSegmentReader) Reset(ts.Segment)               {}
-func (r nullSegmentReader) Finalize()                      {}
-func (r nullSegmentReader) Clone() (SegmentReader, error)  { return r, nil }
+func (r nullSegmentReader) Read([]byte) (n int, err error)      { return 0, nil }
+func (r nullSegmentReader) Segment() (ts.Segment, error)        { return ts.Segment{}, nil }
+func (r nullSegmentReader) Reset(ts.Segment)                    {}
+func (r nullSegmentReader) ResetAndFinalizePrevious(ts.Segment) {}
+func (r nullSegmentReader) Finalize()                           {}
+func (r nullSegmentReader) Clone() (SegmentReader, error)       { return r, nil }
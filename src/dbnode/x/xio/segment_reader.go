@@ -21,37 +21,71 @@
 package xio
 
 import (
+	"errors"
 	"io"
 
 	"github.com/m3db/m3db/src/dbnode/ts"
+	"github.com/m3db/m3x/checked"
+)
+
+var (
+	errSeekNegativeOffset = errors.New("segment reader seek to negative offset")
+	errSeekInvalidWhence  = errors.New("segment reader seek with invalid whence")
 )
 
 type segmentReader struct {
 	segment ts.Segment
 	si      int
 	pool    SegmentReaderPool
+
+	// head and tail cache the segment bytes so they are not fetched from
+	// the checked bytes on every read, they are only refreshed on reset
+	head []byte
+	tail []byte
 }
 
 // NewSegmentReader creates a new segment reader along with a specified segment.
 func NewSegmentReader(segment ts.Segment) SegmentReader {
-	return &segmentReader{segment: segment}
+	sr := &segmentReader{}
+	sr.Reset(segment)
+	return sr
+}
+
+// NewPooledSegmentReader returns a segment reader for the segment from the
+// pool, which is returned to the pool when it is finalized, or a new segment
+// reader if the pool is nil.
+func NewPooledSegmentReader(segment ts.Segment, pool SegmentReaderPool) SegmentReader {
+	if pool == nil {
+		return NewSegmentReader(segment)
+	}
+	sr := pool.Get()
+	sr.Reset(segment)
+	return sr
 }
 
+// Clone returns a reader over a copy of the segment bytes so that the clone
+// remains valid after this reader is finalized and its bytes are returned to
+// a pool, finalizing the clone only releases the copy.
 func (sr *segmentReader) Clone() (SegmentReader, error) {
-	return NewSegmentReader(sr.segment), nil
+	return NewSegmentReader(cloneSegment(sr.segment)), nil
+}
+
+func cloneSegment(segment ts.Segment) ts.Segment {
+	var head, tail checked.Bytes
+	if segment.Head != nil {
+		head = checked.NewBytes(append([]byte(nil), segment.Head.Bytes()...), nil)
+	}
+	if segment.Tail != nil {
+		tail = checked.NewBytes(append([]byte(nil), segment.Tail.Bytes()...), nil)
+	}
+	return ts.NewSegment(head, tail, ts.FinalizeNone)
 }
 
 func (sr *segmentReader) Read(b []byte) (int, error) {
 	if len(b) == 0 {
 		return 0, nil
 	}
-	var head, tail []byte
-	if b := sr.segment.Head; b != nil {
-		head = b.Bytes()
-	}
-	if b := sr.segment.Tail; b != nil {
-		tail = b.Bytes()
-	}
+	head, tail := sr.head, sr.tail
 	nh, nt := len(head), len(tail)
 	if sr.si >= nh+nt {
 		return 0, io.EOF
@@ -76,6 +110,61 @@ func (sr *segmentReader) Read(b []byte) (int, error) {
 	return n, nil
 }
 
+// WriteTo writes the remaining head and tail bytes directly to w without an
+// intermediate buffer, advancing the read position by the bytes written.
+func (sr *segmentReader) WriteTo(w io.Writer) (int64, error) {
+	head, tail := sr.head, sr.tail
+	nh := len(head)
+	var total int64
+	if sr.si < nh {
+		n, err := w.Write(head[sr.si:])
+		sr.si += n
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	if sr.si < nh+len(tail) {
+		n, err := w.Write(tail[sr.si-nh:])
+		sr.si += n
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Seek sets the read position to an offset within the head and tail bytes,
+// seeking past the end clamps the position to the end and returns io.EOF and
+// seeking to a negative offset returns an error leaving the position unchanged.
+func (sr *segmentReader) Seek(offset int64, whence int) (int64, error) {
+	head, tail := sr.head, sr.tail
+	size := int64(len(head) + len(tail))
+
+	var base int64
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		base = int64(sr.si)
+	case io.SeekEnd:
+		base = size
+	default:
+		return int64(sr.si), errSeekInvalidWhence
+	}
+
+	pos := base + offset
+	if pos < 0 {
+		return int64(sr.si), errSeekNegativeOffset
+	}
+	if pos > size {
+		sr.si = int(size)
+		return size, io.EOF
+	}
+	sr.si = int(pos)
+	return pos, nil
+}
+
 func (sr *segmentReader) Segment() (ts.Segment, error) {
 	return sr.segment, nil
 }
@@ -83,11 +172,27 @@ func (sr *segmentReader) Segment() (ts.Segment, error) {
 func (sr *segmentReader) Reset(segment ts.Segment) {
 	sr.segment = segment
 	sr.si = 0
+	sr.head, sr.tail = nil, nil
+	if b := segment.Head; b != nil {
+		sr.head = b.Bytes()
+	}
+	if b := segment.Tail; b != nil {
+		sr.tail = b.Bytes()
+	}
+}
+
+// ResetAndFinalizePrevious finalizes the segment being read before resetting
+// the reader to read the new segment, unlike Reset which leaves finalizing
+// the previous segment to the caller.
+func (sr *segmentReader) ResetAndFinalizePrevious(segment ts.Segment) {
+	sr.segment.Finalize()
+	sr.Reset(segment)
 }
 
 func (sr *segmentReader) Finalize() {
 	// Finalize the segment
 	sr.segment.Finalize()
+	sr.head, sr.tail = nil, nil
 
 	if pool := sr.pool; pool != nil {
 		pool.Put(sr)
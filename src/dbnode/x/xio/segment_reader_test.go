@@ -21,11 +21,13 @@
 package xio
 
 import (
+	"bytes"
 	"io"
 	"testing"
 
 	"github.com/m3db/m3db/src/dbnode/ts"
 	"github.com/m3db/m3x/checked"
+	"github.com/m3db/m3x/pool"
 
 	"github.com/stretchr/testify/require"
 )
@@ -65,3 +67,195 @@ func TestSegmentReader(t *testing.T) {
 	require.Equal(t, head, seg.Head.Bytes())
 	require.Equal(t, tail, seg.Tail.Bytes())
 }
+
+func TestSegmentReaderWriteTo(t *testing.T) {
+	head := []byte{0x1, 0x2, 0x3, 0x4, 0x5}
+	tail := []byte{0x6, 0x7, 0x8}
+
+	for offset := 0; offset <= len(head)+len(tail); offset++ {
+		checkd := func(d []byte) checked.Bytes { return checked.NewBytes(d, nil) }
+		r := NewSegmentReader(ts.NewSegment(checkd(head), checkd(tail), ts.FinalizeNone))
+
+		// Read up to the offset then write the remainder
+		read := make([]byte, offset)
+		if offset > 0 {
+			n, err := io.ReadFull(r, read)
+			require.NoError(t, err)
+			require.Equal(t, offset, n)
+		}
+
+		var buf bytes.Buffer
+		n, err := r.(io.WriterTo).WriteTo(&buf)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(head)+len(tail)-offset), n)
+		require.Equal(t, append(append([]byte{}, head...), tail...), append(read, buf.Bytes()...))
+
+		// Everything has been consumed
+		written, err := r.(io.WriterTo).WriteTo(&buf)
+		require.NoError(t, err)
+		require.Equal(t, int64(0), written)
+		_, err = r.Read(make([]byte, 1))
+		require.Equal(t, io.EOF, err)
+	}
+}
+
+func TestSegmentReaderSeek(t *testing.T) {
+	head := []byte{0x1, 0x2, 0x3, 0x4, 0x5}
+	tail := []byte{0x6, 0x7, 0x8}
+	checkd := func(d []byte) checked.Bytes { return checked.NewBytes(d, nil) }
+	r := NewSegmentReader(ts.NewSegment(checkd(head), checkd(tail), ts.FinalizeNone))
+	seeker := r.(io.Seeker)
+
+	var b [2]byte
+	_, err := io.ReadFull(r, b[:])
+	require.NoError(t, err)
+
+	// Seek back into the head and re-read across into the tail
+	pos, err := seeker.Seek(4, io.SeekStart)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), pos)
+	_, err = io.ReadFull(r, b[:])
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x5, 0x6}, b[:])
+
+	pos, err = seeker.Seek(-1, io.SeekCurrent)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), pos)
+
+	pos, err = seeker.Seek(-1, io.SeekEnd)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), pos)
+	n, err := r.Read(b[:])
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x8}, b[:n])
+
+	// Negative offsets leave the position unchanged
+	_, err = seeker.Seek(3, io.SeekStart)
+	require.NoError(t, err)
+	pos, err = seeker.Seek(-4, io.SeekCurrent)
+	require.Error(t, err)
+	require.Equal(t, int64(3), pos)
+	_, err = io.ReadFull(r, b[:])
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x4, 0x5}, b[:])
+
+	// Seeking past the end clamps to the end
+	pos, err = seeker.Seek(100, io.SeekStart)
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, int64(len(head)+len(tail)), pos)
+	_, err = r.Read(b[:])
+	require.Equal(t, io.EOF, err)
+}
+
+func TestSegmentReaderCloneOutlivesFinalize(t *testing.T) {
+	head := []byte{0x1, 0x2, 0x3}
+	tail := []byte{0x4}
+	// Zero the bytes on finalize as a pool reusing them would overwrite them
+	opts := checked.NewBytesOptions().SetFinalizer(checked.BytesFinalizerFn(func(b checked.Bytes) {
+		for _, data := range [][]byte{head, tail} {
+			for i := range data {
+				data[i] = 0
+			}
+		}
+	}))
+	r := NewSegmentReader(ts.NewSegment(checked.NewBytes(head, opts),
+		checked.NewBytes(tail, opts), ts.FinalizeHead|ts.FinalizeTail))
+
+	clone, err := r.Clone()
+	require.NoError(t, err)
+	r.Finalize()
+	require.Equal(t, []byte{0x0, 0x0, 0x0}, head)
+
+	var b [10]byte
+	n, err := clone.Read(b[:])
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x1, 0x2, 0x3, 0x4}, b[:n])
+	clone.Finalize()
+}
+
+func TestSegmentReaderResetAndFinalizePrevious(t *testing.T) {
+	var finalized int
+	opts := checked.NewBytesOptions().SetFinalizer(checked.BytesFinalizerFn(func(b checked.Bytes) {
+		finalized++
+	}))
+	prev := ts.NewSegment(checked.NewBytes([]byte{0x1, 0x2}, opts), nil, ts.FinalizeHead)
+	r := NewSegmentReader(prev)
+
+	// Reset leaves the previous segment for the caller to finalize
+	r.Reset(prev)
+	require.Equal(t, 0, finalized)
+
+	data := []byte{0x3, 0x4}
+	r.ResetAndFinalizePrevious(ts.NewSegment(checked.NewBytes(data, nil), nil, ts.FinalizeNone))
+	require.Equal(t, 1, finalized)
+
+	var b [10]byte
+	n, err := r.Read(b[:])
+	require.NoError(t, err)
+	require.Equal(t, data, b[:n])
+
+	// Finalizing the reader only finalizes the new segment
+	r.Finalize()
+	require.Equal(t, 1, finalized)
+}
+
+func TestNewPooledSegmentReaderReturnsToPool(t *testing.T) {
+	readerPool := NewSegmentReaderPool(pool.NewObjectPoolOptions().SetSize(1))
+	readerPool.Init()
+
+	data := []byte{0x1, 0x2, 0x3}
+	r := NewPooledSegmentReader(ts.NewSegment(checked.NewBytes(data, nil), nil,
+		ts.FinalizeNone), readerPool)
+
+	var b [10]byte
+	n, err := r.Read(b[:])
+	require.NoError(t, err)
+	require.Equal(t, data, b[:n])
+
+	r.Finalize()
+	require.True(t, r == readerPool.Get())
+}
+
+func BenchmarkSegmentReaderSmallReads(b *testing.B) {
+	head := make([]byte, 4096)
+	tail := make([]byte, 64)
+	checkd := func(d []byte) checked.Bytes { return checked.NewBytes(d, nil) }
+	segment := ts.NewSegment(checkd(head), checkd(tail), ts.FinalizeNone)
+	r := NewSegmentReader(segment)
+
+	var buf [8]byte
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := r.Read(buf[:]); err == io.EOF {
+			r.Reset(segment)
+		}
+	}
+}
+
+func benchmarkSegmentReaderChurn(b *testing.B, readerPool SegmentReaderPool) {
+	head := make([]byte, 256)
+	segment := ts.NewSegment(checked.NewBytes(head, nil), nil, ts.FinalizeNone)
+
+	var buf [64]byte
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r := NewPooledSegmentReader(segment, readerPool)
+		for {
+			if _, err := r.Read(buf[:]); err == io.EOF {
+				break
+			}
+		}
+		r.Finalize()
+	}
+}
+
+func BenchmarkSegmentReaderChurnUnpooled(b *testing.B) {
+	benchmarkSegmentReaderChurn(b, nil)
+}
+
+func BenchmarkSegmentReaderChurnPooled(b *testing.B) {
+	readerPool := NewSegmentReaderPool(pool.NewObjectPoolOptions().SetSize(16))
+	readerPool.Init()
+	benchmarkSegmentReaderChurn(b, readerPool)
+}
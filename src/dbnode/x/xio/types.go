@@ -46,9 +46,14 @@ type SegmentReader interface {
 	// Segment gets the segment read by this reader
 	Segment() (ts.Segment, error)
 
-	// Reset resets the reader to read a new segment
+	// Reset resets the reader to read a new segment, the previous segment
+	// is not finalized and remains the responsibility of the caller
 	Reset(segment ts.Segment)
 
+	// ResetAndFinalizePrevious finalizes the segment currently held by the
+	// reader before resetting the reader to read a new segment
+	ResetAndFinalizePrevious(segment ts.Segment)
+
 	// Clone returns a clone of the underlying data reset
 	Clone() (SegmentReader, error)
 }
@@ -0,0 +1,73 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package index
+
+import (
+	"regexp/syntax"
+)
+
+// RegexpLiteralPrefix returns the literal prefix that every string wholly
+// matched by the regular expression must begin with, and whether the regular
+// expression matches only that literal. Leading and trailing text anchors are
+// ignored since the regular expression is treated as matching whole terms.
+func RegexpLiteralPrefix(regexp []byte) (prefix []byte, complete bool, err error) {
+	parsed, err := syntax.Parse(string(regexp), syntax.Perl)
+	if err != nil {
+		return nil, false, err
+	}
+	parsed = parsed.Simplify()
+
+	for parsed.Op == syntax.OpCapture {
+		parsed = parsed.Sub[0]
+	}
+
+	subs := []*syntax.Regexp{parsed}
+	if parsed.Op == syntax.OpConcat {
+		subs = parsed.Sub
+	}
+
+	// Skip leading anchors
+	for len(subs) > 0 && isTextAnchor(subs[0]) {
+		subs = subs[1:]
+	}
+	if len(subs) == 0 || !isCaseSensitiveLiteral(subs[0]) {
+		return nil, false, nil
+	}
+	prefix = []byte(string(subs[0].Rune))
+
+	// The prefix is complete if only trailing anchors follow the literal
+	complete = true
+	for _, sub := range subs[1:] {
+		if !isTextAnchor(sub) {
+			complete = false
+			break
+		}
+	}
+	return prefix, complete, nil
+}
+
+func isTextAnchor(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpBeginText || re.Op == syntax.OpEndText
+}
+
+func isCaseSensitiveLiteral(re *syntax.Regexp) bool {
+	return re.Op == syntax.OpLiteral && re.Flags&syntax.FoldCase == 0
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2018 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package index
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegexpLiteralPrefix(t *testing.T) {
+	tests := []struct {
+		regexp   string
+		prefix   string
+		complete bool
+	}{
+		{regexp: "http_requests", prefix: "http_requests", complete: true},
+		{regexp: "^http_requests$", prefix: "http_requests", complete: true},
+		{regexp: "(http_requests)", prefix: "http_requests", complete: true},
+		{regexp: "http_.*", prefix: "http_"},
+		{regexp: "^http_.*$", prefix: "http_"},
+		{regexp: "http_(requests|errors)", prefix: "http_"},
+		{regexp: ".*_total", prefix: ""},
+		{regexp: "(?i)http_.*", prefix: ""},
+		{regexp: "http|grpc", prefix: ""},
+		{regexp: "[a-z]+_total", prefix: ""},
+	}
+
+	for _, test := range tests {
+		prefix, complete, err := RegexpLiteralPrefix([]byte(test.regexp))
+		require.NoError(t, err, test.regexp)
+		require.Equal(t, test.prefix, string(prefix), test.regexp)
+		require.Equal(t, test.complete, complete, test.regexp)
+	}
+
+	_, _, err := RegexpLiteralPrefix([]byte("(unclosed"))
+	require.Error(t, err)
+}
@@ -326,6 +326,13 @@ func (r *fsSegment) MatchRegexp(field []byte, regexp []byte, compiled *regexp.Re
 		return nil, err
 	}
 
+	// Only scan the range of terms that can match the literal prefix of the
+	// regexp rather than the entire terms FST
+	startKey, endKey, err := regexpSearchBounds(regexp)
+	if err != nil {
+		return nil, err
+	}
+
 	termsFST, exists, err := r.retrieveTermsFSTWithRLock(field)
 	if err != nil {
 		return nil, err
@@ -339,7 +346,7 @@ func (r *fsSegment) MatchRegexp(field []byte, regexp []byte, compiled *regexp.Re
 	var (
 		fstCloser     = x.NewSafeCloser(termsFST)
 		pl            = r.opts.PostingsListPool.Get()
-		iter, iterErr = termsFST.Search(re, minByteKey, maxByteKey)
+		iter, iterErr = termsFST.Search(re, startKey, endKey)
 		iterCloser    = x.NewSafeCloser(iter)
 	)
 	defer func() {
@@ -617,3 +624,26 @@ func (r *fsSegment) copyBytes(b []byte) []byte {
 	copy(copied, b)
 	return copied
 }
+
+// regexpSearchBounds returns the inclusive start and exclusive end keys of the
+// range of terms that can wholly match the regexp.
+func regexpSearchBounds(regexp []byte) ([]byte, []byte, error) {
+	prefix, _, err := index.RegexpLiteralPrefix(regexp)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(prefix) == 0 {
+		return minByteKey, maxByteKey, nil
+	}
+
+	// The exclusive end key is the shortest key greater than every key that
+	// begins with the prefix.
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return prefix, end[:i+1], nil
+		}
+	}
+	return prefix, maxByteKey, nil
+}
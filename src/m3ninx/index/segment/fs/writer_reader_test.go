@@ -23,6 +23,7 @@ package fs
 import (
 	"bytes"
 	"fmt"
+	"regexp"
 	"sort"
 	"sync"
 	"testing"
@@ -278,6 +279,47 @@ func TestPostingsListRegexAll(t *testing.T) {
 	}
 }
 
+func TestPostingsListRegexPrefix(t *testing.T) {
+	for _, test := range testDocuments {
+		t.Run(test.name, func(t *testing.T) {
+			memSeg, fstSeg := newTestSegments(t, test.docs)
+			fields, err := memSeg.Fields()
+			require.NoError(t, err)
+			for _, f := range fields {
+				terms, err := memSeg.Terms(f)
+				require.NoError(t, err)
+				for _, term := range terms {
+					prefix := regexp.QuoteMeta(string(term[:1]))
+					re := []byte(prefix + ".*")
+
+					reader, err := memSeg.Reader()
+					require.NoError(t, err)
+					memPl, err := reader.MatchRegexp(f, re, regexp.MustCompile("^"+prefix+".*$"))
+					require.NoError(t, err)
+
+					fstReader, err := fstSeg.Reader()
+					require.NoError(t, err)
+					fstPl, err := fstReader.MatchRegexp(f, re, nil)
+					require.NoError(t, err)
+					require.True(t, memPl.Equal(fstPl))
+				}
+			}
+		})
+	}
+}
+
+func TestRegexpSearchBounds(t *testing.T) {
+	start, end, err := regexpSearchBounds([]byte("http_.*"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("http_"), start)
+	require.Equal(t, []byte("http`"), end)
+
+	start, end, err = regexpSearchBounds([]byte(".*_total"))
+	require.NoError(t, err)
+	require.Equal(t, minByteKey, start)
+	require.Equal(t, maxByteKey, end)
+}
+
 func TestSegmentDocs(t *testing.T) {
 	for _, test := range testDocuments {
 		t.Run(test.name, func(t *testing.T) {